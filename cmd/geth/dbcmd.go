@@ -19,9 +19,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"syscall"
 	"time"
 
 	"gopkg.in/urfave/cli.v1"
@@ -30,13 +32,20 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
 var (
+	dbRepairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Schedule the corrupted block bodies and receipts to be re-fetched from the network",
+	}
 	removedbCommand = cli.Command{
 		Action:    utils.MigrateFlags(removeDB),
 		Name:      "removedb",
@@ -63,7 +72,12 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbVerifyAncientsCmd,
 			ancientInspectCmd,
+			dbExportDiffsCmd,
+			dbImportDiffsCmd,
+			dbConvertCmd,
+			dbReindexLogsCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -157,6 +171,83 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		},
 		Description: "This command displays information about the freezer index.",
 	}
+	dbVerifyAncientsCmd = cli.Command{
+		Action: utils.MigrateFlags(verifyAncients),
+		Name:   "verify-ancients",
+		Usage:  "Verify the integrity of the ancient store",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.SyncModeFlag,
+			dbRepairFlag,
+		},
+		Description: `This command scans every item of the ancient store, verifying the checksums
+of the items, the headers against the canonical hashes and the block bodies and
+receipts against the roots in the headers, and reports the corrupted items. Items
+stored before the checksums were introduced are only checked for decodability.
+
+With --repair, the corrupted bodies and receipts are scheduled to be fetched from
+the network again, which the node does once it's running and connected to peers.`,
+	}
+	dbExportDiffsCmd = cli.Command{
+		Action:    utils.MigrateFlags(exportDiffs),
+		Name:      "export-diffs",
+		Usage:     "Export the diff layers of a range of blocks into a file",
+		ArgsUsage: "<dumpfile> <first> <last>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DiffFlag,
+		},
+		Description: `This command exports the diff layers of the given range of canonical blocks,
+both frozen in the ancient store and persisted in the diff store, into a file. If
+the file ends with .gz, the output will be gzipped. Blocks without a known diff
+layer are skipped.`,
+	}
+	dbConvertCmd = cli.Command{
+		Action:    utils.MigrateFlags(convertDB),
+		Name:      "convert",
+		Usage:     "Convert the LevelDB chain database into a Pebble one",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+		},
+		Description: `This command copies every entry of the LevelDB chain database into a new
+Pebble database and swaps the two once done. The ancient and diff stores living
+inside the chain database directory are moved over as they are, and the original
+database is kept next to the converted one with a "-leveldb" suffix. The node must
+not be running while converting.`,
+	}
+	dbImportDiffsCmd = cli.Command{
+		Action:    utils.MigrateFlags(importDiffs),
+		Name:      "import-diffs",
+		Usage:     "Import diff layers from a file into the diff store",
+		ArgsUsage: "<dumpfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DiffFlag,
+		},
+		Description: `This command imports the diff layers exported by export-diffs into the diff
+store, from where they are served to diff syncing peers.`,
+	}
+	dbReindexLogsCmd = cli.Command{
+		Action:    utils.MigrateFlags(reindexLogs),
+		Name:      "reindex-logs",
+		Usage:     "Rebuild the address/topic log index",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.SyncModeFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+		},
+		Description: `This command drops the log index used by --log.index and regenerates it from
+the receipts of the canonical chain, up to the sections the running node would
+have indexed. The node must not be running while reindexing; once restarted with
+--log.index, it continues maintaining the index from where the rebuild stopped.`,
+	}
 	ancientInspectCmd = cli.Command{
 		Action: utils.MigrateFlags(ancientInspect),
 		Name:   "inspect-reserved-oldest-blocks",
@@ -488,3 +579,198 @@ func freezerInspect(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+func verifyAncients(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	repair := ctx.Bool(dbRepairFlag.Name)
+	db := utils.MakeChainDatabase(ctx, stack, !repair, true)
+	defer db.Close()
+
+	corrupted, err := rawdb.VerifyAncients(db, trie.NewStackTrie(nil), repair)
+	if err != nil {
+		return err
+	}
+	if corrupted > 0 {
+		if repair {
+			log.Info("Scheduled repair of corrupted ancient items", "repairs", len(rawdb.ReadAncientRepairs(db)))
+		}
+		return fmt.Errorf("%d corrupted ancient items", corrupted)
+	}
+	return nil
+}
+
+// openDiffDatabase opens the chain database along with its diff store. A read
+// only database is returned without a diff store if the latter is missing.
+func openDiffDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.Database {
+	db := utils.MakeChainDatabase(ctx, stack, readonly, true)
+	diffStore, err := stack.OpenDiffDatabase("chaindata", utils.MakeDatabaseHandles(), ctx.GlobalString(utils.DiffFlag.Name), "", readonly)
+	if err != nil {
+		if readonly {
+			log.Warn("Diff database unavailable", "err", err)
+			return db
+		}
+		db.Close()
+		utils.Fatalf("Could not open diff database: %v", err)
+	}
+	db.SetDiffStore(diffStore)
+	return db
+}
+
+func exportDiffs(ctx *cli.Context) error {
+	if ctx.NArg() != 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	first, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid first block number: %v", err)
+	}
+	last, err := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid last block number: %v", err)
+	}
+	if first > last {
+		return fmt.Errorf("first block #%d is after last block #%d", first, last)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := openDiffDatabase(ctx, stack, true)
+	defer db.Close()
+
+	start := time.Now()
+	if err := utils.ExportDiffLayers(db, ctx.Args().First(), first, last); err != nil {
+		return err
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importDiffs(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := openDiffDatabase(ctx, stack, false)
+	defer db.Close()
+
+	start := time.Now()
+	if err := utils.ImportDiffLayers(db, ctx.Args().First()); err != nil {
+		return err
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+func convertDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	src := stack.ResolvePath("chaindata")
+	if engine := rawdb.PreexistingDatabase(src); engine != rawdb.DBLeveldb {
+		return fmt.Errorf("no leveldb database found in %s", src)
+	}
+	dst, backup := src+"-pebble", src+"-leveldb"
+	for _, path := range []string{dst, backup} {
+		if common.FileExist(path) {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+	handles := utils.MakeDatabaseHandles() / 2
+	from, err := rawdb.NewKeyValueStore(rawdb.DBLeveldb, src, 256, handles, "", true)
+	if err != nil {
+		return err
+	}
+	to, err := rawdb.NewKeyValueStore(rawdb.DBPebble, dst, 1024, handles, "", false)
+	if err != nil {
+		from.Close()
+		return err
+	}
+	start := time.Now()
+	count, err := copyKeyValueStore(from, to)
+	from.Close()
+	if cerr := to.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	// Move the ancient and diff stores, which live inside the chain database
+	// directory by default, over to the converted database
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := os.Rename(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(src, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(dst, src); err != nil {
+		return err
+	}
+	log.Info("Converted chain database", "keys", count, "backup", backup, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// copyKeyValueStore copies all the entries of a key-value store into another one,
+// returning the number of entries copied.
+func copyKeyValueStore(from, to ethdb.KeyValueStore) (uint64, error) {
+	it := from.NewIterator(nil, nil)
+	defer it.Release()
+
+	var (
+		batch  = to.NewBatch()
+		count  uint64
+		logged = time.Now()
+	)
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return count, err
+		}
+		count++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return count, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting chain database", "keys", count, "at", common.Bytes2Hex(it.Key()))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return count, err
+	}
+	return count, batch.Write()
+}
+
+// reindexLogs drops and regenerates the address/topic log index.
+func reindexLogs(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, false)
+	defer db.Close()
+
+	abort := make(chan struct{})
+	go func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigc)
+		<-sigc
+		log.Info("Got interrupt, stopping log reindexing")
+		close(abort)
+	}()
+	return core.RebuildLogIndex(db, params.BloomBitsBlocks, params.BloomConfirms, abort)
+}
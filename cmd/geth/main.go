@@ -65,7 +65,9 @@ var (
 		utils.PasswordFileFlag,
 		utils.BootnodesFlag,
 		utils.DataDirFlag,
+		utils.DBEngineFlag,
 		utils.AncientFlag,
+		utils.AncientCompressionFlag,
 		utils.MinFreeDiskSpaceFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
@@ -73,7 +75,12 @@ var (
 		utils.DirectBroadcastFlag,
 		utils.DisableSnapProtocolFlag,
 		utils.DiffSyncFlag,
+		utils.DiffSyncSampleFlag,
+		utils.DiffSyncHorizonFlag,
+		utils.DiffSyncUnsignedFlag,
 		utils.PipeCommitFlag,
+		utils.ParallelTxFlag,
+		utils.ParallelTxNumFlag,
 		utils.RangeLimitFlag,
 		utils.USBFlag,
 		utils.SmartCardDaemonPathFlag,
@@ -96,6 +103,7 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.HistoryBlocksFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -109,6 +117,10 @@ var (
 		utils.WhitelistFlag,
 		utils.BloomFilterSizeFlag,
 		utils.TriesInMemoryFlag,
+		utils.StateRetentionFlag,
+		utils.StateHistoryFlag,
+		utils.TraceIndexFlag,
+		utils.LogIndexFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -119,6 +131,7 @@ var (
 		utils.CachePreimagesFlag,
 		utils.PersistDiffFlag,
 		utils.DiffBlockFlag,
+		utils.FreezeDiffFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -187,6 +200,8 @@ var (
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCLogBlockRangeFlag,
+		utils.RPCLogResultCapFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -238,6 +253,7 @@ func init() {
 		utils.ShowDeprecated,
 		// See snapshot.go
 		snapshotCommand,
+		traceCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
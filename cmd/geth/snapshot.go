@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/tsdb/fileutil"
@@ -29,11 +30,14 @@ import (
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -49,6 +53,13 @@ var (
 	emptyCode = crypto.Keccak256(nil)
 )
 
+var (
+	snapshotInsecureFlag = cli.BoolFlag{
+		Name:  "insecure",
+		Usage: "Trust the consensus checkpoint of the imported snapshot without a trusted checkpoint hash",
+	}
+)
+
 var (
 	snapshotCommand = cli.Command{
 		Name:        "snapshot",
@@ -162,6 +173,48 @@ verification. The default checking target is the HEAD state. It's basically iden
 to traverse-state, but the check granularity is smaller. 
 
 It's also usable without snapshot enabled.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state snapshot of a block together with its verification headers",
+				ArgsUsage: "<filename> [<number>]",
+				Action:    utils.MigrateFlags(exportSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+				},
+				Description: `
+geth snapshot export <filename> [<number>]
+will export the flat state of the given canonical block, together with the
+contract codes and the headers since the last consensus checkpoint, into a
+chunked and checksummed file. The default block is the bottom-most snapshot
+diff layer, which is very unlikely to be reorged. If the file ends with .gz,
+the output will be gzipped.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Bootstrap a node from an exported state snapshot",
+				ArgsUsage: "<filename> <checkpoint hash>",
+				Action:    utils.MigrateFlags(importSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					snapshotInsecureFlag,
+				},
+				Description: `
+geth snapshot import <filename> <checkpoint hash>
+will import an exported state snapshot into a freshly initialized database. The
+exported headers are verified on top of the consensus checkpoint, which must
+match the given trusted checkpoint hash. The state trie is rebuilt from the flat
+state and verified against the exported block, which becomes the head of the
+chain the node starts syncing from.
+
+The checkpoint hash may only be omitted with --insecure, in which case the
+validator set of the snapshot is trusted blindly.
 `,
 			},
 		},
@@ -603,3 +656,108 @@ func parseRoot(input string) (common.Hash, error) {
 	}
 	return h, nil
 }
+
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		log.Error("Invalid arguments given")
+		return errors.New("invalid arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false, true)
+	defer chaindb.Close()
+
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	triesInMemory := int(ctx.GlobalUint64(utils.TriesInMemoryFlag.Name))
+	snaptree, err := snapshot.New(chaindb, trie.NewDatabase(chaindb), 256, triesInMemory, headBlock.Root(), false, false, false)
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	var block *types.Block
+	if ctx.NArg() == 2 {
+		number, err := strconv.ParseUint(ctx.Args()[1], 10, 64)
+		if err != nil {
+			log.Error("Failed to resolve block number", "err", err)
+			return err
+		}
+		block = rawdb.ReadBlock(chaindb, rawdb.ReadCanonicalHash(chaindb, number), number)
+	} else {
+		// Use the block of the bottom-most diff layer as the target
+		layers := snaptree.Snapshots(headBlock.Root(), triesInMemory, true)
+		if len(layers) == 0 {
+			return errors.New("no snapshot diff layers")
+		}
+		root := layers[len(layers)-1].Root()
+		for block = headBlock; block != nil && block.Root() != root; {
+			block = rawdb.ReadBlock(chaindb, block.ParentHash(), block.NumberU64()-1)
+		}
+	}
+	if block == nil {
+		log.Error("Failed to load target block")
+		return errors.New("no target block")
+	}
+	genesisHash := rawdb.ReadCanonicalHash(chaindb, 0)
+	chainConfig := rawdb.ReadChainConfig(chaindb, genesisHash)
+	if chainConfig == nil {
+		return errors.New("chain config missing")
+	}
+	engine := ethconfig.CreateConsensusEngine(stack, chainConfig, nil, false, chaindb, nil, genesisHash)
+	hc, err := core.NewHeaderChain(chaindb, chainConfig, engine, func() bool { return false })
+	if err != nil {
+		return err
+	}
+	if err := utils.ExportSnapshot(hc, snaptree, chaindb, block, ctx.Args().First()); err != nil {
+		log.Error("Failed to export state snapshot", "err", err)
+		return err
+	}
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		log.Error("Invalid arguments given")
+		return errors.New("invalid arguments")
+	}
+	var (
+		trusted common.Hash
+		err     error
+	)
+	insecure := ctx.Bool(snapshotInsecureFlag.Name)
+	if ctx.NArg() == 2 {
+		if trusted, err = parseRoot(ctx.Args()[1]); err != nil {
+			log.Error("Failed to resolve checkpoint hash", "err", err)
+			return err
+		}
+	} else if !insecure {
+		log.Error("Trusted checkpoint hash required, use --insecure to skip the check")
+		return errors.New("trusted checkpoint hash missing")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false, true)
+	defer chaindb.Close()
+
+	genesisHash := rawdb.ReadCanonicalHash(chaindb, 0)
+	chainConfig := rawdb.ReadChainConfig(chaindb, genesisHash)
+	if chainConfig == nil {
+		log.Error("Database not initialized, run geth init first")
+		return errors.New("chain config missing")
+	}
+	engine := ethconfig.CreateConsensusEngine(stack, chainConfig, nil, false, chaindb, nil, genesisHash)
+	hc, err := core.NewHeaderChain(chaindb, chainConfig, engine, func() bool { return false })
+	if err != nil {
+		return err
+	}
+	if _, err := utils.ImportSnapshot(hc, chaindb, ctx.Args().First(), trusted, insecure); err != nil {
+		log.Error("Failed to import state snapshot", "err", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	traceTracerFlag = cli.StringFlag{
+		Name:  "tracer",
+		Usage: "Name of the tracer to trace the blocks with",
+		Value: "callTracer",
+	}
+	traceTimeoutFlag = cli.StringFlag{
+		Name:  "timeout",
+		Usage: "Timeout of tracing a single transaction",
+	}
+
+	traceCommand = cli.Command{
+		Name:        "trace",
+		Usage:       "A set of commands based on the transaction tracers",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the traces of a block range into files",
+				ArgsUsage: "<from> <to> <dir>",
+				Action:    utils.MigrateFlags(exportTraces),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					traceTracerFlag,
+					traceTimeoutFlag,
+				},
+				Description: `
+geth trace export <from> <to> <dir>
+will trace all the blocks between from and to (both inclusive) with the given
+tracer, writing the traces of each block as a line of JSON into gzip compressed
+files within the directory, one file per 1000 blocks. The progress is kept in a
+checkpoint in the directory: if the export is interrupted, running the command
+again with the same arguments resumes it. The same export can be started on a
+running node with the debug_traceChainToFiles RPC method.
+`,
+			},
+		},
+	}
+)
+
+// exportTraces traces a block range of the local chain into files, stopping
+// gracefully on an interrupt.
+func exportTraces(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("This command requires three arguments.")
+	}
+	first, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return errors.New("invalid start block number")
+	}
+	last, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return errors.New("invalid end block number")
+	}
+	stack, apiBackend := makeFullNode(ctx)
+	defer stack.Close()
+
+	backend, ok := apiBackend.(tracers.Backend)
+	if !ok {
+		utils.Fatalf("Tracing is not supported in light client mode.")
+	}
+	var (
+		tracer = ctx.String(traceTracerFlag.Name)
+		config = &tracers.TraceConfig{Tracer: &tracer}
+	)
+	if ctx.IsSet(traceTimeoutFlag.Name) {
+		timeout := ctx.String(traceTimeoutFlag.Name)
+		config.Timeout = &timeout
+	}
+	// Abort the export on an interrupt, the progress up to the last complete
+	// file is kept for resuming
+	abort := make(chan interface{})
+	go func() {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigc)
+		<-sigc
+		log.Info("Got interrupt, stopping trace export")
+		close(abort)
+	}()
+	return tracers.NewAPI(backend).ExportChain(first, last, ctx.Args().Get(2), config, abort)
+}
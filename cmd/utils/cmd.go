@@ -19,8 +19,10 @@ package utils
 
 import (
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
 	"runtime"
@@ -29,8 +31,10 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
@@ -39,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -344,3 +349,404 @@ func ExportPreimages(db ethdb.Database, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// ExportDiffLayers exports the diff layers of the given range of canonical
+// blocks into the specified file, truncating any data already present in the
+// file. Blocks without a known diff layer are skipped.
+func ExportDiffLayers(db ethdb.Database, fn string, first uint64, last uint64) error {
+	log.Info("Exporting diff layers", "file", fn, "first", first, "last", last)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	var (
+		exported int
+		logged   = time.Now()
+	)
+	for number := first; number <= last; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical block #%d not found", number)
+		}
+		blob := rawdb.ReadAncientDiffLayerRLP(db, number)
+		if len(blob) == 0 && db.DiffStore() != nil {
+			blob = rawdb.ReadDiffLayerRLP(db.DiffStore(), hash)
+		}
+		if len(blob) == 0 {
+			continue
+		}
+		if _, err := writer.Write(blob); err != nil {
+			return err
+		}
+		exported++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting diff layers", "number", number, "exported", exported)
+			logged = time.Now()
+		}
+	}
+	log.Info("Exported diff layers", "file", fn, "exported", exported)
+	return nil
+}
+
+// ImportDiffLayers imports a batch of exported diff layers into the diff store
+// of the database.
+func ImportDiffLayers(db ethdb.Database, fn string) error {
+	log.Info("Importing diff layers", "file", fn)
+
+	diffStore := db.DiffStore()
+	if diffStore == nil {
+		return errors.New("diff store not available")
+	}
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	// Import the diff layers in batches to prevent disk trashing
+	var (
+		batch    = diffStore.NewBatch()
+		imported int
+	)
+	for {
+		// Read the next entry and ensure it's not junk
+		blob, err := stream.Raw()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		var diff types.DiffLayer
+		if err := rlp.DecodeBytes(blob, &diff); err != nil {
+			return fmt.Errorf("diff layer %d: %v", imported, err)
+		}
+		if err := diff.Validate(); err != nil {
+			return fmt.Errorf("diff layer %d: %v", imported, err)
+		}
+		rawdb.WriteDiffLayerRLP(batch, diff.BlockHash, blob)
+		imported++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported diff layers", "file", fn, "imported", imported)
+	return nil
+}
+
+const (
+	snapshotExportVersion   = 1               // Version of the snapshot export format
+	snapshotExportChunkSize = 4 * 1024 * 1024 // Approximate size of a state chunk in a snapshot export
+)
+
+// Kinds of the frames of a snapshot export. An export consists of a preamble,
+// the chunks of the state and a trailer with the number of state chunks, which
+// detects truncated exports.
+const (
+	snapshotFramePreamble = iota
+	snapshotFrameState
+	snapshotFrameTrailer
+)
+
+// snapshotFrame is a checksummed item of a snapshot export.
+type snapshotFrame struct {
+	Kind     uint
+	Data     []byte
+	Checksum common.Hash
+}
+
+// snapshotPreamble is the first frame of a snapshot export, carrying the chain
+// segment needed to verify the exported state and to start the node from it.
+type snapshotPreamble struct {
+	Version    uint64
+	Td         *big.Int        // Total difficulty of the checkpoint
+	Checkpoint []byte          // Validator snapshot at the checkpoint
+	Headers    []*types.Header // Headers from the checkpoint up to the exported block
+	Body       *types.Body     // Body of the exported block
+}
+
+// writeSnapshotFrame encodes an item of a snapshot export into a frame.
+func writeSnapshotFrame(w io.Writer, kind uint, item interface{}) error {
+	data, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, &snapshotFrame{Kind: kind, Data: data, Checksum: crypto.Keccak256Hash(data)})
+}
+
+// readSnapshotFrame reads the next frame of a snapshot export, verifying its
+// checksum.
+func readSnapshotFrame(stream *rlp.Stream) (*snapshotFrame, error) {
+	frame := new(snapshotFrame)
+	if err := stream.Decode(frame); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if hash := crypto.Keccak256Hash(frame.Data); hash != frame.Checksum {
+		return nil, fmt.Errorf("frame checksum mismatch: have %x, want %x", hash, frame.Checksum)
+	}
+	return frame, nil
+}
+
+// ExportSnapshot exports the flat state of the given block into the specified
+// file, truncating any data already present in the file. The export carries the
+// headers from the last consensus checkpoint, so that the importing node can
+// verify the exported block before trusting its state.
+func ExportSnapshot(chain *core.HeaderChain, snaptree *snapshot.Tree, db ethdb.Database, block *types.Block, fn string) error {
+	log.Info("Exporting state snapshot", "file", fn, "number", block.Number(), "hash", block.Hash(), "root", block.Root())
+
+	engine, ok := chain.Engine().(*parlia.Parlia)
+	if !ok {
+		return errors.New("snapshot export requires the parlia consensus engine")
+	}
+	checkpoint, blob, err := engine.Checkpoint(chain, block.Header())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve checkpoint: %v", err)
+	}
+	preamble := &snapshotPreamble{
+		Version:    snapshotExportVersion,
+		Td:         chain.GetTd(checkpoint.Hash(), checkpoint.Number.Uint64()),
+		Checkpoint: blob,
+		Body:       block.Body(),
+	}
+	if preamble.Td == nil {
+		return fmt.Errorf("total difficulty of checkpoint #%d missing", checkpoint.Number)
+	}
+	for number := checkpoint.Number.Uint64(); number <= block.NumberU64(); number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return fmt.Errorf("canonical header #%d not found", number)
+		}
+		preamble.Headers = append(preamble.Headers, header)
+	}
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := writeSnapshotFrame(writer, snapshotFramePreamble, preamble); err != nil {
+		return err
+	}
+	var chunks uint64
+	if err := snapshot.ExportState(snaptree, block.Root(), db, snapshotExportChunkSize, func(chunk *snapshot.StateChunk) error {
+		chunks++
+		return writeSnapshotFrame(writer, snapshotFrameState, chunk)
+	}); err != nil {
+		return err
+	}
+	if err := writeSnapshotFrame(writer, snapshotFrameTrailer, chunks); err != nil {
+		return err
+	}
+	log.Info("Exported state snapshot", "file", fn, "checkpoint", checkpoint.Number, "headers", len(preamble.Headers), "chunks", chunks)
+	return nil
+}
+
+// ImportSnapshot imports a snapshot export into a database holding nothing but
+// the genesis block. The exported headers are verified by the consensus engine
+// on top of the checkpoint, which has to match the trusted checkpoint hash unless
+// the import is explicitly insecure. The state trie is rebuilt from the flat
+// state and verified against the root of the exported block, which becomes the
+// head of the chain.
+func ImportSnapshot(chain *core.HeaderChain, db ethdb.Database, fn string, trusted common.Hash, insecure bool) (*types.Block, error) {
+	if trusted == (common.Hash{}) && !insecure {
+		return nil, errors.New("trusted checkpoint hash required")
+	}
+	log.Info("Importing state snapshot", "file", fn)
+
+	engine, ok := chain.Engine().(*parlia.Parlia)
+	if !ok {
+		return nil, errors.New("snapshot import requires the parlia consensus engine")
+	}
+	// Ensure the database is empty, the imported chain segment is not contiguous
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("genesis block missing, database not initialized")
+	}
+	if head := rawdb.ReadHeadHeaderHash(db); head != genesis {
+		return nil, fmt.Errorf("database not empty, head header %x", head)
+	}
+	if frozen, _ := db.Ancients(); frozen > 0 {
+		return nil, fmt.Errorf("database not empty, %d ancient blocks", frozen)
+	}
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	// Verify the exported chain segment
+	frame, err := readSnapshotFrame(stream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid preamble: %v", err)
+	}
+	var preamble snapshotPreamble
+	if frame.Kind != snapshotFramePreamble {
+		return nil, fmt.Errorf("invalid preamble kind %d", frame.Kind)
+	}
+	if err := rlp.DecodeBytes(frame.Data, &preamble); err != nil {
+		return nil, fmt.Errorf("invalid preamble: %v", err)
+	}
+	if preamble.Version != snapshotExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", preamble.Version)
+	}
+	if len(preamble.Headers) == 0 || preamble.Td == nil || preamble.Body == nil {
+		return nil, errors.New("incomplete preamble")
+	}
+	for i := 1; i < len(preamble.Headers); i++ {
+		if header, parent := preamble.Headers[i], preamble.Headers[i-1]; header.ParentHash != parent.Hash() || header.Number.Uint64() != parent.Number.Uint64()+1 {
+			return nil, fmt.Errorf("non contiguous header #%d [%x…]", header.Number, header.Hash().Bytes()[:4])
+		}
+	}
+	var (
+		checkpoint = preamble.Headers[0]
+		header     = preamble.Headers[len(preamble.Headers)-1]
+		block      = types.NewBlockWithHeader(header).WithBody(preamble.Body.Transactions, preamble.Body.Uncles)
+	)
+	if hash := types.DeriveSha(types.Transactions(preamble.Body.Transactions), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return nil, fmt.Errorf("transaction root mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(preamble.Body.Uncles); hash != header.UncleHash {
+		return nil, fmt.Errorf("uncle root mismatch: have %x, want %x", hash, header.UncleHash)
+	}
+	if trusted == (common.Hash{}) {
+		log.Warn("Insecurely trusting the exported checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash())
+	} else if checkpoint.Hash() != trusted {
+		return nil, fmt.Errorf("checkpoint mismatch: have %x, want %x", checkpoint.Hash(), trusted)
+	}
+	if err := engine.ImportCheckpoint(checkpoint, preamble.Checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %v", err)
+	}
+	batch := db.NewBatch()
+	rawdb.WriteHeader(batch, checkpoint)
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	batch.Reset()
+
+	if headers := preamble.Headers[1:]; len(headers) > 0 {
+		seals := make([]bool, len(headers))
+		for i := range seals {
+			seals[i] = true
+		}
+		abort, results := engine.VerifyHeaders(chain, headers, seals)
+		for i := range headers {
+			if err := <-results; err != nil {
+				close(abort)
+				return nil, fmt.Errorf("invalid header #%d [%x…]: %v", headers[i].Number, headers[i].Hash().Bytes()[:4], err)
+			}
+		}
+		close(abort)
+	}
+	log.Info("Verified exported headers", "checkpoint", checkpoint.Number, "number", header.Number, "hash", header.Hash())
+
+	// Rebuild the exported state and verify it against the verified header
+	importer, err := snapshot.NewStateImporter(db)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		chunks uint64
+		logged = time.Now()
+	)
+	for {
+		frame, err := readSnapshotFrame(stream)
+		if err != nil {
+			return nil, fmt.Errorf("state chunk %d: %v", chunks, err)
+		}
+		if frame.Kind == snapshotFrameTrailer {
+			var exported uint64
+			if err := rlp.DecodeBytes(frame.Data, &exported); err != nil {
+				return nil, fmt.Errorf("invalid trailer: %v", err)
+			}
+			if exported != chunks {
+				return nil, fmt.Errorf("state chunk count mismatch: have %d, want %d", chunks, exported)
+			}
+			break
+		}
+		if frame.Kind != snapshotFrameState {
+			return nil, fmt.Errorf("state chunk %d: invalid kind %d", chunks, frame.Kind)
+		}
+		chunk := new(snapshot.StateChunk)
+		if err := rlp.DecodeBytes(frame.Data, chunk); err != nil {
+			return nil, fmt.Errorf("state chunk %d: %v", chunks, err)
+		}
+		if err := importer.Import(chunk); err != nil {
+			return nil, fmt.Errorf("state chunk %d: %v", chunks, err)
+		}
+		chunks++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state snapshot", "chunks", chunks)
+			logged = time.Now()
+		}
+	}
+	if err := importer.Commit(header.Root); err != nil {
+		return nil, err
+	}
+	// Write the verified chain segment and start the chain from the exported
+	// block. The freezer starts above it, as the receipts of the exported block
+	// and the bodies of its ancestors are missing.
+	td := new(big.Int).Set(preamble.Td)
+	for i, h := range preamble.Headers {
+		if i > 0 {
+			td.Add(td, h.Difficulty)
+		}
+		rawdb.WriteTd(batch, h.Hash(), h.Number.Uint64(), td)
+		rawdb.WriteHeader(batch, h)
+		rawdb.WriteCanonicalHash(batch, h.Hash(), h.Number.Uint64())
+	}
+	rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
+	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	rawdb.WriteTxIndexTail(batch, block.NumberU64())
+	rawdb.WriteOffSetOfCurrentAncientFreezer(batch, block.NumberU64()+1)
+	rawdb.WriteHeadHeaderHash(batch, block.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
+	rawdb.WriteHeadBlockHash(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	log.Info("Imported state snapshot", "file", fn, "number", block.Number(), "hash", block.Hash(), "root", header.Root, "chunks", chunks)
+	return block, nil
+}
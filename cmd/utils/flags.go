@@ -131,18 +131,46 @@ var (
 		Usage: "Enable diffy sync, Please note that enable diffsync will improve the syncing speed, " +
 			"but will degrade the security to light client level",
 	}
+	DiffSyncSampleFlag = cli.Float64Flag{
+		Name:  "diffsync.sample",
+		Usage: "Fraction of diff synced blocks to fully execute, checking the received diff layers (default = 1/21)",
+	}
+	DiffSyncHorizonFlag = cli.DurationFlag{
+		Name:  "diffsync.horizon",
+		Usage: "Age beyond which downloaded blocks are imported by applying their diff layers in bulk without execution (0 = disabled)",
+	}
+	DiffSyncUnsignedFlag = cli.BoolFlag{
+		Name:  "diffsync.unsigned",
+		Usage: "Accept unsigned diff layers from producers which do not sign them yet",
+	}
 	PipeCommitFlag = cli.BoolFlag{
 		Name:  "pipecommit",
 		Usage: "Enable MPT pipeline commit, it will improve syncing performance. It is an experimental feature(default is false)",
 	}
+	ParallelTxFlag = cli.BoolFlag{
+		Name:  "parallel",
+		Usage: "Enable optimistic parallel execution of block transactions. It is an experimental feature(default is false)",
+	}
+	ParallelTxNumFlag = cli.IntFlag{
+		Name:  "parallel.num",
+		Usage: "Number of workers executing transactions in parallel (default = number of CPUs)",
+	}
 	RangeLimitFlag = cli.BoolFlag{
 		Name:  "rangelimit",
 		Usage: "Enable 5000 blocks limit for range query",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to create new databases with ('leveldb' or 'pebble', default = leveldb)",
+	}
 	AncientFlag = DirectoryFlag{
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	AncientCompressionFlag = cli.StringFlag{
+		Name:  "ancient.compression",
+		Usage: "Compression of the ancient block bodies and receipts ('snappy' or 'zstd'), stored ones are kept as is (default = as stored, snappy for new databases)",
+	}
 	DiffFlag = DirectoryFlag{
 		Name:  "datadir.diff",
 		Usage: "Data directory for difflayer segments (default = inside chaindata)",
@@ -230,6 +258,10 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	HistoryBlocksFlag = cli.Uint64Flag{
+		Name:  "history.blocks",
+		Usage: "Number of recent blocks to retain in the ancient store, older blocks are pruned online (0 = entire chain)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -248,6 +280,22 @@ var (
 		Usage: "The layer of tries trees that keep in memory",
 		Value: 128,
 	}
+	StateRetentionFlag = cli.Uint64Flag{
+		Name:  "state.retention",
+		Usage: "Number of recent blocks whose state tries are retained on disk, older trie nodes are pruned online (0 = disabled)",
+	}
+	StateHistoryFlag = cli.BoolFlag{
+		Name:  "state.history",
+		Usage: "Persist reverse state diffs of the blocks to serve historical state queries without an archive node (requires snapshots)",
+	}
+	TraceIndexFlag = cli.BoolFlag{
+		Name:  "trace.index",
+		Usage: "Index the addresses of the internal calls of transactions in the background, to speed up trace_filter",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "log.index",
+		Usage: "Index the blocks by log address and topics in the background, to speed up log filtering over large ranges (requires the full chain history)",
+	}
 	OverrideBerlinFlag = cli.Uint64Flag{
 		Name:  "override.berlin",
 		Usage: "Manually specify Berlin fork-block, overriding the bundled setting",
@@ -407,6 +455,10 @@ var (
 		Usage: "The number of blocks should be persisted in db (default = 86400)",
 		Value: uint64(86400),
 	}
+	FreezeDiffFlag = cli.BoolFlag{
+		Name:  "persistdiff.ancient",
+		Usage: "Keep persisted diff layers until they are frozen into the ancient store along with their blocks",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -456,7 +508,7 @@ var (
 	}
 	MinerDelayLeftoverFlag = cli.DurationFlag{
 		Name:  "miner.delayleftover",
-		Usage: "Time interval to for broadcast block",
+		Usage: "Time reserved at the end of the slot for sealing and broadcasting the block",
 		Value: ethconfig.Defaults.Miner.DelayLeftOver,
 	}
 	MinerNoVerfiyFlag = cli.BoolFlag{
@@ -498,6 +550,14 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCLogBlockRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logrange",
+		Usage: "Sets a cap on the number of blocks a single eth_getLogs query may span (0 = no cap)",
+	}
+	RPCLogResultCapFlag = cli.Uint64Flag{
+		Name:  "rpc.logcap",
+		Usage: "Sets a cap on the number of logs a single eth_getLogs query may return (0 = no cap)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -556,7 +616,7 @@ var (
 	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
-		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
+		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well, subscriptions are served on the WS-RPC endpoint.",
 	}
 	GraphQLCORSDomainFlag = cli.StringFlag{
 		Name:  "graphql.corsdomain",
@@ -1210,6 +1270,21 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		dbEngine := ctx.GlobalString(DBEngineFlag.Name)
+		if dbEngine != rawdb.DBLeveldb && dbEngine != rawdb.DBPebble {
+			Fatalf("Invalid choice for db.engine '%s', allowed '%s' or '%s'", dbEngine, rawdb.DBLeveldb, rawdb.DBPebble)
+		}
+		cfg.DBEngine = dbEngine
+	}
+	if ctx.GlobalIsSet(AncientCompressionFlag.Name) {
+		compression := ctx.GlobalString(AncientCompressionFlag.Name)
+		if compression != rawdb.FreezerCompressionSnappy && compression != rawdb.FreezerCompressionZstd {
+			Fatalf("Invalid choice for ancient.compression '%s', allowed '%s' or '%s'", compression, rawdb.FreezerCompressionSnappy, rawdb.FreezerCompressionZstd)
+		}
+		cfg.AncientCompression = compression
+	}
+
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
@@ -1504,6 +1579,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(DiffBlockFlag.Name) {
 		cfg.DiffBlock = ctx.GlobalUint64(DiffBlockFlag.Name)
 	}
+	if ctx.GlobalIsSet(FreezeDiffFlag.Name) {
+		cfg.FreezeDiff = ctx.GlobalBool(FreezeDiffFlag.Name)
+	}
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -1519,9 +1597,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(DiffSyncFlag.Name) {
 		cfg.DiffSync = ctx.GlobalBool(DiffSyncFlag.Name)
 	}
+	if ctx.GlobalIsSet(DiffSyncSampleFlag.Name) {
+		cfg.DiffSyncSample = ctx.GlobalFloat64(DiffSyncSampleFlag.Name)
+	}
+	if ctx.GlobalIsSet(DiffSyncHorizonFlag.Name) {
+		cfg.DiffSyncHorizon = ctx.GlobalDuration(DiffSyncHorizonFlag.Name)
+	}
+	if ctx.GlobalIsSet(DiffSyncUnsignedFlag.Name) {
+		cfg.DiffSyncUnsigned = ctx.GlobalBool(DiffSyncUnsignedFlag.Name)
+	}
 	if ctx.GlobalIsSet(PipeCommitFlag.Name) {
 		cfg.PipeCommit = ctx.GlobalBool(PipeCommitFlag.Name)
 	}
+	if ctx.GlobalIsSet(ParallelTxFlag.Name) {
+		cfg.ParallelTxs = ctx.GlobalBool(ParallelTxFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelTxNumFlag.Name) {
+		cfg.ParallelTxNum = ctx.GlobalInt(ParallelTxNumFlag.Name)
+	}
 	if ctx.GlobalIsSet(RangeLimitFlag.Name) {
 		cfg.RangeLimit = ctx.GlobalBool(RangeLimitFlag.Name)
 	}
@@ -1534,6 +1627,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(HistoryBlocksFlag.Name) {
+		cfg.BlockHistory = ctx.GlobalUint64(HistoryBlocksFlag.Name)
+	}
+	if cfg.BlockHistory != 0 && (cfg.TxLookupLimit == 0 || cfg.TxLookupLimit > cfg.BlockHistory) {
+		log.Warn("Limiting transaction index to the retained block history", "blocks", cfg.BlockHistory)
+		cfg.TxLookupLimit = cfg.BlockHistory
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1549,6 +1649,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TriesInMemoryFlag.Name) {
 		cfg.TriesInMemory = ctx.GlobalUint64(TriesInMemoryFlag.Name)
 	}
+	if ctx.GlobalIsSet(StateRetentionFlag.Name) {
+		cfg.StateRetention = ctx.GlobalUint64(StateRetentionFlag.Name)
+		if cfg.StateRetention != 0 && ctx.GlobalString(GCModeFlag.Name) == "archive" {
+			log.Warn("Disable online state pruning for archive node")
+			cfg.StateRetention = 0
+		}
+	}
+	if ctx.GlobalIsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.GlobalBool(StateHistoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceIndexFlag.Name) {
+		cfg.TraceIndex = ctx.GlobalBool(TraceIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if cfg.LogIndex && cfg.BlockHistory != 0 {
+		Fatalf("--%s requires the full chain history, it can't be used with --%s", LogIndexFlag.Name, HistoryBlocksFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
 		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
 	}
@@ -1587,6 +1706,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCLogBlockRangeFlag.Name) {
+		cfg.RPCLogBlockRange = ctx.GlobalUint64(RPCLogBlockRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogResultCapFlag.Name) {
+		cfg.RPCLogResultCap = ctx.GlobalUint64(RPCLogResultCapFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -1657,6 +1782,14 @@ func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
 	}
 }
 
+// registerTraceExports adds the runner of the chain trace exports started over
+// RPC to the stack, confining them to the traces directory of the node.
+func registerTraceExports(stack *node.Node) *tracers.ExportService {
+	exports := tracers.NewExportService(stack.ResolvePath("traces"))
+	stack.RegisterLifecycle(exports)
+	return exports
+}
+
 // RegisterEthService adds an Ethereum client to the stack.
 // The second return value is the full node instance, which may be nil if the
 // node is running as a light client.
@@ -1666,7 +1799,7 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 		if err != nil {
 			Fatalf("Failed to register the Ethereum service: %v", err)
 		}
-		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend))
+		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend, registerTraceExports(stack)))
 		return backend.ApiBackend, nil
 	}
 	backend, err := eth.New(stack, cfg)
@@ -1679,7 +1812,10 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 			Fatalf("Failed to create the LES server: %v", err)
 		}
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend, registerTraceExports(stack)))
+	if cfg.TraceIndex {
+		stack.RegisterLifecycle(tracers.NewTraceIndexer(backend.APIBackend))
+	}
 	return backend.APIBackend, backend
 }
 
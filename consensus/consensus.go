@@ -145,4 +145,6 @@ type PoSA interface {
 	EnoughDistance(chain ChainReader, header *types.Header) bool
 	IsLocalBlock(header *types.Header) bool
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
+	SignDiffLayer(header *types.Header, diff *types.DiffLayer) error
+	VerifyDiffLayer(chain ChainHeaderReader, header *types.Header, diff *types.DiffLayer) error
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common/systemcontract"
@@ -45,6 +46,7 @@ import (
 const (
 	inMemorySnapshots  = 128  // Number of recent snapshots to keep in memory
 	inMemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inMemorySealed     = 128  // Number of recently sealed blocks to keep diff layer signers for
 
 	checkpointInterval = 1024        // Number of blocks after which to save the snapshot to the database
 	defaultEpochLength = uint64(100) // Default number of blocks of checkpoint to update validatorSet from contract
@@ -126,6 +128,10 @@ var (
 	// errCoinBaseMisMatch is returned if a header's coinbase do not match with signature
 	errCoinBaseMisMatch = errors.New("coinbase do not match with signature")
 
+	// errDiffSignerMismatch is returned if a diff layer is not signed by the
+	// producer of its block.
+	errDiffSignerMismatch = errors.New("diff layer not signed by block producer")
+
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
@@ -187,6 +193,7 @@ type Parlia struct {
 
 	recentSnaps *lru.ARCCache // Snapshots for recent block to speed up
 	signatures  *lru.ARCCache // Signatures of recent blocks to speed up mining
+	sealed      *lru.ARCCache // Signers of the blocks sealed locally, vouching for their diff layers
 
 	signer types.Signer
 
@@ -228,6 +235,10 @@ func New(
 	if err != nil {
 		panic(err)
 	}
+	sealed, err := lru.NewARC(inMemorySealed)
+	if err != nil {
+		panic(err)
+	}
 	vABI, err := abi.JSON(strings.NewReader(validatorSetABI))
 	if err != nil {
 		panic(err)
@@ -244,6 +255,7 @@ func New(
 		ethAPI:          ethAPI,
 		recentSnaps:     recentSnaps,
 		signatures:      signatures,
+		sealed:          sealed,
 		validatorSetABI: vABI,
 		slashABI:        sABI,
 		signer:          types.NewEIP155Signer(chainConfig.ChainID),
@@ -279,6 +291,23 @@ func (p *Parlia) Author(header *types.Header) (common.Address, error) {
 	return header.Coinbase, nil
 }
 
+// InTurn returns whether the header was sealed by the validator whose turn it was.
+func (p *Parlia) InTurn(header *types.Header) bool {
+	return header.Difficulty != nil && header.Difficulty.Cmp(diffInTurn) == 0
+}
+
+// EpochValidators returns the validator set listed in the extra-data of an epoch
+// block, or nil if the header is not an epoch block.
+func (p *Parlia) EpochValidators(header *types.Header) ([]common.Address, error) {
+	if header.Number.Uint64()%p.config.Epoch != 0 {
+		return nil, nil
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	return ParseValidators(header.Extra[extraVanity : len(header.Extra)-extraSeal])
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (p *Parlia) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
 	return p.verifyHeader(chain, header, nil)
@@ -519,6 +548,55 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 	return snap, err
 }
 
+// Checkpoint retrieves the header of the last checkpoint at or below the given
+// header, together with the encoded validator snapshot at the checkpoint. The
+// snapshot allows verifying the headers following the checkpoint without the
+// chain preceding it.
+func (p *Parlia) Checkpoint(chain consensus.ChainHeaderReader, header *types.Header) (*types.Header, []byte, error) {
+	number := header.Number.Uint64() - header.Number.Uint64()%checkpointInterval
+
+	checkpoint := chain.GetHeaderByNumber(number)
+	if checkpoint == nil {
+		return nil, nil, consensus.ErrUnknownAncestor
+	}
+	snap, err := p.snapshot(chain, number, checkpoint.Hash(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := json.Marshal(snap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checkpoint, blob, nil
+}
+
+// ImportCheckpoint stores the encoded validator snapshot of a checkpoint header,
+// as retrieved by Checkpoint, trusting it to verify the subsequent headers.
+func (p *Parlia) ImportCheckpoint(checkpoint *types.Header, blob []byte) error {
+	snap := new(Snapshot)
+	if err := json.Unmarshal(blob, snap); err != nil {
+		return err
+	}
+	if snap.Number != checkpoint.Number.Uint64() || snap.Hash != checkpoint.Hash() {
+		return fmt.Errorf("checkpoint mismatch: have #%d [%x…], want #%d [%x…]", snap.Number, snap.Hash[:4], checkpoint.Number, checkpoint.Hash().Bytes()[:4])
+	}
+	if snap.Number%checkpointInterval != 0 {
+		return fmt.Errorf("block #%d is not a checkpoint", snap.Number)
+	}
+	if len(snap.Validators) == 0 {
+		return errors.New("checkpoint without validators")
+	}
+	snap.config = p.config
+	snap.sigCache = p.signatures
+	snap.ethAPI = p.ethAPI
+
+	if err := snap.store(p.db); err != nil {
+		return err
+	}
+	p.recentSnaps.Add(snap.Hash, snap)
+	return nil
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (p *Parlia) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
@@ -871,6 +949,10 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
 
+	// Remember the signer of the block, its diff layer is only derived once the
+	// sealed block is written and gets signed by the same key then.
+	p.sealed.Add(header.Hash(), &sealer{val: val, signFn: signFn})
+
 	// Wait until sealing is terminated or delay timeout.
 	log.Info("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
 	go func() {
@@ -940,6 +1022,61 @@ func (p *Parlia) IsLocalBlock(header *types.Header) bool {
 	return p.val == header.Coinbase
 }
 
+// sealer is the signing key a block was sealed with.
+type sealer struct {
+	val    common.Address
+	signFn SignerFn
+}
+
+// SignDiffLayer signs the diff layer of a block sealed by this engine with the
+// key it was sealed with, vouching for the state changes it carries. Diff layers
+// of any other blocks are left untouched.
+func (p *Parlia) SignDiffLayer(header *types.Header, diff *types.DiffLayer) error {
+	cached, ok := p.sealed.Get(header.Hash())
+	if !ok {
+		return nil
+	}
+	s := cached.(*sealer)
+	sig, err := s.signFn(accounts.Account{Address: s.val}, accounts.MimetypeParlia, types.DiffLayerSigData(header.Hash(), header.Root, diff.Hash()))
+	if err != nil {
+		return err
+	}
+	diff.Signature = sig
+	return nil
+}
+
+// VerifyDiffLayer checks that the diff layer of the given block is signed by
+// the producer of the block, who has to be an authorized validator.
+func (p *Parlia) VerifyDiffLayer(chain consensus.ChainHeaderReader, header *types.Header, diff *types.DiffLayer) error {
+	if len(diff.Signature) != extraSeal {
+		return errMissingSignature
+	}
+	if diff.BlockHash != header.Hash() {
+		return errBlockHashInconsistent
+	}
+	pubkey, err := crypto.Ecrecover(crypto.Keccak256(types.DiffLayerSigData(diff.BlockHash, header.Root, diff.Hash())), diff.Signature)
+	if err != nil {
+		return err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	if signer != header.Coinbase {
+		return errDiffSignerMismatch
+	}
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Validators[signer]; !ok {
+		return errUnauthorizedValidator
+	}
+	return nil
+}
+
 func (p *Parlia) SignRecently(chain consensus.ChainReader, parent *types.Header) (bool, error) {
 	snap, err := p.snapshot(chain, parent.Number.Uint64(), parent.ParentHash, nil)
 	if err != nil {
@@ -1,11 +1,18 @@
 package parlia
 
 import (
+	"crypto/ecdsa"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestImpactOfValidatorOutOfService(t *testing.T) {
@@ -144,3 +151,112 @@ func randomAddress() common.Address {
 	rand.Read(addrBytes)
 	return common.BytesToAddress(addrBytes)
 }
+
+// Tests that diff layers signed by the block producer verify, while tampered
+// ones or ones signed by anyone else are rejected.
+func TestDiffLayerSignature(t *testing.T) {
+	newKey := func() (*ecdsa.PrivateKey, common.Address) {
+		key, _ := crypto.GenerateKey()
+		return key, crypto.PubkeyToAddress(key.PublicKey)
+	}
+	signFn := func(key *ecdsa.PrivateKey) SignerFn {
+		return func(_ accounts.Account, _ string, data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), key)
+		}
+	}
+	var (
+		valKey, val       = newKey()
+		otherKey, other   = newKey()
+		outsiderKey, outs = newKey()
+		config            = *params.TestChainConfig
+	)
+	config.Parlia = &params.ParliaConfig{Period: 3, Epoch: 200}
+	engine := New(&config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	parent := &types.Header{Number: big.NewInt(9)}
+	engine.recentSnaps.Add(parent.Hash(), newSnapshot(engine.config, engine.signatures, 9, parent.Hash(), []common.Address{val, other}, nil))
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(10),
+		Coinbase:   val,
+		Root:       common.Hash{0x01},
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	newDiff := func() *types.DiffLayer {
+		return &types.DiffLayer{
+			BlockHash: header.Hash(),
+			Number:    10,
+			Accounts:  []types.DiffAccount{{Account: common.Address{0xaa}, Blob: []byte{1}}},
+		}
+	}
+	// Blocks not sealed locally are not signed, even by their producer
+	diff := newDiff()
+	engine.Authorize(val, signFn(valKey), nil)
+	if err := engine.SignDiffLayer(header, diff); err != nil || diff.Signature != nil {
+		t.Fatalf("signed diff layer of foreign block: sig %x, err %v", diff.Signature, err)
+	}
+	if err := engine.VerifyDiffLayer(nil, header, diff); err != errMissingSignature {
+		t.Fatalf("unsigned diff layer: have %v, want %v", err, errMissingSignature)
+	}
+	engine.sealed.Add(header.Hash(), &sealer{val: val, signFn: signFn(valKey)})
+	if err := engine.SignDiffLayer(header, diff); err != nil {
+		t.Fatalf("failed to sign diff layer: %v", err)
+	}
+	if err := engine.VerifyDiffLayer(nil, header, diff); err != nil {
+		t.Fatalf("failed to verify diff layer: %v", err)
+	}
+	// Tampered state changes
+	tampered := newDiff()
+	tampered.Accounts[0].Blob = []byte{2}
+	tampered.Signature = diff.Signature
+	if err := engine.VerifyDiffLayer(nil, header, tampered); err != errDiffSignerMismatch {
+		t.Fatalf("tampered diff layer: have %v, want %v", err, errDiffSignerMismatch)
+	}
+	// Signed by a validator other than the producer
+	forged := newDiff()
+	engine.sealed.Add(header.Hash(), &sealer{val: val, signFn: signFn(otherKey)})
+	engine.SignDiffLayer(header, forged)
+	if err := engine.VerifyDiffLayer(nil, header, forged); err != errDiffSignerMismatch {
+		t.Fatalf("forged diff layer: have %v, want %v", err, errDiffSignerMismatch)
+	}
+	// Produced and signed by a non validator
+	header.Coinbase = outs
+	unauthorized := newDiff()
+	engine.sealed.Add(header.Hash(), &sealer{val: outs, signFn: signFn(outsiderKey)})
+	engine.SignDiffLayer(header, unauthorized)
+	if err := engine.VerifyDiffLayer(nil, header, unauthorized); err != errUnauthorizedValidator {
+		t.Fatalf("unauthorized diff layer: have %v, want %v", err, errUnauthorizedValidator)
+	}
+}
+
+// Tests that the validator set is only parsed out of epoch blocks.
+func TestEpochValidators(t *testing.T) {
+	config := *params.TestChainConfig
+	config.Parlia = &params.ParliaConfig{Period: 3, Epoch: 200}
+	engine := New(&config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	validators := []common.Address{randomAddress(), randomAddress()}
+	extra := make([]byte, extraVanity)
+	for _, validator := range validators {
+		extra = append(extra, validator.Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+
+	have, err := engine.EpochValidators(&types.Header{Number: big.NewInt(400), Extra: extra})
+	if err != nil {
+		t.Fatalf("failed to parse epoch validators: %v", err)
+	}
+	if len(have) != len(validators) || have[0] != validators[0] || have[1] != validators[1] {
+		t.Fatalf("validator set mismatch: have %v, want %v", have, validators)
+	}
+	if have, err := engine.EpochValidators(&types.Header{Number: big.NewInt(401), Extra: make([]byte, extraVanity+extraSeal)}); have != nil || err != nil {
+		t.Fatalf("non-epoch block returned validators: %v, %v", have, err)
+	}
+	if _, err := engine.EpochValidators(&types.Header{Number: big.NewInt(400), Extra: extra[:extraVanity]}); err != errMissingSignature {
+		t.Fatalf("truncated extra-data: have %v, want %v", err, errMissingSignature)
+	}
+	if !engine.InTurn(&types.Header{Difficulty: new(big.Int).Set(diffInTurn)}) || engine.InTurn(&types.Header{Difficulty: new(big.Int).Set(diffNoTurn)}) {
+		t.Fatalf("in-turn detection mismatch")
+	}
+}
@@ -18,11 +18,13 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	mrand "math/rand"
+	"runtime"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -39,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -77,6 +80,9 @@ var (
 	blockReorgDropMeter     = metrics.NewRegisteredMeter("chain/reorg/drop", nil)
 	blockReorgInvalidatedTx = metrics.NewRegisteredMeter("chain/reorg/invalidTx", nil)
 
+	badDiffLayerMeter = metrics.NewRegisteredMeter("chain/difflayer/bad", nil)
+	blockCatchUpMeter = metrics.NewRegisteredMeter("chain/catchup/blocks", nil)
+
 	errInsertionInterrupted        = errors.New("insertion is interrupted")
 	errStateRootVerificationFailed = errors.New("state root verification failed")
 )
@@ -96,10 +102,13 @@ const (
 
 	diffLayerFreezerRecheckInterval = 3 * time.Second
 	diffLayerPruneRecheckInterval   = 1 * time.Second // The interval to prune unverified diff layers
+	historyPruneRecheckInterval     = 1 * time.Minute // The interval to truncate the ancient chain history
+	statePruneRecheckInterval       = 1 * time.Minute // The interval to release the tries beyond the state retention
 	maxDiffQueueDist                = 2048            // Maximum allowed distance from the chain head to queue diffLayers
 	maxDiffLimit                    = 2048            // Maximum number of unique diff layers a peer may have responded
 	maxDiffForkDist                 = 11              // Maximum allowed backward distance from the chain head
 	maxDiffLimitForBroadcast        = 128             // Maximum number of unique diff layers a peer may have broadcasted
+	stateIndexPruneLimit            = 64              // Maximum number of blocks whose state index is pruned per block indexed
 
 	rewindBadBlockInterval = 1 * time.Second
 
@@ -141,6 +150,8 @@ type CacheConfig struct {
 	SnapshotLimit      int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages          bool          // Whether to store preimage of trie key to the disk
 	TriesInMemory      uint64        // How many tries keeps in memory
+	StateRetention     uint64        // Number of blocks whose flushed tries are kept on disk, 0 to disable online state pruning
+	StateHistory       bool          // Whether to persist the reverse state diffs of blocks to serve historical states
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -195,6 +206,10 @@ type BlockChain struct {
 	txLookupLimit uint64
 	triesInMemory uint64
 
+	// historyBlocks is the number of recent blocks whose bodies, receipts and
+	// headers are retained in the ancient store, 0 to retain the full history.
+	historyBlocks uint64
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -220,12 +235,15 @@ type BlockChain struct {
 	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
 	badBlockCache *lru.Cache     // Cache for the blocks that failed to pass MPT root verification
 
+	stateIndexTail *uint64 // Oldest block whose state is servable from the state index, protected by chainmu
+
 	// trusted diff layers
 	diffLayerCache             *lru.Cache   // Cache for the diffLayers
 	diffLayerRLPCache          *lru.Cache   // Cache for the rlp encoded diffLayers
 	diffQueue                  *prque.Prque // A Priority queue to store recent diff layer
 	diffQueueBuffer            chan *types.DiffLayer
 	diffLayerFreezerBlockLimit uint64
+	freezeDiffLayer            bool // Whether to keep persisted diff layers until frozen into the ancient store
 
 	// untrusted diff layers
 	diffMux               sync.RWMutex
@@ -247,6 +265,10 @@ type BlockChain struct {
 	vmConfig   vm.Config
 	pipeCommit bool
 
+	parallelTxs    int     // Number of workers executing block transactions in parallel, 0 if disabled
+	diffSampleRate float64 // Fraction of diff synced blocks fully executed, 0 for the default
+	diffUnsigned   bool    // Whether unsigned diff layers are accepted from peers not yet signing them
+
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
 }
@@ -284,6 +306,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			Cache:     cacheConfig.TrieCleanLimit,
 			Journal:   cacheConfig.TrieCleanJournal,
 			Preimages: cacheConfig.Preimages,
+			Refcount:  cacheConfig.StateRetention > 0 && !cacheConfig.TrieDirtyDisabled,
 		}),
 		triesInMemory:         cacheConfig.TriesInMemory,
 		quit:                  make(chan struct{}),
@@ -471,6 +494,27 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		go bc.trustedDiffLayerLoop()
 	}
 	go bc.untrustedDiffLayerPruneLoop()
+	if bc.historyBlocks > 0 {
+		bc.wg.Add(1)
+		go bc.historyPruneLoop()
+	}
+	// If online state pruning is enabled, release the tries beyond the retention
+	if bc.cacheConfig.StateRetention > 0 && !bc.cacheConfig.TrieDirtyDisabled {
+		if bc.cacheConfig.StateRetention < bc.triesInMemory {
+			log.Warn("Sanitizing invalid state retention", "provided", bc.cacheConfig.StateRetention, "updated", bc.triesInMemory)
+			bc.cacheConfig.StateRetention = bc.triesInMemory
+		}
+		bc.wg.Add(1)
+		go bc.statePruneLoop()
+	}
+	// State histories are derived from the snapshot diff layers
+	if bc.cacheConfig.StateHistory && bc.snaps == nil {
+		log.Warn("Disabling state history, snapshots are not enabled")
+		bc.cacheConfig.StateHistory = false
+	}
+	if bc.cacheConfig.StateHistory {
+		bc.stateIndexTail = rawdb.ReadStateIndexTail(bc.db)
+	}
 	if bc.pipeCommit {
 		// check current block and rewind invalid one
 		go bc.rewindInvalidHeaderBlockLoop()
@@ -732,6 +776,10 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 	}
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db ethdb.KeyValueWriter, hash common.Hash, num uint64) {
+		// Drop the state index entries while the state history is still around
+		if bc.cacheConfig.StateHistory {
+			bc.unindexStateHistory(db, hash, num)
+		}
 		// Ignore the error here since light client won't hit this path
 		frozen, _ := bc.db.Ancients()
 		if num+1 <= frozen {
@@ -1023,11 +1071,19 @@ func (bc *BlockChain) GetDiffLayerRLP(blockHash common.Hash) rlp.RawValue {
 	}
 
 	// fallback to disk
-	diffStore := bc.db.DiffStore()
-	if diffStore == nil {
+	if diffStore := bc.db.DiffStore(); diffStore != nil {
+		rawData := rawdb.ReadDiffLayerRLP(diffStore, blockHash)
+		if len(rawData) != 0 {
+			bc.diffLayerRLPCache.Add(blockHash, rawData)
+			return rawData
+		}
+	}
+	// fallback to the ancient store, which only holds canonical diff layers
+	number := bc.hc.GetBlockNumber(blockHash)
+	if number == nil || rawdb.ReadCanonicalHash(bc.db, *number) != blockHash {
 		return nil
 	}
-	rawData := rawdb.ReadDiffLayerRLP(diffStore, blockHash)
+	rawData := rawdb.ReadAncientDiffLayerRLP(bc.db, *number)
 	if len(rawData) != 0 {
 		bc.diffLayerRLPCache.Add(blockHash, rawData)
 	}
@@ -1147,6 +1203,13 @@ func (bc *BlockChain) GetBlockByNumber(number uint64) *types.Block {
 	return bc.GetBlock(hash, number)
 }
 
+// HistoryPruned reports whether the canonical block with the given number has
+// been discarded from the tail of the ancient store. The genesis block is never
+// discarded.
+func (bc *BlockChain) HistoryPruned(number uint64) bool {
+	return number > 0 && number < bc.db.AncientOffSet()
+}
+
 // GetReceiptsByHash retrieves the receipts for all transactions in a given block.
 func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	if receipts, ok := bc.receiptsCache.Get(hash); ok {
@@ -1251,14 +1314,14 @@ func (bc *BlockChain) Stop() {
 				recent := bc.GetBlockByNumber(number - offset)
 
 				log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
-				if err := triedb.Commit(recent.Root(), true, nil); err != nil {
+				if err := triedb.CommitRetained(recent.Root(), recent.NumberU64(), true); err != nil {
 					log.Error("Failed to commit recent state trie", "err", err)
 				}
 			}
 		}
 		if snapBase != (common.Hash{}) {
 			log.Info("Writing snapshot state to disk", "root", snapBase)
-			if err := triedb.Commit(snapBase, true, nil); err != nil {
+			if err := triedb.CommitRetained(snapBase, bc.CurrentBlock().NumberU64(), true); err != nil {
 				log.Error("Failed to commit recent state trie", "err", err)
 			}
 		}
@@ -1662,6 +1725,8 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 		if err := bc.reorg(current, block); err != nil {
 			return err
 		}
+	} else if bc.cacheConfig.StateHistory {
+		bc.indexStateHistory(bc.db, block)
 	}
 	bc.writeHeadBlock(block)
 	return nil
@@ -1758,7 +1823,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 								log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/float64(bc.triesInMemory))
 							}
 							// Flush an entire trie and restart the counters
-							triedb.Commit(header.Root, true, nil)
+							triedb.CommitRetained(header.Root, chosen, true)
 							lastWrite = chosen
 							bc.gcproc = 0
 						}
@@ -1790,9 +1855,33 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		diffLayer.Receipts = receipts
 		diffLayer.BlockHash = block.Hash()
 		diffLayer.Number = block.NumberU64()
+
+		// Vouch for the diff layers of the blocks we sealed, the engine leaves
+		// all others unsigned
+		if posa, ok := bc.engine.(consensus.PoSA); ok {
+			if err := posa.SignDiffLayer(block.Header(), diffLayer); err != nil {
+				log.Warn("Failed to sign diff layer", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+			}
+		}
+		if !state.IsLightProcessed() {
+			bc.checkUnTrustedDiffLayers(block, diffLayer)
+		}
+		// Relay the layer signed by the producer if we received one, our own
+		// one is unsigned and would be rejected by diff syncing peers.
+		if len(diffLayer.Signature) == 0 {
+			if signed := bc.signedDiffLayer(block, diffLayer); signed != nil {
+				diffLayer = signed
+			}
+		}
 		bc.cacheDiffLayer(diffLayer)
 	}
-
+	// Persist the reverse state diff of the block, derived while the snapshot
+	// layer of its parent is guaranteed to be live
+	if bc.cacheConfig.StateHistory {
+		if history := bc.buildStateHistory(block); history != nil {
+			rawdb.WriteStateHistory(bc.db, block.Hash(), block.NumberU64(), history)
+		}
+	}
 	wg.Wait()
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
@@ -1828,6 +1917,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	}
 	// Set new head.
 	if status == CanonStatTy {
+		if bc.cacheConfig.StateHistory {
+			bc.indexStateHistory(bc.db, block)
+		}
 		bc.writeHeadBlock(block)
 	}
 	bc.futureBlocks.Remove(block.Hash())
@@ -1921,6 +2013,150 @@ func (bc *BlockChain) InsertChainWithoutSealVerification(block *types.Block) (in
 	return n, err
 }
 
+// InsertChainWithDiffs imports a batch of blocks by applying their diff layers
+// directly to the snapshot and the state trie instead of executing them. The
+// headers are still verified by the consensus engine, and the state root and
+// receipts resulting from every diff layer are checked against the block, so
+// the diff layers don't need to be trusted.
+//
+// Blocks are imported up to the first one whose diff layer is missing or fails
+// to apply, the index of which is returned together with the error so that the
+// caller can import the rest of the batch by execution.
+func (bc *BlockChain) InsertChainWithDiffs(chain types.Blocks, diffs []*types.DiffLayer) (int, error) {
+	// Sanity check that we have something meaningful to import
+	if len(chain) == 0 {
+		return 0, nil
+	}
+	if len(chain) != len(diffs) {
+		return 0, fmt.Errorf("diff layer count mismatch: have %d, want %d", len(diffs), len(chain))
+	}
+	bc.blockProcFeed.Send(true)
+	defer bc.blockProcFeed.Send(false)
+
+	// Do a sanity check that the provided chain is actually ordered and linked
+	for i := 1; i < len(chain); i++ {
+		block, prev := chain[i], chain[i-1]
+		if block.NumberU64() != prev.NumberU64()+1 || block.ParentHash() != prev.Hash() {
+			log.Error("Non contiguous block insert", "number", block.Number(), "hash", block.Hash(),
+				"parent", block.ParentHash(), "prevnumber", prev.Number(), "prevhash", prev.Hash())
+
+			return 0, fmt.Errorf("non contiguous insert: item %d is #%d [%x..], item %d is #%d [%x..] (parent [%x..])", i-1, prev.NumberU64(),
+				prev.Hash().Bytes()[:4], i, block.NumberU64(), block.Hash().Bytes()[:4], block.ParentHash().Bytes()[:4])
+		}
+	}
+	// Pre-checks passed, start the diff layer imports
+	bc.wg.Add(1)
+	bc.chainmu.Lock()
+	n, err := bc.insertChainWithDiffs(chain, diffs)
+	bc.chainmu.Unlock()
+	bc.wg.Done()
+
+	return n, err
+}
+
+// insertChainWithDiffs is the internal implementation of InsertChainWithDiffs,
+// which assumes that the chain mutex is held.
+func (bc *BlockChain) insertChainWithDiffs(chain types.Blocks, diffs []*types.DiffLayer) (int, error) {
+	// If the chain is terminating, don't even bother starting up
+	if atomic.LoadInt32(&bc.procInterrupt) == 1 {
+		return 0, nil
+	}
+	var (
+		stats     = insertStats{startTime: mclock.Now()}
+		lastCanon *types.Block
+		processor = NewLightStateProcessor(bc.chainConfig, bc, bc.engine)
+	)
+	// Fire a single chain head event if we've progressed the chain
+	defer func() {
+		if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
+			bc.chainHeadFeed.Send(ChainHeadEvent{lastCanon})
+		}
+	}()
+	// Start the parallel header verifier
+	headers := make([]*types.Header, len(chain))
+	seals := make([]bool, len(chain))
+
+	for i, block := range chain {
+		headers[i] = block.Header()
+		seals[i] = true
+	}
+	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
+	defer close(abort)
+
+	it := newInsertIterator(chain, results, bc.validator)
+	for block, err := it.next(); block != nil; block, err = it.next() {
+		// If the chain is terminating, stop processing blocks
+		if bc.insertStopped() {
+			log.Debug("Abort during diff layer import")
+			return it.index, errInsertionInterrupted
+		}
+		// Skip the blocks we already have on the canonical chain, anything
+		// else is left for the regular import to deal with
+		if err == ErrKnownBlock && bc.GetCanonicalHash(block.NumberU64()) == block.Hash() {
+			stats.ignored++
+			continue
+		}
+		if err != nil {
+			return it.index, err
+		}
+		if BadHashes[block.Hash()] {
+			bc.reportBlock(block, nil, ErrBlacklistedHash)
+			return it.index, ErrBlacklistedHash
+		}
+		diff := diffs[it.index]
+		if diff == nil || diff.BlockHash != block.Hash() {
+			return it.index, ErrDiffLayerNotFound
+		}
+		start := time.Now()
+
+		parent := it.previous()
+		if parent == nil {
+			parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+		}
+		statedb, err := state.New(parent.Root, bc.stateCache, bc.snaps)
+		if err != nil {
+			return it.index, err
+		}
+		statedb.SetExpectedStateRoot(block.Root())
+		bc.updateHighestVerifiedHeader(block.Header())
+
+		if err := diff.Receipts.DeriveFields(bc.chainConfig, block.Hash(), block.NumberU64(), block.Transactions()); err != nil {
+			return it.index, err
+		}
+		// Apply the diff layer, the state root and the receipts are validated
+		// against the block before anything is written
+		receipts, logs, usedGas, err := processor.LightProcess(diff, block, statedb)
+		if err != nil {
+			badDiffLayerMeter.Mark(1)
+			bc.removeDiffLayers(diff.DiffHash)
+			return it.index, err
+		}
+		bc.cacheReceipts(block.Hash(), receipts)
+		bc.cacheBlock(block.Hash(), block)
+		proctime := time.Since(start)
+
+		substart := time.Now()
+		status, err := bc.writeBlockWithState(block, receipts, logs, statedb, false)
+		if err != nil {
+			return it.index, err
+		}
+		blockWriteTimer.Update(time.Since(substart))
+		blockInsertTimer.UpdateSince(start)
+		blockCatchUpMeter.Mark(1)
+
+		if status == CanonStatTy {
+			lastCanon = block
+			bc.gcproc += proctime
+		}
+		stats.processed++
+		stats.usedGas += usedGas
+
+		dirty, _ := bc.stateCache.TrieDB().Size()
+		stats.report(chain, it.index, dirty)
+	}
+	return it.index, nil
+}
+
 // insertChain is the internal implementation of InsertChain, which assumes that
 // 1) chains are contiguous, and 2) The chain mutex is held.
 //
@@ -2515,6 +2751,15 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	for _, tx := range types.TxDifference(deletedTxs, addedTxs) {
 		rawdb.DeleteTxLookupEntry(indexesBatch, tx.Hash())
 	}
+	// Replace the state index entries of the old chain with the new ones
+	if bc.cacheConfig.StateHistory {
+		for _, block := range oldChain {
+			bc.unindexStateHistory(indexesBatch, block.Hash(), block.NumberU64())
+		}
+		for i := len(newChain) - 1; i >= 0; i-- {
+			bc.indexStateHistory(indexesBatch, newChain[i])
+		}
+	}
 	// Delete any canonical number assignments above the new head
 	number := bc.CurrentBlock().NumberU64()
 	for i := number + 1; ; i++ {
@@ -2629,8 +2874,11 @@ func (bc *BlockChain) trustedDiffLayerLoop() {
 						batch = bc.db.DiffStore().NewBatch()
 					}
 					rawdb.WriteDiffLayer(batch, diffLayer.BlockHash, diffLayer)
-					staleHash := bc.GetCanonicalHash(uint64(-prio) - bc.diffLayerFreezerBlockLimit)
-					rawdb.DeleteDiffLayer(batch, staleHash)
+					// The freezer moves the stale ones into the ancient store if enabled
+					if !bc.freezeDiffLayer {
+						staleHash := bc.GetCanonicalHash(uint64(-prio) - bc.diffLayerFreezerBlockLimit)
+						rawdb.DeleteDiffLayer(batch, staleHash)
+					}
 				}
 				if batch != nil && batch.ValueSize() > ethdb.IdealBatchSize {
 					if err := batch.Write(); err != nil {
@@ -2677,6 +2925,116 @@ func (bc *BlockChain) GetUnTrustedDiffLayer(blockHash common.Hash, pid string) *
 	return nil
 }
 
+// checkUnTrustedDiffLayers compares the diff layers received for a block with
+// the one produced by executing it, discarding the ones which don't match along
+// with everything else received from the peers which sent them.
+func (bc *BlockChain) checkUnTrustedDiffLayers(block *types.Block, local *types.DiffLayer) {
+	bc.diffMux.RLock()
+	diffs := make([]*types.DiffLayer, 0, len(bc.blockHashToDiffLayers[block.Hash()]))
+	for _, diff := range bc.blockHashToDiffLayers[block.Hash()] {
+		diffs = append(diffs, diff)
+	}
+	bc.diffMux.RUnlock()
+
+	for _, diff := range diffs {
+		if diffLayerStateEqual(local, diff) {
+			continue
+		}
+		log.Error("Detected bad diff layer", "number", block.NumberU64(), "hash", block.Hash(), "diff", diff.DiffHash,
+			"producer", block.Coinbase(), "signed", len(diff.Signature) != 0)
+		badDiffLayerMeter.Mark(1)
+		bc.removeDiffLayers(diff.DiffHash)
+	}
+}
+
+// signedDiffLayer returns a diff layer received for the block which carries the
+// same state changes as the local one, the receipts of the block and a valid
+// signature of the block producer, or nil if there is none.
+func (bc *BlockChain) signedDiffLayer(block *types.Block, local *types.DiffLayer) *types.DiffLayer {
+	posa, ok := bc.engine.(consensus.PoSA)
+	if !ok {
+		return nil
+	}
+	bc.diffMux.RLock()
+	diffs := make([]*types.DiffLayer, 0, len(bc.blockHashToDiffLayers[block.Hash()]))
+	for _, diff := range bc.blockHashToDiffLayers[block.Hash()] {
+		if len(diff.Signature) != 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	bc.diffMux.RUnlock()
+
+	for _, diff := range diffs {
+		if !diffLayerStateEqual(local, diff) {
+			continue
+		}
+		if types.DeriveSha(diff.Receipts, trie.NewStackTrie(nil)) != block.ReceiptHash() {
+			continue
+		}
+		if err := posa.VerifyDiffLayer(bc, block.Header(), diff); err != nil {
+			continue
+		}
+		return diff
+	}
+	return nil
+}
+
+// diffLayerStateEqual reports whether two diff layers carry the same state
+// changes, regardless of the order they were encoded in. The codes of the second
+// layer are checked against their hashes, as the first one is trusted.
+func diffLayerStateEqual(a, b *types.DiffLayer) bool {
+	if len(a.Codes) != len(b.Codes) || len(a.Destructs) != len(b.Destructs) || len(a.Accounts) != len(b.Accounts) || len(a.Storages) != len(b.Storages) {
+		return false
+	}
+	codes := make(map[common.Hash]struct{}, len(a.Codes))
+	for _, code := range a.Codes {
+		codes[code.Hash] = struct{}{}
+	}
+	for _, code := range b.Codes {
+		if _, ok := codes[code.Hash]; !ok || crypto.Keccak256Hash(code.Code) != code.Hash {
+			return false
+		}
+	}
+	destructs := make(map[common.Address]struct{}, len(a.Destructs))
+	for _, addr := range a.Destructs {
+		destructs[addr] = struct{}{}
+	}
+	for _, addr := range b.Destructs {
+		if _, ok := destructs[addr]; !ok {
+			return false
+		}
+	}
+	accounts := make(map[common.Address][]byte, len(a.Accounts))
+	for _, account := range a.Accounts {
+		accounts[account.Account] = account.Blob
+	}
+	for _, account := range b.Accounts {
+		if blob, ok := accounts[account.Account]; !ok || !bytes.Equal(blob, account.Blob) {
+			return false
+		}
+	}
+	storages := make(map[common.Address]map[string][]byte, len(a.Storages))
+	for _, storage := range a.Storages {
+		slots := make(map[string][]byte, len(storage.Keys))
+		for i, key := range storage.Keys {
+			slots[key] = storage.Vals[i]
+		}
+		storages[storage.Account] = slots
+	}
+	for _, storage := range b.Storages {
+		slots, ok := storages[storage.Account]
+		if !ok || len(slots) != len(storage.Keys) || len(storage.Keys) != len(storage.Vals) {
+			return false
+		}
+		for i, key := range storage.Keys {
+			if val, ok := slots[key]; !ok || !bytes.Equal(val, storage.Vals[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (bc *BlockChain) removeDiffLayers(diffHash common.Hash) {
 	bc.diffMux.Lock()
 	defer bc.diffMux.Unlock()
@@ -2721,6 +3079,81 @@ func (bc *BlockChain) untrustedDiffLayerPruneLoop() {
 	}
 }
 
+// historyPruneLoop periodically truncates the tail of the ancient store to keep
+// only the configured number of recent blocks.
+func (bc *BlockChain) historyPruneLoop() {
+	defer bc.wg.Done()
+
+	recheck := time.NewTicker(historyPruneRecheckInterval)
+	defer recheck.Stop()
+
+	bc.pruneHistory()
+	for {
+		select {
+		case <-bc.quit:
+			return
+		case <-recheck.C:
+			bc.pruneHistory()
+		}
+	}
+}
+
+// statePruneLoop periodically releases the tries flushed to disk which fell out
+// of the state retention, deleting their nodes no longer referenced.
+func (bc *BlockChain) statePruneLoop() {
+	defer bc.wg.Done()
+
+	recheck := time.NewTicker(statePruneRecheckInterval)
+	defer recheck.Stop()
+
+	for {
+		select {
+		case <-bc.quit:
+			return
+		case <-recheck.C:
+			head := bc.CurrentBlock().NumberU64()
+			if head <= bc.cacheConfig.StateRetention {
+				continue
+			}
+			start := time.Now()
+			nodes, err := bc.stateCache.TrieDB().Release(head - bc.cacheConfig.StateRetention)
+			if err != nil {
+				log.Error("Failed to prune state online", "err", err)
+				continue
+			}
+			if nodes > 0 {
+				log.Info("Pruned stale trie nodes", "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			}
+		}
+	}
+}
+
+// pruneHistory discards the ancient blocks older than the retained history.
+// Blocks still referenced by the transaction index are kept until they are
+// unindexed, so that no dangling lookup entries are left behind.
+func (bc *BlockChain) pruneHistory() {
+	head := bc.CurrentBlock().NumberU64()
+	if head <= bc.historyBlocks {
+		return
+	}
+	target := head - bc.historyBlocks
+	if tail := rawdb.ReadTxIndexTail(bc.db); tail != nil && *tail < target {
+		target = *tail
+	}
+	old := bc.db.AncientOffSet()
+	if target <= old {
+		return
+	}
+	start := time.Now()
+	if err := bc.db.TruncateTail(target); err != nil {
+		log.Error("Failed to prune ancient chain history", "target", target, "err", err)
+		return
+	}
+	if tail := bc.db.AncientOffSet(); tail > old {
+		log.Info("Pruned ancient chain history", "from", old, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
 func (bc *BlockChain) pruneDiffLayer() {
 	currentHeight := bc.CurrentBlock().NumberU64()
 	bc.diffMux.Lock()
@@ -3089,14 +3522,61 @@ func EnableLightProcessor(bc *BlockChain) *BlockChain {
 	return bc
 }
 
+// EnableDiffSampling makes the diff sync processor fully execute the given
+// fraction of blocks, checking the diff layers received for them.
+func EnableDiffSampling(rate float64) BlockChainOption {
+	return func(chain *BlockChain) *BlockChain {
+		chain.diffSampleRate = rate
+		return chain
+	}
+}
+
+// EnableUnsignedDiffLayers makes the diff sync processor accept unsigned diff
+// layers, as served by producers which do not sign them yet. Signed layers are
+// still verified.
+func EnableUnsignedDiffLayers(bc *BlockChain) *BlockChain {
+	bc.diffUnsigned = true
+	return bc
+}
+
 func EnablePipelineCommit(bc *BlockChain) *BlockChain {
 	bc.pipeCommit = true
 	return bc
 }
 
+// EnableParallelProcessor makes the block processor execute transactions
+// optimistically in parallel with the given number of workers, defaulting to
+// the number of CPUs.
+func EnableParallelProcessor(workers int) BlockChainOption {
+	return func(chain *BlockChain) *BlockChain {
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		chain.parallelTxs = workers
+		return chain
+	}
+}
+
 func EnablePersistDiff(limit uint64) BlockChainOption {
 	return func(chain *BlockChain) *BlockChain {
 		chain.diffLayerFreezerBlockLimit = limit
 		return chain
 	}
 }
+
+// EnableHistoryPruning makes the chain continuously discard the ancient blocks
+// beyond the given number of recent blocks from the tail of the ancient store.
+func EnableHistoryPruning(blocks uint64) BlockChainOption {
+	return func(chain *BlockChain) *BlockChain {
+		chain.historyBlocks = blocks
+		return chain
+	}
+}
+
+// EnableFreezeDiff keeps the persisted diff layers until they are moved into
+// the ancient store along with their blocks, instead of discarding them once
+// they are older than the persisted diff block limit.
+func EnableFreezeDiff(bc *BlockChain) *BlockChain {
+	bc.freezeDiffLayer = true
+	return bc
+}
@@ -22,7 +22,9 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
@@ -30,6 +32,7 @@ import (
 	"golang.org/x/crypto/sha3"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
@@ -203,6 +206,12 @@ func newTestBackend(blocks int, light bool) *testBackend {
 // newTestBackend creates a chain with a number of explicitly defined blocks and
 // wraps it into a mock backend.
 func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
+	return newTestBackendWithEngine(blocks, lightProcess, ethash.NewFaker())
+}
+
+// newTestBackendWithEngine creates a chain with a number of explicitly defined
+// blocks, running the given consensus engine, and wraps it into a mock backend.
+func newTestBackendWithEngine(blocks int, lightProcess bool, engine consensus.Engine) *testBackend {
 	signer := types.HomesteadSigner{}
 	// Create a database pre-initialize with a genesis block
 	db := rawdb.NewMemoryDatabase()
@@ -212,7 +221,7 @@ func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
 		Alloc:  GenesisAlloc{testAddr: {Balance: big.NewInt(100000000000000000)}},
 	}).MustCommit(db)
 
-	chain, _ := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil, EnablePersistDiff(860000))
+	chain, _ := NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil, EnablePersistDiff(860000))
 	generator := func(i int, block *BlockGen) {
 		// The chain maker doesn't have access to a chain, so the difficulty will be
 		// lets unset (nil). Set it here to the correct value.
@@ -269,6 +278,56 @@ func newTestBackendWithGenerator(blocks int, lightProcess bool) *testBackend {
 	}
 }
 
+// diffSigningEngine is a PoSA engine on top of ethash, signing the diff layers
+// of the blocks mined by the local key and verifying that the diff layers of
+// all blocks are signed by their coinbase.
+type diffSigningEngine struct {
+	consensus.Engine
+	key *ecdsa.PrivateKey
+}
+
+func newDiffSigningEngine(key *ecdsa.PrivateKey) *diffSigningEngine {
+	return &diffSigningEngine{Engine: ethash.NewFaker(), key: key}
+}
+
+func (e *diffSigningEngine) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	return false, nil
+}
+func (e *diffSigningEngine) IsSystemContract(to *common.Address) bool { return false }
+func (e *diffSigningEngine) EnoughDistance(chain consensus.ChainReader, header *types.Header) bool {
+	return true
+}
+func (e *diffSigningEngine) AllowLightProcess(chain consensus.ChainReader, header *types.Header) bool {
+	return true
+}
+
+func (e *diffSigningEngine) IsLocalBlock(header *types.Header) bool {
+	return e.key != nil && crypto.PubkeyToAddress(e.key.PublicKey) == header.Coinbase
+}
+
+func (e *diffSigningEngine) SignDiffLayer(header *types.Header, diff *types.DiffLayer) error {
+	if !e.IsLocalBlock(header) {
+		return nil
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(types.DiffLayerSigData(header.Hash(), header.Root, diff.Hash())), e.key)
+	if err != nil {
+		return err
+	}
+	diff.Signature = sig
+	return nil
+}
+
+func (e *diffSigningEngine) VerifyDiffLayer(chain consensus.ChainHeaderReader, header *types.Header, diff *types.DiffLayer) error {
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(types.DiffLayerSigData(diff.BlockHash, header.Root, diff.Hash())), diff.Signature)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pubkey) != header.Coinbase {
+		return errors.New("diff layer not signed by coinbase")
+	}
+	return nil
+}
+
 // close tears down the transaction pool and chain behind the mock backend.
 func (b *testBackend) close() {
 	b.chain.Stop()
@@ -360,6 +419,168 @@ func TestProcessDiffLayer(t *testing.T) {
 	}
 }
 
+// Tests that fully processed blocks have the diff layers received for them
+// checked, discarding everything received from the peers sending bad ones.
+func TestSampleDiffLayer(t *testing.T) {
+	blockNum := 16
+	fullBackend := newTestBackend(blockNum, false)
+	defer fullBackend.close()
+
+	lightBackend := newTestBackend(0, true)
+	defer lightBackend.close()
+	lightBackend.chain.diffSampleRate = 1
+
+	for i := 1; i <= blockNum; i++ {
+		block := fullBackend.chain.GetBlockByNumber(uint64(i))
+		rawDiff := fullBackend.chain.GetDiffLayerRLP(block.Hash())
+		if len(rawDiff) != 0 {
+			good, err := rawDataToDiffLayer(rawDiff)
+			if err != nil {
+				t.Fatalf("failed to decode rawdata %v", err)
+			}
+			lightBackend.Chain().HandleDiffLayer(good, "good", true)
+
+			bad, _ := rawDataToDiffLayer(rawDiff)
+			bad.Accounts = bad.Accounts[1:]
+			bad.DiffHash = common.Hash{byte(i)}
+			lightBackend.Chain().HandleDiffLayer(bad, "bad", true)
+		}
+		if _, err := lightBackend.chain.insertChain([]*types.Block{block}, true); err != nil {
+			t.Fatalf("failed to insert block %v", err)
+		}
+	}
+	if _, exist := lightBackend.chain.diffPeersToDiffHashes["bad"]; exist {
+		t.Errorf("diff layers of the bad peer should be discarded")
+	}
+	if _, exist := lightBackend.chain.diffPeersToDiffHashes["good"]; !exist {
+		t.Errorf("diff layers of the good peer should be kept")
+	}
+}
+
+// Tests that the producer signature of a diff layer survives a relay hop, so
+// that nodes diff syncing from non-validators can still verify it.
+func TestRelaySignedDiffLayer(t *testing.T) {
+	blockNum := 16
+	producer := newTestBackendWithEngine(blockNum, false, newDiffSigningEngine(testKey))
+	defer producer.close()
+
+	relay := newTestBackendWithEngine(0, true, newDiffSigningEngine(nil))
+	defer relay.close()
+
+	consumer := newTestBackendWithEngine(0, true, newDiffSigningEngine(nil))
+	defer consumer.close()
+
+	engine := newDiffSigningEngine(nil)
+	for i := 1; i <= blockNum; i++ {
+		block := producer.chain.GetBlockByNumber(uint64(i))
+		if len(block.Transactions()) == 0 {
+			// Empty blocks have no diff layers to relay
+			if _, err := relay.chain.insertChain([]*types.Block{block}, true); err != nil {
+				t.Fatalf("block %d: relay failed to insert block: %v", i, err)
+			}
+			if _, err := consumer.chain.insertChain([]*types.Block{block}, true); err != nil {
+				t.Fatalf("block %d: consumer failed to insert block: %v", i, err)
+			}
+			continue
+		}
+		produced, err := rawDataToDiffLayer(producer.chain.GetDiffLayerRLP(block.Hash()))
+		if err != nil {
+			t.Fatalf("block %d: failed to decode produced diff layer: %v", i, err)
+		}
+		if err := engine.VerifyDiffLayer(producer.chain, block.Header(), produced); err != nil {
+			t.Fatalf("block %d: produced diff layer not signed: %v", i, err)
+		}
+		relay.chain.HandleDiffLayer(produced, "producer", true)
+		if _, err := relay.chain.insertChain([]*types.Block{block}, true); err != nil {
+			t.Fatalf("block %d: relay failed to insert block: %v", i, err)
+		}
+		relayed, err := rawDataToDiffLayer(relay.chain.GetDiffLayerRLP(block.Hash()))
+		if err != nil {
+			t.Fatalf("block %d: failed to decode relayed diff layer: %v", i, err)
+		}
+		if err := engine.VerifyDiffLayer(relay.chain, block.Header(), relayed); err != nil {
+			t.Fatalf("block %d: relayed diff layer not signed: %v", i, err)
+		}
+		consumer.chain.HandleDiffLayer(relayed, "relay", true)
+		if _, err := consumer.chain.insertChain([]*types.Block{block}, true); err != nil {
+			t.Fatalf("block %d: consumer failed to insert block: %v", i, err)
+		}
+		if checks, exist := checkBlocks[i]; exist {
+			for _, check := range checks.txs {
+				s, _ := consumer.chain.Snapshots().Snapshot(block.Root()).Storage(crypto.Keccak256Hash((*check.to)[:]), check.slot)
+				if !bytes.Equal(s, check.value) {
+					t.Fatalf("block %d: storage mismatch: have %x, want %x", i, s, check.value)
+				}
+			}
+		}
+	}
+}
+
+// Tests that unsigned diff layers are only accepted if explicitly enabled, while
+// signed ones are verified either way.
+func TestVerifyUnsignedDiffLayer(t *testing.T) {
+	producer := newTestBackendWithEngine(1, false, newDiffSigningEngine(testKey))
+	defer producer.close()
+
+	block := producer.chain.GetBlockByNumber(1)
+	signed, err := rawDataToDiffLayer(producer.chain.GetDiffLayerRLP(block.Hash()))
+	if err != nil {
+		t.Fatalf("failed to decode produced diff layer: %v", err)
+	}
+	unsigned, _ := rawDataToDiffLayer(producer.chain.GetDiffLayerRLP(block.Hash()))
+	unsigned.Signature = nil
+
+	otherKey, _ := crypto.GenerateKey()
+	forged, _ := rawDataToDiffLayer(producer.chain.GetDiffLayerRLP(block.Hash()))
+	forged.Signature, _ = crypto.Sign(crypto.Keccak256(types.DiffLayerSigData(block.Hash(), block.Root(), forged.Hash())), otherKey)
+
+	for _, accept := range []bool{false, true} {
+		consumer := newTestBackendWithEngine(0, true, newDiffSigningEngine(nil))
+		if accept {
+			EnableUnsignedDiffLayers(consumer.chain)
+		}
+		processor := NewLightStateProcessor(consumer.chain.Config(), consumer.chain, consumer.chain.engine)
+		if !processor.verifyDiffLayer(block, signed) {
+			t.Errorf("accept %v: signed diff layer rejected", accept)
+		}
+		if processor.verifyDiffLayer(block, unsigned) != accept {
+			t.Errorf("accept %v: unsigned diff layer acceptance mismatch", accept)
+		}
+		if processor.verifyDiffLayer(block, forged) {
+			t.Errorf("accept %v: forged diff layer accepted", accept)
+		}
+		consumer.close()
+	}
+}
+
+// Tests that diff layers are only considered equal if they carry the same state
+// changes, including the same contract codes.
+func TestDiffLayerStateEqual(t *testing.T) {
+	var (
+		code1 = types.DiffCode{Hash: crypto.Keccak256Hash([]byte{0x01}), Code: []byte{0x01}}
+		code2 = types.DiffCode{Hash: crypto.Keccak256Hash([]byte{0x02}), Code: []byte{0x02}}
+		local = &types.DiffLayer{
+			Codes:    []types.DiffCode{code1, code2},
+			Accounts: []types.DiffAccount{{Account: common.Address{0x01}, Blob: []byte{0x01}}},
+		}
+	)
+	tests := []struct {
+		codes []types.DiffCode
+		equal bool
+	}{
+		{[]types.DiffCode{code2, code1}, true},
+		{[]types.DiffCode{code1}, false},
+		{[]types.DiffCode{code1, {Hash: common.Hash{0x02}, Code: []byte{0x02}}}, false},
+		{[]types.DiffCode{code1, {Hash: code2.Hash, Code: []byte{0x03}}}, false},
+	}
+	for i, tt := range tests {
+		diff := &types.DiffLayer{Codes: tt.codes, Accounts: local.Accounts}
+		if equal := diffLayerStateEqual(local, diff); equal != tt.equal {
+			t.Errorf("test %d: equality mismatch: have %v, want %v", i, equal, tt.equal)
+		}
+	}
+}
+
 func TestFreezeDiffLayer(t *testing.T) {
 	blockNum := 1024
 	fullBackend := newTestBackend(blockNum, true)
@@ -484,3 +705,61 @@ func TestGetDiffAccounts(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a batch of blocks can be imported by applying their diff layers,
+// stopping at the first block whose diff layer is missing or doesn't match.
+func TestInsertChainWithDiffs(t *testing.T) {
+	blockNum := 32
+	fullBackend := newTestBackend(blockNum, false)
+	defer fullBackend.close()
+
+	lightBackend := newTestBackend(0, false)
+	defer lightBackend.close()
+
+	var (
+		blocks = make(types.Blocks, blockNum)
+		diffs  = make([]*types.DiffLayer, blockNum)
+	)
+	for i := range blocks {
+		blocks[i] = fullBackend.chain.GetBlockByNumber(uint64(i + 1))
+		if rawDiff := fullBackend.chain.GetDiffLayerRLP(blocks[i].Hash()); len(rawDiff) != 0 {
+			diff, err := rawDataToDiffLayer(rawDiff)
+			if err != nil {
+				t.Fatalf("failed to decode diff layer of block %d: %v", i+1, err)
+			}
+			diffs[i] = diff
+		}
+	}
+	// Tamper with a diff layer, the import should stop right before it
+	tampered := 8
+	genuine := diffs[tampered]
+	diffs[tampered], _ = rawDataToDiffLayer(fullBackend.chain.GetDiffLayerRLP(blocks[tampered].Hash()))
+	account, _ := snapshot.FullAccount(diffs[tampered].Accounts[0].Blob)
+	account.Balance = new(big.Int).Add(account.Balance, big.NewInt(1))
+	diffs[tampered].Accounts[0].Blob, _ = rlp.EncodeToBytes(&account)
+
+	if n, err := lightBackend.chain.InsertChainWithDiffs(blocks, diffs); err == nil || n != tampered {
+		t.Fatalf("tampered diff layer import mismatch: have %d/%v, want %d/error", n, err, tampered)
+	}
+	if head := lightBackend.chain.CurrentBlock(); head.Hash() != blocks[tampered-1].Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), tampered)
+	}
+	// Block 15 has no transactions and hence no diff layer, it needs to be executed
+	diffs[tampered] = genuine
+	if n, err := lightBackend.chain.InsertChainWithDiffs(blocks[tampered:], diffs[tampered:]); err != ErrDiffLayerNotFound || n != 14-tampered {
+		t.Fatalf("missing diff layer import mismatch: have %d/%v, want %d/%v", n, err, 14-tampered, ErrDiffLayerNotFound)
+	}
+	if _, err := lightBackend.chain.InsertChain(blocks[14:15]); err != nil {
+		t.Fatalf("failed to execute block 15: %v", err)
+	}
+	if n, err := lightBackend.chain.InsertChainWithDiffs(blocks[15:], diffs[15:]); err != nil {
+		t.Fatalf("failed to import block %d: %v", 16+n, err)
+	}
+	head := lightBackend.chain.CurrentBlock()
+	if head.Hash() != fullBackend.chain.CurrentBlock().Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), blockNum)
+	}
+	if !lightBackend.chain.HasState(head.Root()) {
+		t.Fatalf("state of the head is not available")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// tailDatabase is a database recording the tail truncations of its ancient
+// store, as the freezer only discards whole data files.
+type tailDatabase struct {
+	ethdb.Database
+	tail uint64
+}
+
+func (db *tailDatabase) AncientOffSet() uint64 { return db.tail }
+
+func (db *tailDatabase) TruncateTail(tail uint64) error {
+	if tail > db.tail {
+		db.tail = tail
+	}
+	return nil
+}
+
+// Tests that the ancient chain history is pruned beyond the retained number of
+// blocks, but never past the tail of the transaction index.
+func TestPruneHistory(t *testing.T) {
+	var (
+		db      = &tailDatabase{Database: rawdb.NewMemoryDatabase()}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 64, nil)
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Nothing is pruned while the chain is shorter than the retained history
+	chain.historyBlocks = 32
+	if _, err := chain.InsertChain(blocks[:32]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.pruneHistory()
+	if db.tail != 0 {
+		t.Fatalf("ancient tail mismatch: have %d, want %d", db.tail, 0)
+	}
+	if _, err := chain.InsertChain(blocks[32:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Blocks still indexed are kept, and the tail follows the index
+	rawdb.WriteTxIndexTail(db, 20)
+	chain.pruneHistory()
+	if db.tail != 20 {
+		t.Fatalf("ancient tail mismatch: have %d, want %d", db.tail, 20)
+	}
+	// Once unindexed, history is pruned up to the retained blocks
+	rawdb.WriteTxIndexTail(db, 40)
+	chain.pruneHistory()
+	if db.tail != 32 {
+		t.Fatalf("ancient tail mismatch: have %d, want %d", db.tail, 32)
+	}
+	for number := uint64(0); number <= 64; number++ {
+		if pruned := chain.HistoryPruned(number); pruned != (number > 0 && number < 32) {
+			t.Errorf("block %d: pruned mismatch: have %v, want %v", number, pruned, !pruned)
+		}
+	}
+}
@@ -37,6 +37,14 @@ var (
 
 	// ErrKnownBadBlock is return when the block is a known bad block
 	ErrKnownBadBlock = errors.New("already known bad block")
+
+	// ErrHistoryPruned is returned when the requested block was discarded by
+	// the chain history pruning.
+	ErrHistoryPruned = errors.New("block history pruned")
+
+	// ErrStateHistoryUnavailable is returned when a historical state can't be
+	// reconstructed, as the reverse state diffs needed are not available.
+	ErrStateHistoryUnavailable = errors.New("state history unavailable")
 )
 
 // List of evm-call-message pre-checking errors. All state transition messages will
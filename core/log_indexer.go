@@ -0,0 +1,189 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errLogIndexInterrupted is returned if rebuilding the log index is aborted.
+	errLogIndexInterrupted = errors.New("log index rebuild interrupted")
+
+	// ErrLogIndexHistoryPruned is returned if the log index is requested for a
+	// chain whose ancient history is pruned. The index is built from the first
+	// section onwards, which needs the receipts of every block.
+	ErrLogIndexHistoryPruned = errors.New("log index requires the full chain history")
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an inverted index from
+// log addresses and topics to the blocks containing them. Unlike the bloom bits,
+// the index never yields false positives, so busy blocks with saturated blooms
+// don't need to be scanned.
+type LogIndexer struct {
+	size    uint64         // section size to generate the index for
+	db      ethdb.Database // database instance to write index data and metadata into
+	section uint64         // Section is the section number being processed currently
+	head    common.Hash    // Head is the hash of the last header processed
+
+	addresses map[common.Address][]uint64 // Block offsets within the section per log address
+	topics    map[common.Hash][]uint64    // Block offsets within the section per log topic
+}
+
+// NewLogIndexer returns a chain indexer that generates the address and topic
+// log index for the canonical chain for fast logs filtering.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head = section, common.Hash{}
+	l.addresses = make(map[common.Address][]uint64)
+	l.topics = make(map[common.Hash][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the addresses and topics
+// of a new block's logs into the index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+	l.head = hash
+
+	// Blocks without logs have an empty bloom, no need to look at the receipts
+	if header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	receipts := rawdb.ReadRawReceipts(l.db, hash, number)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d [%x..] not found", number, hash[:4])
+	}
+	offset := number - l.section*l.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if blocks := l.addresses[log.Address]; len(blocks) == 0 || blocks[len(blocks)-1] != offset {
+				l.addresses[log.Address] = append(blocks, offset)
+			}
+			for _, topic := range log.Topics {
+				if blocks := l.topics[topic]; len(blocks) == 0 || blocks[len(blocks)-1] != offset {
+					l.topics[topic] = append(blocks, offset)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+	for address, blocks := range l.addresses {
+		rawdb.WriteLogIndexAddress(batch, address, l.section, l.head, blocks)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	for topic, blocks := range l.topics {
+		rawdb.WriteLogIndexTopic(batch, topic, l.section, l.head, blocks)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// RebuildLogIndex drops the address and topic log index from the database and
+// regenerates it for every confirmed section of the canonical chain. It is meant
+// to be run offline, the regenerated index is picked up by the log indexer on
+// the next start.
+func RebuildLogIndex(db ethdb.Database, size, confirms uint64, abort <-chan struct{}) error {
+	if tail := db.AncientOffSet(); tail > 0 {
+		return fmt.Errorf("%w, blocks below #%d are pruned", ErrLogIndexHistoryPruned, tail)
+	}
+	rawdb.DeleteLogIndex(db)
+
+	headHash := rawdb.ReadHeadBlockHash(db)
+	number := rawdb.ReadHeaderNumber(db, headHash)
+	if number == nil {
+		return errors.New("head block missing")
+	}
+	var sections uint64
+	if *number+1 > confirms {
+		sections = (*number + 1 - confirms) / size
+	}
+	indexer := NewLogIndexer(db, size, confirms)
+	defer indexer.Close()
+
+	var (
+		start  = mclock.Now()
+		logged = mclock.Now()
+	)
+	for section := uint64(0); section < sections; section++ {
+		select {
+		case <-abort:
+			return errLogIndexInterrupted
+		default:
+		}
+		var lastHead common.Hash
+		if section > 0 {
+			lastHead = indexer.SectionHead(section - 1)
+		}
+		head, err := indexer.processSection(section, lastHead)
+		if err != nil {
+			return fmt.Errorf("section %d: %v", section, err)
+		}
+		indexer.lock.Lock()
+		indexer.setSectionHead(section, head)
+		indexer.setValidSections(section + 1)
+		indexer.lock.Unlock()
+
+		if time.Duration(mclock.Now()-logged) > 8*time.Second {
+			log.Info("Rebuilding log index", "section", section+1, "sections", sections, "elapsed", common.PrettyDuration(time.Duration(mclock.Now()-start)))
+			logged = mclock.Now()
+		}
+	}
+	log.Info("Rebuilt log index", "sections", sections, "blocks", sections*size, "elapsed", common.PrettyDuration(time.Duration(mclock.Now()-start)))
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that rebuilding the log index covers every confirmed section of the
+// canonical chain, indexing the blocks by log address and topics.
+func TestRebuildLogIndex(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		addr1  = common.HexToAddress("0x1")
+		addr2  = common.HexToAddress("0x2")
+		topic1 = common.HexToHash("0x11")
+		topic2 = common.HexToHash("0x22")
+		parent common.Hash
+	)
+	// Emit logs from addr1 in every third block, and from addr2 in block 5
+	for i := uint64(0); i < 10; i++ {
+		var receipts types.Receipts
+		if i%3 == 0 {
+			receipts = append(receipts, &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: addr1, Topics: []common.Hash{topic1}}}})
+		}
+		if i == 5 {
+			receipts = append(receipts, &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: addr2, Topics: []common.Hash{topic1, topic2}}}})
+		}
+		for _, receipt := range receipts {
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Bloom: types.CreateBloom(receipts), Difficulty: big.NewInt(1)}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), i)
+		rawdb.WriteReceipts(db, header.Hash(), i, receipts)
+		rawdb.WriteHeadBlockHash(db, header.Hash())
+		parent = header.Hash()
+	}
+	// Stale index entries must be dropped by the rebuild
+	rawdb.WriteLogIndexAddress(db, addr2, 0, rawdb.ReadCanonicalHash(db, 3), []uint64{1})
+
+	// With 2 confirmations, only blocks 0-7 are indexed in sections of 4
+	if err := RebuildLogIndex(db, 4, 2, nil); err != nil {
+		t.Fatalf("failed to rebuild log index: %v", err)
+	}
+	head0, head1 := rawdb.ReadCanonicalHash(db, 3), rawdb.ReadCanonicalHash(db, 7)
+	tests := []struct {
+		have, want []uint64
+	}{
+		{rawdb.ReadLogIndexAddress(db, addr1, 0, head0), []uint64{0, 3}},
+		{rawdb.ReadLogIndexAddress(db, addr1, 1, head1), []uint64{2}},
+		{rawdb.ReadLogIndexAddress(db, addr2, 0, head0), nil},
+		{rawdb.ReadLogIndexAddress(db, addr2, 1, head1), []uint64{1}},
+		{rawdb.ReadLogIndexTopic(db, topic1, 1, head1), []uint64{1, 2}},
+		{rawdb.ReadLogIndexTopic(db, topic2, 1, head1), []uint64{1}},
+		{rawdb.ReadLogIndexAddress(db, addr1, 2, rawdb.ReadCanonicalHash(db, 9)), nil},
+	}
+	for i, tt := range tests {
+		if !reflect.DeepEqual(tt.have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, tt.have, tt.want)
+		}
+	}
+	// The indexer must pick up the rebuilt sections
+	indexer := NewLogIndexer(db, 4, 2)
+	defer indexer.Close()
+
+	if sections, _, head := indexer.Sections(); sections != 2 || head != head1 {
+		t.Fatalf("indexer progress mismatch: have %d sections at %x, want 2 at %x", sections, head, head1)
+	}
+}
+
+// prunedHistoryDB is a database whose ancient store tail was truncated.
+type prunedHistoryDB struct {
+	ethdb.Database
+	tail uint64
+}
+
+func (db *prunedHistoryDB) AncientOffSet() uint64 { return db.tail }
+
+// Tests that the log index is not rebuilt if the chain history is pruned, as the
+// sections below the tail could never be indexed.
+func TestRebuildLogIndexPrunedHistory(t *testing.T) {
+	db := &prunedHistoryDB{Database: rawdb.NewMemoryDatabase(), tail: 100}
+	if err := RebuildLogIndex(db, 4, 2, nil); !errors.Is(err, ErrLogIndexHistoryPruned) {
+		t.Fatalf("rebuild error mismatch: have %v, want %v", err, ErrLogIndexHistoryPruned)
+	}
+}
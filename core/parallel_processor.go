@@ -0,0 +1,205 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// parallelTx is a transaction speculatively executed on the parent state of
+// its block.
+type parallelTx struct {
+	index int
+	tx    *types.Transaction
+	done  chan struct{} // Closed when the speculative execution finished
+
+	msg      types.Message
+	msgErr   error // Error deriving the message, the block is invalid
+	result   *ExecutionResult
+	err      error // Execution error, the transaction is re-executed in order
+	recorder *stateRecorder
+}
+
+// speculate executes the given transactions concurrently, each on top of the
+// parent state of the block, recording the state locations they access along
+// with the mutations they make. Every worker executes on its own copy of the
+// state, reverting it after each transaction.
+func (p *StateProcessor) speculate(txs []*parallelTx, block *types.Block, statedb *state.StateDB, signer types.Signer, cfg vm.Config, abort chan struct{}) {
+	workers := p.bc.parallelTxs
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	queue := make(chan *parallelTx, len(txs))
+	for _, ptx := range txs {
+		queue <- ptx
+	}
+	close(queue)
+
+	for i := 0; i < workers; i++ {
+		// The copies have to be created before the state is modified
+		db := statedb.Copy()
+		go func() {
+			blockContext := NewEVMBlockContext(block.Header(), p.bc, nil)
+			vmenv := vm.NewEVM(blockContext, vm.TxContext{}, db, p.config, cfg)
+
+			for ptx := range queue {
+				select {
+				case <-abort:
+					return
+				default:
+				}
+				ptx.msg, ptx.msgErr = ptx.tx.AsMessage(signer)
+				if ptx.msgErr == nil {
+					db.Prepare(ptx.tx.Hash(), block.Hash(), ptx.index)
+					ptx.recorder = newStateRecorder(db, true)
+
+					snap := db.Snapshot()
+					vmenv.Reset(NewEVMTxContext(ptx.msg), ptx.recorder)
+					ptx.result, ptx.err = ApplyMessage(vmenv, ptx.msg, new(GasPool).AddGas(block.GasLimit()))
+					db.RevertToSnapshot(snap)
+				}
+				close(ptx.done)
+			}
+		}()
+	}
+}
+
+// processParallel is the optimistic parallel counterpart of Process. All the
+// transactions are executed speculatively on the parent state, then committed
+// in order: a transaction which didn't read any state location modified by the
+// ones before it has its recorded mutations replayed, any other one is executed
+// again on the current state. The receipts and the resulting state are
+// identical to the sequential ones.
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config) (*state.StateDB, types.Receipts, []*types.Log, uint64, error) {
+	var (
+		usedGas = new(uint64)
+		header  = block.Header()
+		allLogs []*types.Log
+		gp      = new(GasPool).AddGas(block.GasLimit())
+	)
+	signer := types.MakeSigner(p.bc.chainConfig, block.Number())
+	statedb.TryPreload(block, signer)
+	var receipts = make([]*types.Receipt, 0)
+
+	txNum := len(block.Transactions())
+	posa, isPoSA := p.engine.(consensus.PoSA)
+	commonTxs := make([]*types.Transaction, 0, txNum)
+	systemTxs := make([]*types.Transaction, 0, 2)
+
+	// Split off the system transactions, which are applied by the engine
+	txs := make([]*parallelTx, 0, txNum)
+	for i, tx := range block.Transactions() {
+		if isPoSA {
+			if isSystemTx, err := posa.IsSystemTransaction(tx, header); err != nil {
+				return statedb, nil, nil, 0, err
+			} else if isSystemTx {
+				systemTxs = append(systemTxs, tx)
+				continue
+			}
+		}
+		txs = append(txs, &parallelTx{index: i, tx: tx, done: make(chan struct{})})
+	}
+	abort := make(chan struct{})
+	defer close(abort)
+	p.speculate(txs, block, statedb, signer, cfg, abort)
+
+	// initilise bloom processors
+	bloomProcessors := NewAsyncReceiptBloomGenerator(txNum)
+	statedb.MarkFullProcessed()
+
+	blockContext := NewEVMBlockContext(header, p.bc, nil)
+	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
+
+	written := newAccessSet()
+	for _, ptx := range txs {
+		<-ptx.done
+		if ptx.msgErr != nil {
+			return statedb, nil, nil, 0, ptx.msgErr
+		}
+		statedb.Prepare(ptx.tx.Hash(), block.Hash(), ptx.index)
+
+		var (
+			result *ExecutionResult
+			writes *accessSet
+		)
+		if ptx.err == nil && gp.Gas() >= ptx.msg.Gas() && !ptx.recorder.reads.conflicts(written) {
+			// The speculation holds, replay it
+			if err := gp.SubGas(ptx.result.UsedGas); err != nil {
+				return statedb, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", ptx.index, ptx.tx.Hash().Hex(), err)
+			}
+			ptx.recorder.apply(statedb)
+			result, writes = ptx.result, ptx.recorder.writes
+		} else {
+			// The transaction saw stale state or failed, execute it again
+			recorder := newStateRecorder(statedb, false)
+			vmenv.Reset(NewEVMTxContext(ptx.msg), recorder)
+
+			res, err := ApplyMessage(vmenv, ptx.msg, gp)
+			if err != nil {
+				return statedb, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", ptx.index, ptx.tx.Hash().Hex(), err)
+			}
+			result, writes = res, recorder.writes
+			parallelConflictMeter.Mark(1)
+		}
+		receipt := newReceipt(ptx.msg, result, p.config, statedb, header, ptx.tx, usedGas, bloomProcessors)
+
+		// Accounts deleted upon finalisation lose their storage too
+		for addr := range writes.accounts {
+			if !statedb.Exist(addr) {
+				writes.addStorage(addr)
+			}
+		}
+		written.merge(writes)
+
+		commonTxs = append(commonTxs, ptx.tx)
+		receipts = append(receipts, receipt)
+	}
+	bloomProcessors.Close()
+	parallelTxMeter.Mark(int64(len(txs)))
+
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	err := p.engine.Finalize(p.bc, header, statedb, &commonTxs, block.Uncles(), &receipts, &systemTxs, usedGas)
+	if err != nil {
+		return statedb, receipts, allLogs, *usedGas, err
+	}
+	for _, receipt := range receipts {
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+
+	return statedb, receipts, allLogs, *usedGas, nil
+}
+
+// parallelEnabled reports whether the transactions of the given block may be
+// executed in parallel.
+func (p *StateProcessor) parallelEnabled(block *types.Block, cfg vm.Config) bool {
+	// Receipts before Byzantium carry the intermediate state root, which can't
+	// be computed without executing the transactions in order. Tracers expect
+	// to see the execution in order too.
+	return p.bc != nil && p.bc.parallelTxs > 0 && len(block.Transactions()) > 1 &&
+		p.config.IsByzantium(block.Number()) && !cfg.Debug
+}
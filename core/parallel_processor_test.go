@@ -0,0 +1,141 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that executing transactions in parallel yields the same receipts and
+// state as executing them in order, both for independent and for conflicting
+// transactions.
+func TestParallelProcessor(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 4)
+		addrs = make([]common.Address, 4)
+		alloc = make(GenesisAlloc)
+
+		// counter increments storage slot 0 and emits an empty log
+		counter     = common.Address{0xc0}
+		counterCode = common.FromHex("600054600101600055600060006000a000")
+		// destructor self destructs to the caller
+		destructor     = common.Address{0xde}
+		destructorCode = common.FromHex("33ff")
+		// creation code deploying counter
+		initCode = append(common.FromHex("6011600c60003960116000f3"), counterCode...)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	alloc[counter] = GenesisAccount{Code: counterCode, Balance: common.Big0}
+	alloc[destructor] = GenesisAccount{Code: destructorCode, Balance: big.NewInt(params.GWei)}
+
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+		db     = rawdb.NewMemoryDatabase()
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 8, func(n int, b *BlockGen) {
+		send := func(i int, to *common.Address, value int64, gas uint64, data []byte) {
+			tx, err := types.SignNewTx(keys[i], signer, &types.LegacyTx{
+				Nonce:    b.TxNonce(addrs[i]),
+				To:       to,
+				Value:    big.NewInt(value),
+				Gas:      gas,
+				GasPrice: big.NewInt(int64(1 + i)),
+				Data:     data,
+			})
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			b.AddTx(tx)
+		}
+		// Independent transfers, some funding a later sender
+		for i := range addrs {
+			to := common.Address{byte(n), byte(i)}
+			send(i, &to, 1000, params.TxGas, nil)
+		}
+		send(0, &addrs[1], 1, params.TxGas, nil)
+		send(1, &addrs[2], 1, params.TxGas, nil)
+
+		// Conflicting storage updates and logs
+		for i := range addrs {
+			send(i, &counter, 0, 100000, nil)
+		}
+		switch n {
+		case 2:
+			send(3, &destructor, 0, 100000, nil)
+			send(2, &destructor, 1, 100000, nil)
+		case 4:
+			send(2, nil, 0, 200000, initCode)
+		case 5:
+			// Zero value transfer to an empty account, deleted upon finalisation
+			empty := common.Address{0xee}
+			send(3, &empty, 0, params.TxGas, nil)
+			send(0, &empty, 5, params.TxGas, nil)
+		}
+		// Failing transaction, the gas is still charged
+		send(3, &counter, 0, 25000, nil)
+	})
+
+	newChain := func(options ...BlockChainOption) *BlockChain {
+		db := rawdb.NewMemoryDatabase()
+		gspec.MustCommit(db)
+		chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, options...)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to import chain: %v", err)
+		}
+		return chain
+	}
+	sequential := newChain()
+	defer sequential.Stop()
+
+	for _, workers := range []int{1, 4} {
+		parallel := newChain(EnableParallelProcessor(workers))
+
+		for _, block := range blocks {
+			want, _ := json.Marshal(sequential.GetReceiptsByHash(block.Hash()))
+			have, _ := json.Marshal(parallel.GetReceiptsByHash(block.Hash()))
+			if string(have) != string(want) {
+				t.Errorf("workers %d, block %d: receipts mismatch:\nhave %s\nwant %s", workers, block.NumberU64(), have, want)
+			}
+		}
+		if have, want := parallel.CurrentBlock().Root(), sequential.CurrentBlock().Root(); have != want {
+			t.Errorf("workers %d: state root mismatch: have %x, want %x", workers, have, want)
+		}
+		parallel.Stop()
+	}
+}
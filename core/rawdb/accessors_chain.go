@@ -479,12 +479,82 @@ func ReadDiffLayerRLP(db ethdb.KeyValueReader, blockHash common.Hash) rlp.RawVal
 	return data
 }
 
+// ReadAncientDiffLayerRLP retrieves the diff layer of the canonical block with
+// the given number from the ancient store, if it was frozen along with it.
+func ReadAncientDiffLayerRLP(db ethdb.AncientReader, number uint64) rlp.RawValue {
+	data, _ := db.Ancient(freezerDiffLayerTable, number)
+	return data
+}
+
 func DeleteDiffLayer(db ethdb.KeyValueWriter, blockHash common.Hash) {
 	if err := db.Delete(diffLayerKey(blockHash)); err != nil {
 		log.Crit("Failed to delete diffLayer", "err", err)
 	}
 }
 
+// ReadStateHistoryRLP retrieves the reverse state diff of a block in RLP encoding.
+func ReadStateHistoryRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	// First try to look up the data in ancient database. Extra hash
+	// comparison is necessary since ancient database only maintains
+	// the canonical data.
+	data, _ := db.Ancient(freezerStateHistoryTable, number)
+	if len(data) > 0 {
+		h, _ := db.Ancient(freezerHashTable, number)
+		if common.BytesToHash(h) == hash {
+			return data
+		}
+	}
+	// Then try to look up the data in leveldb.
+	data, _ = db.Get(stateHistoryKey(number, hash))
+	if len(data) > 0 {
+		return data
+	}
+	// In the background freezer is moving data from leveldb to flatten files.
+	// So during the first check for ancient db, the data is not yet in there,
+	// but when we reach into leveldb, the data was already moved. That would
+	// result in a not found error.
+	data, _ = db.Ancient(freezerStateHistoryTable, number)
+	if len(data) > 0 {
+		h, _ := db.Ancient(freezerHashTable, number)
+		if common.BytesToHash(h) == hash {
+			return data
+		}
+	}
+	return nil // Can't find the data anywhere.
+}
+
+// ReadStateHistory retrieves the reverse state diff of a block.
+func ReadStateHistory(db ethdb.Reader, hash common.Hash, number uint64) *types.StateHistory {
+	data := ReadStateHistoryRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	history := new(types.StateHistory)
+	if err := rlp.DecodeBytes(data, history); err != nil {
+		log.Error("Invalid state history RLP", "hash", hash, "number", number, "err", err)
+		return nil
+	}
+	return history
+}
+
+// WriteStateHistory stores the reverse state diff of a block into the database.
+func WriteStateHistory(db ethdb.KeyValueWriter, hash common.Hash, number uint64, history *types.StateHistory) {
+	data, err := rlp.EncodeToBytes(history)
+	if err != nil {
+		log.Crit("Failed to RLP encode state history", "err", err)
+	}
+	if err := db.Put(stateHistoryKey(number, hash), data); err != nil {
+		log.Crit("Failed to store state history", "err", err)
+	}
+}
+
+// DeleteStateHistory removes the reverse state diff of a block.
+func DeleteStateHistory(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(stateHistoryKey(number, hash)); err != nil {
+		log.Crit("Failed to delete state history", "err", err)
+	}
+}
+
 // DeleteBody removes all block body data associated with a hash.
 func DeleteBody(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	if err := db.Delete(blockBodyKey(number, hash)); err != nil {
@@ -730,6 +800,7 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteStateHistory(db, hash, number)
 }
 
 // DeleteBlockWithoutNumber removes all block data associated with a hash, except
@@ -739,6 +810,7 @@ func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteStateHistory(db, hash, number)
 }
 
 const badBlockToKeep = 10
@@ -29,6 +29,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"golang.org/x/crypto/sha3"
@@ -496,6 +497,73 @@ func TestAncientStorage(t *testing.T) {
 	}
 }
 
+// Tests that the persisted diff layers of frozen blocks are moved into the
+// ancient store if enabled, leaving the items of blocks without one empty.
+func TestAncientDiffLayerStorage(t *testing.T) {
+	testAncientDiffLayerStorage(t, false)
+	testAncientDiffLayerStorage(t, true)
+}
+
+func testAncientDiffLayerStorage(t *testing.T, freeze bool) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false, false, false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+	db.SetDiffStore(memorydb.New())
+	if freeze {
+		db.FreezeDiffLayers()
+	}
+	var (
+		parent common.Hash
+		hashes []common.Hash
+		diffs  = make(map[uint64][]byte)
+	)
+	for number := uint64(0); number < 5; number++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			ParentHash:  parent,
+			Number:      new(big.Int).SetUint64(number),
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		})
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), number, nil)
+		WriteTd(db, block.Hash(), number, big.NewInt(int64(number)))
+		WriteCanonicalHash(db, block.Hash(), number)
+		WriteHeadBlockHash(db, block.Hash())
+
+		if number%2 == 0 {
+			diff := &types.DiffLayer{BlockHash: block.Hash(), Number: number}
+			WriteDiffLayer(db.DiffStore(), block.Hash(), diff)
+			diffs[number] = ReadDiffLayerRLP(db.DiffStore(), block.Hash())
+		}
+		parent = block.Hash()
+		hashes = append(hashes, parent)
+	}
+	if err := db.(*freezerdb).Freeze(0); err != nil {
+		t.Fatalf("failed to freeze: %v", err)
+	}
+	for number, hash := range hashes {
+		ancient, recent := ReadAncientDiffLayerRLP(db, uint64(number)), ReadDiffLayerRLP(db.DiffStore(), hash)
+		if !freeze {
+			ancient, recent = recent, ancient
+		}
+		if want := diffs[uint64(number)]; !bytes.Equal(ancient, want) {
+			t.Errorf("freeze %v, block %d: diff layer mismatch: have %x, want %x", freeze, number, ancient, want)
+		}
+		if len(recent) != 0 {
+			t.Errorf("freeze %v, block %d: diff layer left behind", freeze, number)
+		}
+	}
+}
+
 func TestCanonicalHashIteration(t *testing.T) {
 	var cases = []struct {
 		from, to uint64
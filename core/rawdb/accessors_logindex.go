@@ -0,0 +1,136 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// encodeLogIndexBlocks packs an ascending list of block offsets within a section
+// as varint deltas.
+func encodeLogIndexBlocks(offsets []uint64) []byte {
+	var (
+		blob = make([]byte, len(offsets)*binary.MaxVarintLen64)
+		size int
+		last uint64
+	)
+	for _, offset := range offsets {
+		size += binary.PutUvarint(blob[size:], offset-last)
+		last = offset
+	}
+	return blob[:size]
+}
+
+// decodeLogIndexBlocks unpacks a list of block offsets packed with
+// encodeLogIndexBlocks.
+func decodeLogIndexBlocks(blob []byte) []uint64 {
+	var (
+		offsets []uint64
+		last    uint64
+	)
+	for len(blob) > 0 {
+		delta, n := binary.Uvarint(blob)
+		if n <= 0 {
+			return nil
+		}
+		last += delta
+		offsets = append(offsets, last)
+		blob = blob[n:]
+	}
+	return offsets
+}
+
+// ReadLogIndexAddress retrieves the offsets of the blocks within a section that
+// contain logs emitted by the given address, in ascending order.
+func ReadLogIndexAddress(db ethdb.KeyValueReader, address common.Address, section uint64, head common.Hash) []uint64 {
+	blob, _ := db.Get(logIndexKey(logIndexAddressPrefix, address.Bytes(), section, head))
+	return decodeLogIndexBlocks(blob)
+}
+
+// WriteLogIndexAddress stores the offsets of the blocks within a section that
+// contain logs emitted by the given address. The offsets must be ascending.
+func WriteLogIndexAddress(db ethdb.KeyValueWriter, address common.Address, section uint64, head common.Hash, offsets []uint64) {
+	if err := db.Put(logIndexKey(logIndexAddressPrefix, address.Bytes(), section, head), encodeLogIndexBlocks(offsets)); err != nil {
+		log.Crit("Failed to store log index address entry", "err", err)
+	}
+}
+
+// ReadLogIndexTopic retrieves the offsets of the blocks within a section that
+// contain logs with the given topic at any position, in ascending order.
+func ReadLogIndexTopic(db ethdb.KeyValueReader, topic common.Hash, section uint64, head common.Hash) []uint64 {
+	blob, _ := db.Get(logIndexKey(logIndexTopicPrefix, topic.Bytes(), section, head))
+	return decodeLogIndexBlocks(blob)
+}
+
+// WriteLogIndexTopic stores the offsets of the blocks within a section that
+// contain logs with the given topic at any position. The offsets must be
+// ascending.
+func WriteLogIndexTopic(db ethdb.KeyValueWriter, topic common.Hash, section uint64, head common.Hash, offsets []uint64) {
+	if err := db.Put(logIndexKey(logIndexTopicPrefix, topic.Bytes(), section, head), encodeLogIndexBlocks(offsets)); err != nil {
+		log.Crit("Failed to store log index topic entry", "err", err)
+	}
+}
+
+// isLogIndexKey reports whether a key found under one of the log index prefixes
+// belongs to the index. The prefixes are short enough for legacy trie nodes, keyed
+// by their raw hash, to share them, so the key lengths must match exactly.
+func isLogIndexKey(prefix, key []byte) bool {
+	switch {
+	case bytes.Equal(prefix, logIndexAddressPrefix):
+		return len(key) == len(prefix)+common.AddressLength+8+common.HashLength
+	case bytes.Equal(prefix, logIndexTopicPrefix):
+		return len(key) == len(prefix)+common.HashLength+8+common.HashLength
+	default:
+		// The progress of the chain indexer, see ChainIndexer.setValidSections
+		// and ChainIndexer.setSectionHead
+		key = key[len(prefix):]
+		return string(key) == "count" || (len(key) == len("shead")+8 && bytes.HasPrefix(key, []byte("shead")))
+	}
+}
+
+// DeleteLogIndex removes the entire address/topic log index, along with the
+// progress of the indexer maintaining it.
+func DeleteLogIndex(db ethdb.KeyValueStore) {
+	for _, prefix := range [][]byte{logIndexAddressPrefix, logIndexTopicPrefix, LogIndexPrefix} {
+		it := db.NewIterator(prefix, nil)
+		batch := db.NewBatch()
+		for it.Next() {
+			if !isLogIndexKey(prefix, it.Key()) {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to delete log index", "err", err)
+				}
+				batch.Reset()
+			}
+		}
+		if err := it.Error(); err != nil {
+			log.Crit("Failed to iterate log index", "err", err)
+		}
+		it.Release()
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete log index", "err", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the log index stores block lists per address and topic, keyed by
+// section head, and that it can be dropped altogether.
+func TestLogIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		addr  = common.HexToAddress("0x1")
+		topic = common.HexToHash("0x2")
+		head  = common.HexToHash("0xff")
+	)
+	WriteLogIndexAddress(db, addr, 1, head, []uint64{0, 3, 200, 4095})
+	WriteLogIndexTopic(db, topic, 1, head, []uint64{7})
+	db.Put(append(append([]byte{}, LogIndexPrefix...), []byte("count")...), []byte{1})
+	db.Put(append(append([]byte{}, LogIndexPrefix...), append([]byte("shead"), make([]byte, 8)...)...), head.Bytes())
+
+	// Legacy trie nodes are keyed by their raw hash, which may start with any of
+	// the log index prefixes
+	var nodes [][]byte
+	for _, prefix := range [][]byte{logIndexAddressPrefix, logIndexTopicPrefix, LogIndexPrefix} {
+		node := common.HexToHash("0xdeadbeef").Bytes()
+		copy(node, prefix)
+		db.Put(node, []byte{0x80})
+		nodes = append(nodes, node)
+	}
+
+	if have, want := ReadLogIndexAddress(db, addr, 1, head), []uint64{0, 3, 200, 4095}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("address blocks mismatch: have %v, want %v", have, want)
+	}
+	if have, want := ReadLogIndexTopic(db, topic, 1, head), []uint64{7}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("topic blocks mismatch: have %v, want %v", have, want)
+	}
+	if have := ReadLogIndexAddress(db, addr, 0, head); have != nil {
+		t.Fatalf("blocks in other section: %v", have)
+	}
+	if have := ReadLogIndexAddress(db, addr, 1, common.Hash{}); have != nil {
+		t.Fatalf("blocks under other section head: %v", have)
+	}
+	DeleteLogIndex(db)
+
+	if have := ReadLogIndexAddress(db, addr, 1, head); have != nil {
+		t.Fatalf("address blocks not deleted: %v", have)
+	}
+	if have := ReadLogIndexTopic(db, topic, 1, head); have != nil {
+		t.Fatalf("topic blocks not deleted: %v", have)
+	}
+	if ok, _ := db.Has(append(append([]byte{}, LogIndexPrefix...), []byte("count")...)); ok {
+		t.Fatal("indexer progress not deleted")
+	}
+	if ok, _ := db.Has(append(append([]byte{}, LogIndexPrefix...), append([]byte("shead"), make([]byte, 8)...)...)); ok {
+		t.Fatal("indexer section head not deleted")
+	}
+	for _, node := range nodes {
+		if ok, _ := db.Has(node); !ok {
+			t.Errorf("trie node %x deleted along with the log index", node)
+		}
+	}
+}
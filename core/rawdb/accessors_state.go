@@ -17,7 +17,10 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -94,3 +97,208 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadTrieNodeRefs retrieves the reference count record of the trie node with
+// the provided hash, kept by the online state pruning.
+func ReadTrieNodeRefs(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(trieNodeRefsKey(hash))
+	return data
+}
+
+// WriteTrieNodeRefs stores the reference count record of a trie node.
+func WriteTrieNodeRefs(db ethdb.KeyValueWriter, hash common.Hash, refs []byte) {
+	if err := db.Put(trieNodeRefsKey(hash), refs); err != nil {
+		log.Crit("Failed to store trie node references", "err", err)
+	}
+}
+
+// DeleteTrieNodeRefs deletes the reference count record of a trie node.
+func DeleteTrieNodeRefs(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(trieNodeRefsKey(hash)); err != nil {
+		log.Crit("Failed to delete trie node references", "err", err)
+	}
+}
+
+// RetainedTrieRoot is a state root kept on disk by the online state pruning,
+// along with the number of the block it was retained at.
+type RetainedTrieRoot struct {
+	Number uint64
+	Root   common.Hash
+}
+
+// ReadRetainedTrieRoots retrieves all the state roots retained by the online
+// state pruning, in ascending block number order.
+func ReadRetainedTrieRoots(db ethdb.Iteratee) []RetainedTrieRoot {
+	var roots []RetainedTrieRoot
+
+	it := db.NewIterator(retainedTrieRootPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(retainedTrieRootPrefix)+8+common.HashLength {
+			continue
+		}
+		roots = append(roots, RetainedTrieRoot{
+			Number: binary.BigEndian.Uint64(key[len(retainedTrieRootPrefix):]),
+			Root:   common.BytesToHash(key[len(retainedTrieRootPrefix)+8:]),
+		})
+	}
+	return roots
+}
+
+// WriteRetainedTrieRoot stores a state root retained at the given block number.
+func WriteRetainedTrieRoot(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Put(retainedTrieRootKey(number, root), nil); err != nil {
+		log.Crit("Failed to store retained trie root", "err", err)
+	}
+}
+
+// DeleteRetainedTrieRoot deletes a state root retained at the given block number.
+func DeleteRetainedTrieRoot(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Delete(retainedTrieRootKey(number, root)); err != nil {
+		log.Crit("Failed to delete retained trie root", "err", err)
+	}
+}
+
+// DeleteTrieNodeRefsAll deletes all the reference count records and retained
+// state roots of the online state pruning, leaving all trie nodes untracked.
+func DeleteTrieNodeRefsAll(db ethdb.KeyValueStore) error {
+	for _, prefix := range [][]byte{trieNodeRefsPrefix, retainedTrieRootPrefix} {
+		batch := db.NewBatch()
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			if prefix[0] == trieNodeRefsPrefix[0] && len(it.Key()) != len(trieNodeRefsPrefix)+common.HashLength {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadStateIndexTail retrieves the number of the oldest block whose state can be
+// served from the state index.
+func ReadStateIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(stateIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateIndexTail stores the number of the oldest block whose state can be
+// served from the state index.
+func WriteStateIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the state index tail", "err", err)
+	}
+}
+
+// WriteStateIndex adds the values of the accounts and storage slots before the
+// given canonical block, as recorded in its state history, to the state index.
+func WriteStateIndex(db ethdb.KeyValueWriter, number uint64, history *types.StateHistory) {
+	for _, account := range history.Accounts {
+		if err := db.Put(stateIndexAccountKey(account.Account, number), account.Blob); err != nil {
+			log.Crit("Failed to store account index", "err", err)
+		}
+	}
+	for _, storage := range history.Storages {
+		if storage.Wiped {
+			if err := db.Put(stateIndexWipeKey(storage.Account, number), nil); err != nil {
+				log.Crit("Failed to store storage wipe index", "err", err)
+			}
+		}
+		for i, slot := range storage.Keys {
+			if err := db.Put(stateIndexStorageKey(storage.Account, slot, number), storage.Vals[i]); err != nil {
+				log.Crit("Failed to store storage index", "err", err)
+			}
+		}
+	}
+}
+
+// DeleteStateIndex removes the entries of a block no longer canonical from the
+// state index.
+func DeleteStateIndex(db ethdb.KeyValueWriter, number uint64, history *types.StateHistory) {
+	for _, account := range history.Accounts {
+		if err := db.Delete(stateIndexAccountKey(account.Account, number)); err != nil {
+			log.Crit("Failed to delete account index", "err", err)
+		}
+	}
+	for _, storage := range history.Storages {
+		if storage.Wiped {
+			if err := db.Delete(stateIndexWipeKey(storage.Account, number)); err != nil {
+				log.Crit("Failed to delete storage wipe index", "err", err)
+			}
+		}
+		for _, slot := range storage.Keys {
+			if err := db.Delete(stateIndexStorageKey(storage.Account, slot, number)); err != nil {
+				log.Crit("Failed to delete storage index", "err", err)
+			}
+		}
+	}
+}
+
+// seekStateIndex retrieves the first entry of an index series at or after the
+// given block number, as long as it's not beyond the limit.
+func seekStateIndex(db ethdb.Iteratee, prefix []byte, number uint64, limit uint64) ([]byte, uint64, bool) {
+	it := db.NewIterator(prefix, encodeBlockNumber(number))
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		found := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if found > limit {
+			return nil, 0, false
+		}
+		return common.CopyBytes(it.Value()), found, true
+	}
+	return nil, 0, false
+}
+
+// ReadAccountIndex retrieves the value of an account in the state of the given
+// block from the state index, which is the value before its first modification
+// in the blocks after it, up to the limit. The flag reports whether such a
+// modification was found, otherwise the account is unchanged since.
+func ReadAccountIndex(db ethdb.Iteratee, account common.Hash, number uint64, limit uint64) ([]byte, bool) {
+	blob, _, ok := seekStateIndex(db, append(stateIndexAccountPrefix, account.Bytes()...), number+1, limit)
+	return blob, ok
+}
+
+// ReadStorageIndex retrieves the value of a storage slot in the state of the
+// given block from the state index, which is the value before its first
+// modification or wipe in the blocks after it, up to the limit. The flag reports
+// whether such a modification was found, otherwise the slot is unchanged since.
+func ReadStorageIndex(db ethdb.Iteratee, account common.Hash, slot common.Hash, number uint64, limit uint64) ([]byte, bool) {
+	var (
+		slotPrefix = append(append(stateIndexStoragePrefix, account.Bytes()...), slot.Bytes()...)
+		wipePrefix = append(stateIndexWipePrefix, account.Bytes()...)
+	)
+	blob, modified, ok := seekStateIndex(db, slotPrefix, number+1, limit)
+	if ok {
+		limit = modified
+	}
+	// A wipe records all the existing slots, so one before the modification
+	// means the slot didn't exist
+	if _, wiped, wipe := seekStateIndex(db, wipePrefix, number+1, limit); wipe && (!ok || wiped < modified) {
+		return nil, true
+	}
+	return blob, ok
+}
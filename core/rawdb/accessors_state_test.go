@@ -0,0 +1,90 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the state index resolves the values of accounts and storage slots
+// from the first modification after the requested block.
+func TestStateIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		acc  = common.HexToHash("0xaa")
+		slot = common.HexToHash("0x01")
+		new  = common.HexToHash("0x02")
+	)
+	// Block 3 modifies the account and the slot, block 5 destructs the account
+	// and block 7 recreates the slot which didn't exist before the destruction
+	WriteStateIndex(db, 3, &types.StateHistory{
+		Accounts: []types.HistoryAccount{{Account: acc, Blob: []byte{0x01}}},
+		Storages: []types.HistoryStorage{{Account: acc, Keys: []common.Hash{slot}, Vals: [][]byte{{0x01}}}},
+	})
+	WriteStateIndex(db, 5, &types.StateHistory{
+		Accounts: []types.HistoryAccount{{Account: acc, Blob: []byte{0x03}}},
+		Storages: []types.HistoryStorage{{Account: acc, Wiped: true, Keys: []common.Hash{slot}, Vals: [][]byte{{0x03}}}},
+	})
+	WriteStateIndex(db, 7, &types.StateHistory{
+		Storages: []types.HistoryStorage{{Account: acc, Keys: []common.Hash{new}, Vals: [][]byte{nil}}},
+	})
+	tests := []struct {
+		number, limit uint64
+		account       []byte
+		accountOk     bool
+		slot          []byte
+		slotOk        bool
+		new           []byte
+		newOk         bool
+	}{
+		{0, 10, []byte{0x01}, true, []byte{0x01}, true, nil, true},
+		{2, 10, []byte{0x01}, true, []byte{0x01}, true, nil, true},
+		{3, 10, []byte{0x03}, true, []byte{0x03}, true, nil, true},
+		{4, 10, []byte{0x03}, true, []byte{0x03}, true, nil, true},
+		{5, 10, nil, false, nil, false, nil, true},
+		{7, 10, nil, false, nil, false, nil, false},
+		{2, 4, []byte{0x01}, true, []byte{0x01}, true, nil, false},
+		{3, 4, nil, false, nil, false, nil, false},
+	}
+	for i, tt := range tests {
+		if blob, ok := ReadAccountIndex(db, acc, tt.number, tt.limit); ok != tt.accountOk || !bytes.Equal(blob, tt.account) {
+			t.Errorf("test %d: account mismatch: have %x/%v, want %x/%v", i, blob, ok, tt.account, tt.accountOk)
+		}
+		if blob, ok := ReadStorageIndex(db, acc, slot, tt.number, tt.limit); ok != tt.slotOk || !bytes.Equal(blob, tt.slot) {
+			t.Errorf("test %d: slot mismatch: have %x/%v, want %x/%v", i, blob, ok, tt.slot, tt.slotOk)
+		}
+		if blob, ok := ReadStorageIndex(db, acc, new, tt.number, tt.limit); ok != tt.newOk || !bytes.Equal(blob, tt.new) {
+			t.Errorf("test %d: new slot mismatch: have %x/%v, want %x/%v", i, blob, ok, tt.new, tt.newOk)
+		}
+	}
+	// Removing the destruction exposes the later modification
+	DeleteStateIndex(db, 5, &types.StateHistory{
+		Accounts: []types.HistoryAccount{{Account: acc}},
+		Storages: []types.HistoryStorage{{Account: acc, Wiped: true, Keys: []common.Hash{slot}}},
+	})
+	if blob, ok := ReadAccountIndex(db, acc, 4, 10); ok {
+		t.Errorf("account index not deleted: %x", blob)
+	}
+	if blob, ok := ReadStorageIndex(db, acc, new, 4, 10); !ok || blob != nil {
+		t.Errorf("new slot mismatch after deletion: have %x/%v", blob, ok)
+	}
+}
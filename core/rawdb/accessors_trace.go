@@ -0,0 +1,119 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// TraceIndexFrom marks an address as the sender of a call frame, or the
+	// self-destructed contract.
+	TraceIndexFrom byte = 1 << iota
+
+	// TraceIndexTo marks an address as the recipient of a call frame, the
+	// created contract, or the beneficiary of a self-destruct.
+	TraceIndexTo
+)
+
+// TraceIndexEntry is a transaction whose call frames involve an address.
+type TraceIndexEntry struct {
+	Number     uint64 // Number of the block containing the transaction
+	TxIndex    uint32 // Index of the transaction within the block
+	Directions byte   // Combination of TraceIndexFrom and TraceIndexTo
+}
+
+// ReadTraceIndexTail retrieves the number of the oldest block whose call traces
+// have been indexed by address.
+func ReadTraceIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(traceIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTraceIndexTail stores the number of the oldest block whose call traces
+// have been indexed by address.
+func WriteTraceIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace index tail", "err", err)
+	}
+}
+
+// ReadTraceIndexHead retrieves the number of the newest block whose call traces
+// have been indexed by address.
+func ReadTraceIndexHead(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(traceIndexHeadKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTraceIndexHead stores the number of the newest block whose call traces
+// have been indexed by address.
+func WriteTraceIndexHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(traceIndexHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the trace index head", "err", err)
+	}
+}
+
+// WriteTraceIndex stores the addresses involved in the call frames of the
+// transactions of a canonical block, each mapped to the directions it was
+// involved in, keyed by transaction index.
+func WriteTraceIndex(db ethdb.KeyValueWriter, number uint64, addresses []map[common.Address]byte) {
+	for txIndex, directions := range addresses {
+		for address, dirs := range directions {
+			if err := db.Put(traceIndexKey(address, number, uint32(txIndex)), []byte{dirs}); err != nil {
+				log.Crit("Failed to store trace index entry", "err", err)
+			}
+		}
+	}
+}
+
+// ReadTraceIndex retrieves the transactions within the given inclusive block
+// range whose call frames involve the given address, in ascending order.
+func ReadTraceIndex(db ethdb.Iteratee, address common.Address, from, to uint64) []TraceIndexEntry {
+	prefix := append(append([]byte{}, traceIndexPrefix...), address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var entries []TraceIndexEntry
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if len(key) != len(prefix)+12 || len(value) != 1 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		entries = append(entries, TraceIndexEntry{
+			Number:     number,
+			TxIndex:    binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Directions: value[0],
+		})
+	}
+	return entries
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the trace index returns the transactions involving an address
+// within a block range, in order.
+func TestTraceIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+	)
+	for number := uint64(1); number <= 3; number++ {
+		WriteTraceIndex(db, number, []map[common.Address]byte{
+			{a: TraceIndexFrom, b: TraceIndexTo},
+			{},
+			{b: TraceIndexFrom | TraceIndexTo},
+		})
+	}
+	WriteTraceIndexTail(db, 1)
+	WriteTraceIndexHead(db, 3)
+
+	if tail := ReadTraceIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("tail mismatch: have %v, want 1", tail)
+	}
+	if head := ReadTraceIndexHead(db); head == nil || *head != 3 {
+		t.Fatalf("head mismatch: have %v, want 3", head)
+	}
+	if have, want := ReadTraceIndex(db, a, 2, 3), []TraceIndexEntry{{2, 0, TraceIndexFrom}, {3, 0, TraceIndexFrom}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("entries of a mismatch: have %v, want %v", have, want)
+	}
+	if have, want := ReadTraceIndex(db, b, 2, 2), []TraceIndexEntry{{2, 0, TraceIndexTo}, {2, 2, TraceIndexFrom | TraceIndexTo}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("entries of b mismatch: have %v, want %v", have, want)
+	}
+	if entries := ReadTraceIndex(db, common.Address{0x0c}, 0, 3); len(entries) != 0 {
+		t.Errorf("entries of unknown address: %v", entries)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"time"
 
@@ -38,7 +39,8 @@ import (
 type freezerdb struct {
 	ethdb.KeyValueStore
 	ethdb.AncientStore
-	diffStore ethdb.KeyValueStore
+	diffStore  ethdb.KeyValueStore
+	freezeDiff bool // Whether the freezer moves diff layers into the ancient store
 }
 
 // Close implements io.Closer, closing both the fast key-value store as well as
@@ -71,6 +73,19 @@ func (frdb *freezerdb) SetDiffStore(diff ethdb.KeyValueStore) {
 		frdb.diffStore.Close()
 	}
 	frdb.diffStore = diff
+	if frdb.freezeDiff {
+		frdb.FreezeDiffLayers()
+	}
+}
+
+// FreezeDiffLayers makes the chain freezer move the diff layers of frozen blocks
+// from the diff store into the ancient store, instead of leaving them for the
+// blockchain to prune.
+func (frdb *freezerdb) FreezeDiffLayers() {
+	frdb.freezeDiff = true
+	if f, ok := frdb.AncientStore.(*freezer); ok {
+		f.setDiffStore(frdb.diffStore)
+	}
 }
 
 // Freeze is a helper method used for external testing to trigger and block until
@@ -134,6 +149,16 @@ func (db *nofreezedb) TruncateAncients(items uint64) error {
 	return errNotSupported
 }
 
+// TruncateTail returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) TruncateTail(n uint64) error {
+	return errNotSupported
+}
+
+// RepairAncient returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) RepairAncient(kind string, number uint64, item []byte) error {
+	return errNotSupported
+}
+
 // Sync returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) Sync() error {
 	return errNotSupported
@@ -147,6 +172,9 @@ func (db *nofreezedb) SetDiffStore(diff ethdb.KeyValueStore) {
 	db.diffStore = diff
 }
 
+// FreezeDiffLayers is a noop as we don't have a backing chain freezer.
+func (db *nofreezedb) FreezeDiffLayers() {}
+
 func (db *nofreezedb) AncientOffSet() uint64 {
 	return 0
 }
@@ -189,7 +217,7 @@ func WriteOffSetOfLastAncientFreezer(db ethdb.KeyValueWriter, offset uint64) {
 // NewFreezerDb only create a freezer without statedb.
 func NewFreezerDb(db ethdb.KeyValueStore, frz, namespace string, readonly bool, newOffSet uint64) (*freezer, error) {
 	// Create the idle freezer instance, this operation should be atomic to avoid mismatch between offset and acientDB.
-	frdb, err := newFreezer(frz, namespace, readonly)
+	frdb, err := newFreezer(frz, namespace, readonly, "")
 	if err != nil {
 		return nil, err
 	}
@@ -202,8 +230,16 @@ func NewFreezerDb(db ethdb.KeyValueStore, frz, namespace string, readonly bool,
 // value data store with a freezer moving immutable chain segments into cold
 // storage.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace string, readonly, disableFreeze, isLastOffset bool) (ethdb.Database, error) {
+	return NewDatabaseWithFreezerCompression(db, freezer, namespace, "", readonly, disableFreeze, isLastOffset)
+}
+
+// NewDatabaseWithFreezerCompression creates a high level database with a freezer,
+// compressing the ancient bodies and receipts with the given algorithm. Existing
+// items compressed differently are retained, an empty compression keeps using the
+// compression of the existing tables.
+func NewDatabaseWithFreezerCompression(db ethdb.KeyValueStore, freezer string, namespace string, compression string, readonly, disableFreeze, isLastOffset bool) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newFreezer(freezer, namespace, readonly)
+	frdb, err := newFreezer(freezer, namespace, readonly, compression)
 	if err != nil {
 		return nil, err
 	}
@@ -338,6 +374,68 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+const (
+	// DBLeveldb is the identifier of the LevelDB key-value backend.
+	DBLeveldb = "leveldb"
+
+	// DBPebble is the identifier of the Pebble key-value backend.
+	DBPebble = "pebble"
+)
+
+// NewKeyValueStore opens a persistent key-value store backed by the given
+// database engine.
+func NewKeyValueStore(engine string, file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+	switch engine {
+	case DBLeveldb:
+		return leveldb.New(file, cache, handles, namespace, readonly)
+	case DBPebble:
+		return newPebbleDB(file, cache, handles, namespace, readonly)
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
+
+// NewPebbleDBDatabase creates a persistent key-value database without a freezer
+// moving immutable chain segments into cold storage.
+func NewPebbleDBDatabase(file string, cache int, handles int, namespace string, readonly bool) (ethdb.Database, error) {
+	db, err := newPebbleDB(file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(db), nil
+}
+
+// NewPebbleDBDatabaseWithFreezer creates a persistent key-value database with a
+// freezer moving immutable chain segments into cold storage.
+func NewPebbleDBDatabaseWithFreezer(file string, cache int, handles int, freezer string, namespace string, readonly, disableFreeze, isLastOffset bool) (ethdb.Database, error) {
+	kvdb, err := newPebbleDB(file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, freezer, namespace, readonly, disableFreeze, isLastOffset)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
+// PreexistingDatabase checks the given data directory whether a database is
+// already instantiated at that location, and if so, returns the engine of it
+// (or the empty string).
+func PreexistingDatabase(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		return "" // No pre-existing db
+	}
+	if matches, err := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 || err != nil {
+		if err != nil {
+			panic(err) // only possible if the pattern is malformed
+		}
+		return DBPebble
+	}
+	return DBLeveldb
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -368,7 +466,7 @@ func (s *stat) Count() string {
 	return s.count.String()
 }
 func AncientInspect(db ethdb.Database) error {
-	offset := counter(ReadOffSetOfCurrentAncientFreezer(db))
+	offset := counter(db.AncientOffSet())
 	// Get number of ancient rows inside the freezer.
 	ancients := counter(0)
 	if count, err := db.ItemAmountInAncient(); err != nil {
@@ -424,6 +522,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		bloomBits       stat
 		cliqueSnaps     stat
 		parliaSnaps     stat
+		stateHistories  stat
+		traceIndexes    stat
+		logIndexes      stat
 
 		// Ancient store statistics
 		ancientHeadersSize  common.StorageSize
@@ -431,6 +532,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		ancientReceiptsSize common.StorageSize
 		ancientTdsSize      common.StorageSize
 		ancientHashesSize   common.StorageSize
+		ancientDiffsSize    common.StorageSize
+		ancientHistorySize  common.StorageSize
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -456,6 +559,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			headers.Add(size)
 		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
 			bodies.Add(size)
+		case bytes.HasPrefix(key, stateHistoryPrefix) && len(key) == (len(stateHistoryPrefix)+8+common.HashLength):
+			stateHistories.Add(size)
 		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
 			receipts.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
@@ -476,6 +581,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			storageSnaps.Add(size)
 		case bytes.HasPrefix(key, preimagePrefix) && len(key) == (len(preimagePrefix)+common.HashLength):
 			preimages.Add(size)
+		case bytes.HasPrefix(key, traceIndexPrefix) && len(key) == (len(traceIndexPrefix)+common.AddressLength+12):
+			traceIndexes.Add(size)
+		case bytes.HasPrefix(key, logIndexAddressPrefix) && len(key) == (len(logIndexAddressPrefix)+common.AddressLength+8+common.HashLength):
+			logIndexes.Add(size)
+		case bytes.HasPrefix(key, logIndexTopicPrefix) && len(key) == (len(logIndexTopicPrefix)+2*common.HashLength+8):
+			logIndexes.Add(size)
+		case bytes.HasPrefix(key, LogIndexPrefix):
+			logIndexes.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
@@ -501,7 +614,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
 				fastTrieProgressKey, snapshotDisabledKey, snapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey,
+				uncleanShutdownKey, badBlockKey, traceIndexTailKey, traceIndexHeadKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -520,8 +633,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		}
 	}
 	// Inspect append-only file store then.
-	ancientSizes := []*common.StorageSize{&ancientHeadersSize, &ancientBodiesSize, &ancientReceiptsSize, &ancientHashesSize, &ancientTdsSize}
-	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerHashTable, freezerDifficultyTable} {
+	ancientSizes := []*common.StorageSize{&ancientHeadersSize, &ancientBodiesSize, &ancientReceiptsSize, &ancientHashesSize, &ancientTdsSize, &ancientDiffsSize, &ancientHistorySize}
+	for i, category := range []string{freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerHashTable, freezerDifficultyTable, freezerDiffLayerTable, freezerStateHistoryTable} {
 		if size, err := db.AncientSize(category); err == nil {
 			*ancientSizes[i] += common.StorageSize(size)
 			total += common.StorageSize(size)
@@ -543,6 +656,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Trace address index", traceIndexes.Size(), traceIndexes.Count()},
+		{"Key-Value store", "Log address/topic index", logIndexes.Size(), logIndexes.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -550,6 +665,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
 		{"Key-Value store", "Parlia snapshots", parliaSnaps.Size(), parliaSnaps.Count()},
+		{"Key-Value store", "State histories", stateHistories.Size(), stateHistories.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Key-Value store", "Shutdown metadata", shutdownInfo.Size(), shutdownInfo.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
@@ -557,6 +673,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Ancient store", "Receipt lists", ancientReceiptsSize.String(), ancients.String()},
 		{"Ancient store", "Difficulties", ancientTdsSize.String(), ancients.String()},
 		{"Ancient store", "Block number->hash", ancientHashesSize.String(), ancients.String()},
+		{"Ancient store", "Diff layers", ancientDiffsSize.String(), ancients.String()},
+		{"Ancient store", "State histories", ancientHistorySize.String(), ancients.String()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
 	}
@@ -75,6 +75,7 @@ type freezer struct {
 	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
 	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
 	frozen    uint64 // Number of blocks already frozen
+	tail      uint64 // Number of blocks discarded from the tail of the current freezer
 	threshold uint64 // Number of recent blocks not to freeze (params.FullImmutabilityThreshold apart from tests)
 
 	readonly     bool
@@ -87,11 +88,15 @@ type freezer struct {
 	closeOnce sync.Once
 
 	offset uint64 // Starting BlockNumber in current freezer
+
+	diffStore atomic.Value // Diff layer store to freeze the diff layers of frozen blocks from
 }
 
 // newFreezer creates a chain freezer that moves ancient chain data into
-// append-only flat file containers.
-func newFreezer(datadir string, namespace string, readonly bool) (*freezer, error) {
+// append-only flat file containers. The compression of the bodies and receipts
+// defaults to the one of the existing tables, with a different one only the new
+// items are compressed with it.
+func newFreezer(datadir string, namespace string, readonly bool, compression string) (*freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -121,7 +126,17 @@ func newFreezer(datadir string, namespace string, readonly bool) (*freezer, erro
 		quit:         make(chan struct{}),
 	}
 	for name, disableSnappy := range FreezerNoSnappy {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+		codec := codecSnappy
+		if disableSnappy {
+			codec = codecNone
+		}
+		if _, ok := freezerCompressibleTables[name]; ok {
+			codec, err = resolveTableCodec(datadir, name, compression)
+		}
+		var table *freezerTable
+		if err == nil {
+			table, err = newCodecTable(datadir, name, readMeter, writeMeter, sizeGauge, freezerTableSize, codec)
+		}
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -186,12 +201,13 @@ func (f *freezer) Ancients() (uint64, error) {
 
 // ItemAmountInAncient returns the actual length of current ancientDB.
 func (f *freezer) ItemAmountInAncient() (uint64, error) {
-	return atomic.LoadUint64(&f.frozen) - atomic.LoadUint64(&f.offset), nil
+	return atomic.LoadUint64(&f.frozen) - f.AncientOffSet(), nil
 }
 
-// AncientOffSet returns the offset of current ancientDB.
+// AncientOffSet returns the number of the first block available in current
+// ancientDB, including the blocks discarded from its tail.
 func (f *freezer) AncientOffSet() uint64 {
-	return atomic.LoadUint64(&f.offset)
+	return atomic.LoadUint64(&f.offset) + atomic.LoadUint64(&f.tail)
 }
 
 // AncientSize returns the ancient size of the specified category.
@@ -269,6 +285,43 @@ func (f *freezer) TruncateAncients(items uint64) error {
 	return nil
 }
 
+// TruncateTail discards the ancient data below the provided block number. The
+// tables drop data in whole files only, so the resulting tail reported by
+// AncientOffSet may be lower than requested.
+func (f *freezer) TruncateTail(tail uint64) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	if tail <= f.AncientOffSet() {
+		return nil
+	}
+	if frozen := atomic.LoadUint64(&f.frozen); tail > frozen {
+		tail = frozen
+	}
+	for _, table := range f.tables {
+		if err := table.truncateTail(tail - f.offset); err != nil {
+			return err
+		}
+	}
+	f.repairTail()
+	return nil
+}
+
+// RepairAncient overwrites a corrupted item of an ancient table with its
+// original content, which must encode to the size of the stored item.
+func (f *freezer) RepairAncient(kind string, number uint64, item []byte) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	if number < f.AncientOffSet() {
+		return errOutOfBounds
+	}
+	if table := f.tables[kind]; table != nil {
+		return table.rewrite(number-f.offset, item)
+	}
+	return errUnknownTable
+}
+
 // Sync flushes all data tables to disk.
 func (f *freezer) Sync() error {
 	var errs []error
@@ -283,6 +336,41 @@ func (f *freezer) Sync() error {
 	return nil
 }
 
+// setDiffStore sets the store the diff layers of frozen blocks are moved from.
+func (f *freezer) setDiffStore(diff ethdb.KeyValueStore) {
+	if diff != nil {
+		f.diffStore.Store(diff)
+	}
+}
+
+// appendDiffLayer moves the diff layer of a frozen block from the diff store
+// into the ancient diff layer table, if it's available. Items of blocks without
+// a diff layer are left empty. It returns whether the diff layer was frozen.
+func (f *freezer) appendDiffLayer(diffStore ethdb.KeyValueReader, number uint64, hash common.Hash) bool {
+	blob := ReadDiffLayerRLP(diffStore, hash)
+	if len(blob) == 0 {
+		return false
+	}
+	return f.appendOptional(freezerDiffLayerTable, number, hash, blob)
+}
+
+// appendOptional appends the item of a frozen block to an optional table,
+// padding the items of the preceding blocks missing from it with empty ones.
+func (f *freezer) appendOptional(kind string, number uint64, hash common.Hash, blob []byte) bool {
+	table := f.tables[kind]
+	for items := atomic.LoadUint64(&table.items); items < number-f.offset; items++ {
+		if err := table.Append(items, nil); err != nil {
+			log.Error("Failed to pad ancient table", "table", kind, "number", items+f.offset, "err", err)
+			return false
+		}
+	}
+	if err := table.Append(number-f.offset, blob); err != nil {
+		log.Error("Failed to append ancient item", "table", kind, "number", number, "hash", hash, "err", err)
+		return false
+	}
+	return true
+}
+
 // freeze is a background thread that periodically checks the blockchain for any
 // import progress and moves ancient data from the fast database into the freezer.
 //
@@ -358,6 +446,9 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 			start    = time.Now()
 			first    = f.frozen
 			ancients = make([]common.Hash, 0, limit-f.frozen)
+
+			diffs        []common.Hash
+			diffStore, _ = f.diffStore.Load().(ethdb.KeyValueStore)
 		)
 		for f.frozen <= limit {
 			// Retrieves all the components of the canonical block
@@ -392,6 +483,13 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 				break
 			}
 			ancients = append(ancients, hash)
+
+			if diffStore != nil && f.appendDiffLayer(diffStore, f.frozen-1, hash) {
+				diffs = append(diffs, hash)
+			}
+			if history := ReadStateHistoryRLP(nfdb, hash, f.frozen-1); len(history) > 0 {
+				f.appendOptional(freezerStateHistoryTable, f.frozen-1, hash, history)
+			}
 		}
 		// Batch of blocks have been frozen, flush them before wiping from leveldb
 		if err := f.Sync(); err != nil {
@@ -411,6 +509,17 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 		}
 		batch.Reset()
 
+		// Wipe out the frozen diff layers from the diff store
+		if len(diffs) > 0 {
+			diffBatch := diffStore.NewBatch()
+			for _, hash := range diffs {
+				DeleteDiffLayer(diffBatch, hash)
+			}
+			if err := diffBatch.Write(); err != nil {
+				log.Crit("Failed to delete frozen diff layers", "err", err)
+			}
+		}
+
 		// Wipe out side chains also and track dangling side chians
 		var dangling []common.Hash
 		for number := first; number < f.frozen; number++ {
@@ -477,10 +586,14 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 	}
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length. Optional tables are only
+// truncated if they are ahead of the others.
 func (f *freezer) repair() error {
 	min := uint64(math.MaxUint64)
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if _, ok := freezerOptionalTables[name]; ok {
+			continue
+		}
 		items := atomic.LoadUint64(&table.items)
 		if min > items {
 			min = items
@@ -491,6 +604,23 @@ func (f *freezer) repair() error {
 			return err
 		}
 	}
-	atomic.StoreUint64(&f.frozen, min)
+	atomic.StoreUint64(&f.frozen, f.offset+min)
+	f.repairTail()
 	return nil
 }
+
+// repairTail sets the tail of the freezer to the highest tail of the data
+// tables, since the blocks below it are incomplete. Optional tables are not
+// considered.
+func (f *freezer) repairTail() {
+	var max uint64
+	for name, table := range f.tables {
+		if _, ok := freezerOptionalTables[name]; ok {
+			continue
+		}
+		if tail := uint64(atomic.LoadUint32(&table.itemOffset)); tail > max {
+			max = tail
+		}
+	}
+	atomic.StoreUint64(&f.tail, max)
+}
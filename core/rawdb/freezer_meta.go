@@ -0,0 +1,279 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// FreezerCompressionSnappy is the identifier of the snappy compression of
+	// the ancient bodies and receipts.
+	FreezerCompressionSnappy = "snappy"
+
+	// FreezerCompressionZstd is the identifier of the zstd compression of the
+	// ancient bodies and receipts.
+	FreezerCompressionZstd = "zstd"
+)
+
+// freezerTableSize is the maximum size of the data files of the freezer tables.
+const freezerTableSize = 2 * 1000 * 1000 * 1000
+
+// freezerCodec is the encoding of the items stored in a freezer table, which
+// also determines the names of the table files.
+type freezerCodec uint8
+
+const (
+	codecNone freezerCodec = iota
+	codecSnappy
+	codecZstd
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// initZstd creates the shared zstd encoder and decoder on first use, both are
+// safe for concurrent use of their stateless methods.
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
+
+// String implements fmt.Stringer.
+func (c freezerCodec) String() string {
+	switch c {
+	case codecSnappy:
+		return FreezerCompressionSnappy
+	case codecZstd:
+		return FreezerCompressionZstd
+	default:
+		return "none"
+	}
+}
+
+// marker returns the character tagging the file extensions of the codec.
+func (c freezerCodec) marker() byte {
+	switch c {
+	case codecSnappy:
+		return 'c'
+	case codecZstd:
+		return 'z'
+	default:
+		return 'r'
+	}
+}
+
+// indexName returns the name of the index file of a table.
+func (c freezerCodec) indexName(name string) string {
+	return fmt.Sprintf("%s.%cidx", name, c.marker())
+}
+
+// dataName returns the name of a data file of a table.
+func (c freezerCodec) dataName(name string, num uint32) string {
+	return fmt.Sprintf("%s.%04d.%cdat", name, num, c.marker())
+}
+
+// metaName returns the name of the metadata file of a table.
+func (c freezerCodec) metaName(name string) string {
+	return fmt.Sprintf("%s.%cmeta", name, c.marker())
+}
+
+// encode encodes an item for storage.
+func (c freezerCodec) encode(blob []byte) []byte {
+	switch c {
+	case codecSnappy:
+		return snappy.Encode(nil, blob)
+	case codecZstd:
+		initZstd()
+		return zstdEncoder.EncodeAll(blob, nil)
+	default:
+		return blob
+	}
+}
+
+// decode decodes a stored item.
+func (c freezerCodec) decode(blob []byte) ([]byte, error) {
+	switch c {
+	case codecSnappy:
+		return snappy.Decode(nil, blob)
+	case codecZstd:
+		initZstd()
+		return zstdDecoder.DecodeAll(blob, nil)
+	default:
+		return blob, nil
+	}
+}
+
+// parseFreezerCompression returns the codec of the given compression setting.
+func parseFreezerCompression(compression string) (freezerCodec, error) {
+	switch compression {
+	case FreezerCompressionSnappy:
+		return codecSnappy, nil
+	case FreezerCompressionZstd:
+		return codecZstd, nil
+	default:
+		return codecNone, fmt.Errorf("unknown ancient compression %q", compression)
+	}
+}
+
+const (
+	// freezerTableLegacy is the version of the tables created without a metadata
+	// file, whose index entries don't carry checksums.
+	freezerTableLegacy = uint16(0)
+
+	// freezerTableVersion is the version of the tables whose index entries carry
+	// the checksums of the items, either created as such or upgraded on append.
+	freezerTableVersion = uint16(1)
+)
+
+// freezerTableMeta is the metadata of a freezer table, stored next to its index.
+type freezerTableMeta struct {
+	Version uint16
+	Checked uint64 `rlp:"optional"` // First item carrying a checksum, the ones before predate the upgrade
+}
+
+// openTableMeta returns the metadata of a table, writing the one of the current
+// version if the table doesn't exist yet. An index upgrade interrupted after
+// writing the metadata is completed.
+func openTableMeta(path, name string, codec freezerCodec) (*freezerTableMeta, error) {
+	metaPath := filepath.Join(path, codec.metaName(name))
+	upgradePath := filepath.Join(path, codec.indexName(name)) + ".upgrade"
+	if blob, err := os.ReadFile(metaPath); err == nil {
+		meta := new(freezerTableMeta)
+		if err := rlp.DecodeBytes(blob, meta); err != nil {
+			return nil, fmt.Errorf("invalid freezer table metadata %s: %v", metaPath, err)
+		}
+		if meta.Version > freezerTableVersion {
+			return nil, fmt.Errorf("unsupported freezer table version %d of %s", meta.Version, metaPath)
+		}
+		if _, err := os.Stat(upgradePath); err == nil {
+			if err := os.Rename(upgradePath, filepath.Join(path, codec.indexName(name))); err != nil {
+				return nil, err
+			}
+		}
+		return meta, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No metadata, tables holding data already are legacy ones. Discard the
+	// upgraded index of an upgrade interrupted before switching over.
+	if stat, err := os.Stat(filepath.Join(path, codec.indexName(name))); err == nil && stat.Size() > 0 {
+		if err := os.Remove(upgradePath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return &freezerTableMeta{Version: freezerTableLegacy}, nil
+	}
+	meta := &freezerTableMeta{Version: freezerTableVersion}
+	if err := writeTableMeta(path, name, codec, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// writeTableMeta writes the metadata of a table.
+func writeTableMeta(path, name string, codec freezerCodec, meta *freezerTableMeta) error {
+	blob, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(path, codec.metaName(name)), blob)
+}
+
+// writeFileAtomic writes the content of a small file, replacing it atomically.
+func writeFileAtomic(path string, blob []byte) error {
+	file, err := openFreezerFileTruncated(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(blob); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// dataFileCodec returns the codec of a data file, told by the marker of its
+// file extension.
+func dataFileCodec(file string) freezerCodec {
+	if len(file) < 4 {
+		return codecNone
+	}
+	switch file[len(file)-4] {
+	case codecSnappy.marker():
+		return codecSnappy
+	case codecZstd.marker():
+		return codecZstd
+	default:
+		return codecNone
+	}
+}
+
+// tableLayoutCodec returns the codec naming the index and metadata files of a
+// table. Compressed tables retain the names of the codec they were created with
+// when switching codecs, only their new data files are named after the new one.
+func tableLayoutCodec(path, name string, codec freezerCodec) freezerCodec {
+	if codec == codecNone {
+		return codec
+	}
+	if existing, ok := existingTableCodec(path, name); ok {
+		return existing
+	}
+	return codec
+}
+
+// existingTableCodec returns the codec a compressed table was created with.
+func existingTableCodec(path, name string) (freezerCodec, bool) {
+	for _, codec := range []freezerCodec{codecZstd, codecSnappy} {
+		if _, err := os.Stat(filepath.Join(path, codec.indexName(name))); err == nil {
+			return codec, true
+		}
+	}
+	return codecNone, false
+}
+
+// resolveTableCodec returns the codec to compress the new items of a table with.
+// An empty compression setting keeps the codec the table was created with, snappy
+// is used for new ones. Items already stored keep their codec, which is told by
+// the data file holding them.
+func resolveTableCodec(path, name, compression string) (freezerCodec, error) {
+	if compression == "" {
+		if existing, ok := existingTableCodec(path, name); ok {
+			return existing, nil
+		}
+		return codecSnappy, nil
+	}
+	return parseFreezerCompression(compression)
+}
@@ -17,19 +17,21 @@
 package rawdb
 
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -43,46 +45,68 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// errChecksumMismatch is returned if the data of an item doesn't match the
+	// checksum stored in its index entry.
+	errChecksumMismatch = errors.New("checksum mismatch")
 )
 
+// checksumTable is the CRC32 polynomial used to checksum the stored items.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
 // indexEntry contains the number/id of the file that the data resides in, aswell as the
-// offset within the file to the end of the data
-// In serialized form, the filenum is stored as uint16.
+// offset within the file to the end of the data and the checksum of the stored item.
+// In serialized form, the filenum is stored as uint16. Tables created before the
+// checksums were introduced store entries without it.
 type indexEntry struct {
-	filenum uint32 // stored as uint16 ( 2 bytes)
-	offset  uint32 // stored as uint32 ( 4 bytes)
+	filenum  uint32 // stored as uint16 ( 2 bytes)
+	offset   uint32 // stored as uint32 ( 4 bytes)
+	checksum uint32 // stored as uint32 ( 4 bytes)
 }
 
-const indexEntrySize = 6
+const (
+	indexEntrySize       = 10
+	legacyIndexEntrySize = 6
+)
 
-// unmarshallBinary deserializes binary b into the rawIndex entry.
+// unmarshallBinary deserializes binary b into the rawIndex entry. The checksum
+// is only decoded if b holds a full entry, as legacy entries have none.
 func (i *indexEntry) unmarshalBinary(b []byte) error {
 	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
 	i.offset = binary.BigEndian.Uint32(b[2:6])
+	if len(b) >= indexEntrySize {
+		i.checksum = binary.BigEndian.Uint32(b[6:10])
+	}
 	return nil
 }
 
-// marshallBinary serializes the rawIndex entry into binary.
+// marshallBinary serializes the rawIndex entry into binary. The legacy format
+// is the prefix of the result without the checksum.
 func (i *indexEntry) marshallBinary() []byte {
 	b := make([]byte, indexEntrySize)
 	binary.BigEndian.PutUint16(b[:2], uint16(i.filenum))
 	binary.BigEndian.PutUint32(b[2:6], i.offset)
+	binary.BigEndian.PutUint32(b[6:10], i.checksum)
 	return b
 }
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
-// file (uncompressed 64 bit indices into the data file).
+// It consists of a data file (snappy or zstd encoded arbitrary data blobs) and an
+// indexEntry file (uncompressed indices into the data file with the item checksums).
 type freezerTable struct {
 	// WARNING: The `items` field is accessed atomically. On 32 bit platforms, only
 	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
 	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
 	items uint64 // Number of items stored in the table (including items removed from tail)
 
-	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	codec       freezerCodec // Encoding of the new items, the stored ones keep the codec of their data file
+	layout      freezerCodec // Codec naming the index and metadata files, the one the table was created with
+	legacy      bool         // Whether the index entries lack checksums
+	checked     uint64       // First item carrying a checksum, the ones before predate the index upgrade
+	entrySize   int64        // Size of the index entries
+	maxFileSize uint32       // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	files  map[uint32]*os.File // open files
@@ -91,7 +115,7 @@ type freezerTable struct {
 	index  *os.File            // File descriptor for the indexEntry file of the table
 
 	// In the case that old items are deleted (from the tail), we use itemOffset
-	// to count how many historic items have gone missing.
+	// to count how many historic items have gone missing. It's accessed atomically.
 	itemOffset uint32 // Offset (number of discarded items)
 
 	headBytes  uint32        // Number of bytes written to the head file
@@ -103,14 +127,21 @@ type freezerTable struct {
 	lock   sync.RWMutex // Mutex protecting the data file descriptors
 }
 
-// NewFreezerTable opens the given path as a freezer table.
+// NewFreezerTable opens the given path as a freezer table. Compressed tables
+// created with zstd are opened as such.
 func NewFreezerTable(path, name string, disableSnappy bool) (*freezerTable, error) {
-	return newTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, disableSnappy)
+	codec := codecSnappy
+	if disableSnappy {
+		codec = codecNone
+	} else if existing, ok := existingTableCodec(path, name); ok {
+		codec = existing
+	}
+	return newCodecTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, codec)
 }
 
 // newTable opens a freezer table with default settings - 2G files
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (*freezerTable, error) {
-	return newCustomTable(path, name, readMeter, writeMeter, sizeGauge, 2*1000*1000*1000, disableSnappy)
+	return newCustomTable(path, name, readMeter, writeMeter, sizeGauge, freezerTableSize, disableSnappy)
 }
 
 // openFreezerFileForAppend opens a freezer table file and seeks to the end
@@ -155,34 +186,51 @@ func truncateFreezerFile(file *os.File, size int64) error {
 // non existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newCustomTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression bool) (*freezerTable, error) {
+	codec := codecSnappy
+	if noCompression {
+		codec = codecNone
+	}
+	return newCodecTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, codec)
+}
+
+// newCodecTable opens a freezer table storing its new items with the given codec.
+// Compressed tables created with another codec keep their existing items, their
+// new items are stored in new data files. The index of a newly created table
+// carries the checksums of the items, whereas tables created before don't, as
+// told by the lack of the metadata file, until upgraded on the next append.
+func newCodecTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, codec freezerCodec) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		// Raw idx
-		idxName = fmt.Sprintf("%s.ridx", name)
-	} else {
-		// Compressed idx
-		idxName = fmt.Sprintf("%s.cidx", name)
+	layout := tableLayoutCodec(path, name, codec)
+	meta, err := openTableMeta(path, name, layout)
+	if err != nil {
+		return nil, err
 	}
-	offsets, err := openFreezerFileForAppend(filepath.Join(path, idxName))
+	offsets, err := openFreezerFileForAppend(filepath.Join(path, layout.indexName(name)))
 	if err != nil {
 		return nil, err
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         offsets,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		maxFileSize:   maxFilesize,
+		index:       offsets,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		codec:       codec,
+		layout:      layout,
+		legacy:      meta.Version == freezerTableLegacy,
+		checked:     meta.Checked,
+		entrySize:   indexEntrySize,
+		maxFileSize: maxFilesize,
+	}
+	if tab.legacy {
+		tab.entrySize = legacyIndexEntrySize
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -203,7 +251,7 @@ func newCustomTable(path string, name string, readMeter metrics.Meter, writeMete
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
 	// Create a temporary offset buffer to init files with and read indexEntry into
-	buffer := make([]byte, indexEntrySize)
+	buffer := make([]byte, t.entrySize)
 
 	// If we've just created the files, initialize the index with the 0 indexEntry
 	stat, err := t.index.Stat()
@@ -215,8 +263,8 @@ func (t *freezerTable) repair() error {
 			return err
 		}
 	}
-	// Ensure the index is a multiple of indexEntrySize bytes
-	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+	// Ensure the index is a multiple of the entry size
+	if overflow := stat.Size() % t.entrySize; overflow != 0 {
 		truncateFreezerFile(t.index, stat.Size()-overflow) // New file can't trigger this path
 	}
 	// Retrieve the file sizes and prepare for truncation
@@ -239,8 +287,9 @@ func (t *freezerTable) repair() error {
 
 	t.tailId = firstIndex.filenum
 	t.itemOffset = firstIndex.offset
+	t.repairTail()
 
-	t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
+	t.index.ReadAt(buffer, offsetsSize-t.entrySize)
 	lastIndex.unmarshalBinary(buffer)
 	t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForAppend)
 	if err != nil {
@@ -266,11 +315,11 @@ func (t *freezerTable) repair() error {
 		// Truncate the index to point within the head file
 		if contentExp > contentSize {
 			t.logger.Warn("Truncating dangling indexes", "indexed", common.StorageSize(contentExp), "stored", common.StorageSize(contentSize))
-			if err := truncateFreezerFile(t.index, offsetsSize-indexEntrySize); err != nil {
+			if err := truncateFreezerFile(t.index, offsetsSize-t.entrySize); err != nil {
 				return err
 			}
-			offsetsSize -= indexEntrySize
-			t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
+			offsetsSize -= t.entrySize
+			t.index.ReadAt(buffer, offsetsSize-t.entrySize)
 			var newLastIndex indexEntry
 			newLastIndex.unmarshalBinary(buffer)
 			// We might have slipped back into an earlier head-file here
@@ -299,7 +348,7 @@ func (t *freezerTable) repair() error {
 		return err
 	}
 	// Update the item and byte counters and return
-	t.items = uint64(t.itemOffset) + uint64(offsetsSize/indexEntrySize-1) // last indexEntry points to the end of the data file
+	t.items = uint64(t.itemOffset) + uint64(offsetsSize/t.entrySize-1) // last indexEntry points to the end of the data file
 	t.headBytes = uint32(contentSize)
 	t.headId = lastIndex.filenum
 
@@ -307,10 +356,36 @@ func (t *freezerTable) repair() error {
 	if err := t.preopen(); err != nil {
 		return err
 	}
-	t.logger.Debug("Chain freezer table opened", "items", t.items, "size", common.StorageSize(t.headBytes))
+	t.logger.Debug("Chain freezer table opened", "items", t.items, "size", common.StorageSize(t.headBytes), "codec", t.codec, "checksums", !t.legacy)
 	return nil
 }
 
+// repairTail deletes the data files below the tail file, which a crash during a
+// tail truncation might have left behind after switching the index over, along
+// with the index it was being rewritten into.
+func (t *freezerTable) repairTail() {
+	os.Remove(t.index.Name() + ".tmp")
+
+	codecs := []freezerCodec{codecNone}
+	if t.codec != codecNone {
+		codecs = []freezerCodec{codecSnappy, codecZstd}
+	}
+	// Data files are deleted in ascending order, the dangling ones are the
+	// ones right below the tail file
+	for num := t.tailId; num > 0; num-- {
+		var deleted bool
+		for _, codec := range codecs {
+			if os.Remove(filepath.Join(t.path, codec.dataName(t.name, num-1))) == nil {
+				deleted = true
+			}
+		}
+		if !deleted {
+			return
+		}
+		t.logger.Warn("Deleted dangling tail file", "number", num-1)
+	}
+}
+
 // preopen opens all files that the freezer will need. This method should be called from an init-context,
 // since it assumes that it doesn't have to bother with locking
 // The rationale for doing preopen is to not have to do it from within Retrieve, thus not needing to ever
@@ -350,12 +425,18 @@ func (t *freezerTable) truncate(items uint64) error {
 		log = t.logger.Warn // Only loud warn if we delete multiple items
 	}
 	log("Truncating freezer table", "items", existing, "limit", items)
-	if err := truncateFreezerFile(t.index, int64(items+1)*indexEntrySize); err != nil {
+
+	// The index only holds the items above the tail, never truncate into it
+	offset := uint64(atomic.LoadUint32(&t.itemOffset))
+	if items < offset {
+		return fmt.Errorf("truncating below the tail: tail %d, limit %d", offset, items)
+	}
+	if err := truncateFreezerFile(t.index, int64(items-offset+1)*t.entrySize); err != nil {
 		return err
 	}
 	// Calculate the new expected size of the data file and truncate it
-	buffer := make([]byte, indexEntrySize)
-	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+	buffer := make([]byte, t.entrySize)
+	if _, err := t.index.ReadAt(buffer, int64(items-offset)*t.entrySize); err != nil {
 		return err
 	}
 	var expected indexEntry
@@ -393,6 +474,116 @@ func (t *freezerTable) truncate(items uint64) error {
 	return nil
 }
 
+// truncateTail discards the data files holding only items below the provided
+// threshold number. Data is deleted in whole files, so the items sharing the
+// data file with the first retained item are kept too.
+func (t *freezerTable) truncateTail(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table is still accessible
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	offset := uint64(atomic.LoadUint32(&t.itemOffset))
+	if items <= offset {
+		return nil
+	}
+	// Find the data file that becomes the new tail. If all items are to be
+	// discarded, the head file is retained to keep appending to.
+	var (
+		buffer = make([]byte, t.entrySize)
+		tailId = atomic.LoadUint32(&t.headId)
+		count  = atomic.LoadUint64(&t.items) - offset
+	)
+	if items < atomic.LoadUint64(&t.items) {
+		if _, err := t.index.ReadAt(buffer, int64(items-offset+1)*t.entrySize); err != nil {
+			return err
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+		tailId = entry.filenum
+	}
+	if tailId == t.tailId {
+		return nil
+	}
+	// Search the first item stored in the new tail file. The end offset of
+	// item n is stored in index entry n+1.
+	var err error
+	first := uint64(sort.Search(int(count), func(n int) bool {
+		if _, rerr := t.index.ReadAt(buffer, int64(n+1)*t.entrySize); rerr != nil {
+			err = rerr
+			return true
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+		return entry.filenum >= tailId
+	}))
+	if err != nil {
+		return err
+	}
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	// Rewrite the index into a temporary file, starting with the entry that
+	// carries the new tail file and item offset, then atomically replace it.
+	name := t.index.Name()
+	index, err := openFreezerFileTruncated(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	tail := indexEntry{filenum: tailId, offset: uint32(offset + first)}
+	if _, err := index.Write(tail.marshallBinary()[:t.entrySize]); err != nil {
+		index.Close()
+		return err
+	}
+	stat, err := t.index.Stat()
+	if err != nil {
+		index.Close()
+		return err
+	}
+	start := int64(first+1) * t.entrySize
+	if _, err := io.Copy(index, io.NewSectionReader(t.index, start, stat.Size()-start)); err != nil {
+		index.Close()
+		return err
+	}
+	if err := index.Sync(); err != nil {
+		index.Close()
+		return err
+	}
+	if err := index.Close(); err != nil {
+		return err
+	}
+	t.index.Close()
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return err
+	}
+	if t.index, err = openFreezerFileForAppend(name); err != nil {
+		return err
+	}
+	// The index has been switched over, delete the discarded data files
+	t.logger.Info("Truncating freezer table tail", "items", offset+first, "limit", items, "tailfile", tailId)
+	for fnum := t.tailId; fnum < tailId; fnum++ {
+		if f, exist := t.files[fnum]; exist {
+			delete(t.files, fnum)
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	t.tailId = tailId
+	atomic.StoreUint32(&t.itemOffset, uint32(offset+first))
+
+	// Retrieve the new size and update the total size counter
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeGauge.Dec(int64(oldSize - newSize))
+
+	return nil
+}
+
 // Close closes all opened files.
 func (t *freezerTable) Close() error {
 	t.lock.Lock()
@@ -421,13 +612,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(t.dataFile(num))
 		if err != nil {
 			return nil, err
 		}
@@ -436,6 +621,24 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// dataFile returns the path of a data file. Compressed tables may hold data files
+// of different codecs, the existing one is returned, a new one is named after the
+// codec of the table.
+func (t *freezerTable) dataFile(num uint32) string {
+	if t.codec != codecNone {
+		for _, codec := range []freezerCodec{codecSnappy, codecZstd} {
+			if codec == t.codec {
+				continue
+			}
+			file := filepath.Join(t.path, codec.dataName(t.name, num))
+			if _, err := os.Stat(file); err == nil {
+				return file
+			}
+		}
+	}
+	return filepath.Join(t.path, t.codec.dataName(t.name, num))
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -466,9 +669,8 @@ func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 // fsync before irreversibly deleting data from the database.
 func (t *freezerTable) Append(item uint64, blob []byte) error {
 	// Encode the blob before the lock portion
-	if !t.noCompression {
-		blob = snappy.Encode(nil, blob)
-	}
+	blob = t.codec.encode(blob)
+
 	// Read lock prevents competition with truncate
 	retry, err := t.append(item, blob, false)
 	if err != nil {
@@ -503,10 +705,21 @@ func (t *freezerTable) append(item uint64, encodedBlob []byte, wlock bool) (bool
 	if atomic.LoadUint64(&t.items) != item {
 		return false, fmt.Errorf("appending unexpected item: want %d, have %d", t.items, item)
 	}
+	// Upgrade the index of legacy tables to carry the checksums of new items
+	if t.legacy {
+		if !wlock {
+			return true, nil
+		}
+		if err := t.upgradeIndex(); err != nil {
+			return false, err
+		}
+	}
 	bLen := uint32(len(encodedBlob))
 	if t.headBytes+bLen < bLen ||
-		t.headBytes+bLen > t.maxFileSize {
-		// Writing would overflow, so we need to open a new data file.
+		t.headBytes+bLen > t.maxFileSize ||
+		dataFileCodec(t.head.Name()) != t.codec {
+		// Writing would overflow or the head file was written with another codec,
+		// so we need to open a new data file.
 		// If we don't already hold the writelock, abort and let the caller
 		// invoke this method a second time.
 		if !wlock {
@@ -514,7 +727,15 @@ func (t *freezerTable) append(item uint64, encodedBlob []byte, wlock bool) (bool
 		}
 		nextID := atomic.LoadUint32(&t.headId) + 1
 		// We open the next file in truncated mode -- if this file already
-		// exists, we need to start over from scratch on it
+		// exists, we need to start over from scratch on it. Stale files of
+		// other codecs are removed, they would shadow the new one.
+		if t.codec != codecNone {
+			for _, codec := range []freezerCodec{codecSnappy, codecZstd} {
+				if codec != t.codec {
+					os.Remove(filepath.Join(t.path, codec.dataName(t.name, nextID)))
+				}
+			}
+		}
 		newHead, err := t.openFile(nextID, openFreezerFileTruncated)
 		if err != nil {
 			return false, err
@@ -536,30 +757,97 @@ func (t *freezerTable) append(item uint64, encodedBlob []byte, wlock bool) (bool
 		filenum: atomic.LoadUint32(&t.headId),
 		offset:  newOffset,
 	}
+	idx.checksum = crc32.Checksum(encodedBlob, checksumTable)
+
 	// Write indexEntry
-	t.index.Write(idx.marshallBinary())
+	t.index.Write(idx.marshallBinary()[:t.entrySize])
 
-	t.writeMeter.Mark(int64(bLen + indexEntrySize))
-	t.sizeGauge.Inc(int64(bLen + indexEntrySize))
+	t.writeMeter.Mark(int64(bLen) + t.entrySize)
+	t.sizeGauge.Inc(int64(bLen) + t.entrySize)
 
 	atomic.AddUint64(&t.items, 1)
 	return false, nil
 }
 
+// upgradeIndex rewrites the index of a legacy table into the current format, with
+// the items stored so far lacking checksums. The upgraded index is switched over
+// only after the metadata telling its format is written, an upgrade interrupted
+// in between is completed when the table is opened.
+//
+// Note, this method assumes that the write-lock is held by the caller.
+func (t *freezerTable) upgradeIndex() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	name := t.index.Name()
+	index, err := openFreezerFileTruncated(name + ".upgrade")
+	if err != nil {
+		return err
+	}
+	var (
+		reader = bufio.NewReader(io.NewSectionReader(t.index, 0, stat.Size()))
+		writer = bufio.NewWriter(index)
+		buffer = make([]byte, legacyIndexEntrySize)
+	)
+	for {
+		if _, err := io.ReadFull(reader, buffer); err == io.EOF {
+			break
+		} else if err != nil {
+			index.Close()
+			return err
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+		if _, err := writer.Write(entry.marshallBinary()); err != nil {
+			index.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		index.Close()
+		return err
+	}
+	if err := index.Sync(); err != nil {
+		index.Close()
+		return err
+	}
+	if err := index.Close(); err != nil {
+		return err
+	}
+	checked := atomic.LoadUint64(&t.items)
+	if err := writeTableMeta(t.path, t.name, t.layout, &freezerTableMeta{Version: freezerTableVersion, Checked: checked}); err != nil {
+		return err
+	}
+	t.index.Close()
+	if err := os.Rename(name+".upgrade", name); err != nil {
+		return err
+	}
+	if t.index, err = openFreezerFileForAppend(name); err != nil {
+		return err
+	}
+	t.legacy, t.checked, t.entrySize = false, checked, indexEntrySize
+
+	t.sizeGauge.Inc(stat.Size() / legacyIndexEntrySize * (indexEntrySize - legacyIndexEntrySize))
+	t.logger.Info("Upgraded freezer table index to carry checksums", "from", checked)
+	return nil
+}
+
 // getBounds returns the indexes for the item
-// returns start, end, filenumber and error
-func (t *freezerTable) getBounds(item uint64) (uint32, uint32, uint32, error) {
-	buffer := make([]byte, indexEntrySize)
+// returns start, end, filenumber and error. The index entry of the item end
+// carries its checksum.
+func (t *freezerTable) getBounds(item uint64) (uint32, uint32, indexEntry, error) {
+	buffer := make([]byte, t.entrySize)
 	var startIdx, endIdx indexEntry
 	// Read second index
-	if _, err := t.index.ReadAt(buffer, int64((item+1)*indexEntrySize)); err != nil {
-		return 0, 0, 0, err
+	if _, err := t.index.ReadAt(buffer, int64(item+1)*t.entrySize); err != nil {
+		return 0, 0, endIdx, err
 	}
 	endIdx.unmarshalBinary(buffer)
 	// Read first index (unless it's the very first item)
 	if item != 0 {
-		if _, err := t.index.ReadAt(buffer, int64(item*indexEntrySize)); err != nil {
-			return 0, 0, 0, err
+		if _, err := t.index.ReadAt(buffer, int64(item)*t.entrySize); err != nil {
+			return 0, 0, endIdx, err
 		}
 		startIdx.unmarshalBinary(buffer)
 	} else {
@@ -568,68 +856,125 @@ func (t *freezerTable) getBounds(item uint64) (uint32, uint32, uint32, error) {
 		// only support deletion by files, so that the assumption is held).
 		// This means we can use the first item metadata to carry information about
 		// the 'global' offset, for the deletion-case
-		return 0, endIdx.offset, endIdx.filenum, nil
+		return 0, endIdx.offset, endIdx, nil
 	}
 	if startIdx.filenum != endIdx.filenum {
 		// If a piece of data 'crosses' a data-file,
 		// it's actually in one piece on the second data-file.
 		// We return a zero-indexEntry for the second file as start
-		return 0, endIdx.offset, endIdx.filenum, nil
+		return 0, endIdx.offset, endIdx, nil
 	}
-	return startIdx.offset, endIdx.offset, endIdx.filenum, nil
+	return startIdx.offset, endIdx.offset, endIdx, nil
 }
 
 // Retrieve looks up the data offset of an item with the given number and retrieves
 // the raw binary blob from the data file.
 func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
-	blob, err := t.retrieve(item)
+	blob, codec, err := t.retrieve(item)
 	if err != nil {
 		return nil, err
 	}
-	if t.noCompression {
-		return blob, nil
-	}
-	return snappy.Decode(nil, blob)
+	return codec.decode(blob)
 }
 
 // retrieve looks up the data offset of an item with the given number and retrieves
-// the raw binary blob from the data file. OBS! This method does not decode
-// compressed data.
-func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
+// the raw binary blob from the data file, verifying its checksum, along with the
+// codec of the data file. OBS! This method does not decode compressed data.
+func (t *freezerTable) retrieve(item uint64) ([]byte, freezerCodec, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 	// Ensure the table and the item is accessible
 	if t.index == nil || t.head == nil {
-		return nil, errClosed
+		return nil, codecNone, errClosed
 	}
 	if atomic.LoadUint64(&t.items) <= item {
-		return nil, errOutOfBounds
+		return nil, codecNone, errOutOfBounds
 	}
 	// Ensure the item was not deleted from the tail either
-	if uint64(t.itemOffset) > item {
-		return nil, errOutOfBounds
+	offset := uint64(atomic.LoadUint32(&t.itemOffset))
+	if offset > item {
+		return nil, codecNone, errOutOfBounds
 	}
-	startOffset, endOffset, filenum, err := t.getBounds(item - uint64(t.itemOffset))
+	startOffset, endOffset, entry, err := t.getBounds(item - offset)
 	if err != nil {
-		return nil, err
+		return nil, codecNone, err
 	}
-	dataFile, exist := t.files[filenum]
+	dataFile, exist := t.files[entry.filenum]
 	if !exist {
-		return nil, fmt.Errorf("missing data file %d", filenum)
+		return nil, codecNone, fmt.Errorf("missing data file %d", entry.filenum)
 	}
-	// Retrieve the data itself, decompress and return
+	// Retrieve the data itself, verify and return
 	blob := make([]byte, endOffset-startOffset)
 	if _, err := dataFile.ReadAt(blob, int64(startOffset)); err != nil {
-		return nil, err
+		return nil, codecNone, err
+	}
+	t.readMeter.Mark(int64(len(blob)) + 2*t.entrySize)
+
+	if !t.legacy && item >= t.checked {
+		if sum := crc32.Checksum(blob, checksumTable); sum != entry.checksum {
+			return nil, codecNone, fmt.Errorf("%w: item %d, have %08x, want %08x", errChecksumMismatch, item, sum, entry.checksum)
+		}
+	}
+	return blob, dataFileCodec(dataFile.Name()), nil
+}
+
+// rewrite replaces the content of an existing item, repairing its data after
+// corruption. The re-encoded item must have the exact size of the stored one,
+// as the items following it can't be moved.
+func (t *freezerTable) rewrite(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table and the item is accessible
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	offset := uint64(atomic.LoadUint32(&t.itemOffset))
+	if atomic.LoadUint64(&t.items) <= item || offset > item {
+		return errOutOfBounds
+	}
+	startOffset, endOffset, entry, err := t.getBounds(item - offset)
+	if err != nil {
+		return err
+	}
+	dataFile, exist := t.files[entry.filenum]
+	if !exist {
+		return fmt.Errorf("missing data file %d", entry.filenum)
+	}
+	encoded := dataFileCodec(dataFile.Name()).encode(blob)
+	if uint32(len(encoded)) != endOffset-startOffset {
+		return fmt.Errorf("item %d size mismatch: have %d, want %d", item, len(encoded), endOffset-startOffset)
+	}
+	// Non-head data files are opened read only, write through a separate handle
+	file, err := os.OpenFile(dataFile.Name(), os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(encoded, int64(startOffset)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if t.legacy {
+		return nil
+	}
+	entry.checksum = crc32.Checksum(encoded, checksumTable)
+	if _, err := t.index.WriteAt(entry.marshallBinary()[legacyIndexEntrySize:], int64(item-offset+1)*t.entrySize+legacyIndexEntrySize); err != nil {
+		return err
 	}
-	t.readMeter.Mark(int64(len(blob) + 2*indexEntrySize))
-	return blob, nil
+	return t.index.Sync()
 }
 
 // has returns an indicator whether the specified number data
 // exists in the freezer table.
 func (t *freezerTable) has(number uint64) bool {
-	return atomic.LoadUint64(&t.items) > number
+	return atomic.LoadUint64(&t.items) > number && uint64(atomic.LoadUint32(&t.itemOffset)) <= number
 }
 
 // size returns the total data size in the freezer table.
@@ -663,21 +1008,21 @@ func (t *freezerTable) Sync() error {
 // DumpIndex is a debug print utility function, mainly for testing. It can also
 // be used to analyse a live freezer table index.
 func (t *freezerTable) DumpIndex(start, stop int64) {
-	buf := make([]byte, indexEntrySize)
+	buf := make([]byte, t.entrySize)
 
-	fmt.Printf("| number | fileno | offset |\n")
-	fmt.Printf("|--------|--------|--------|\n")
+	fmt.Printf("| number | fileno | offset | checksum |\n")
+	fmt.Printf("|--------|--------|--------|----------|\n")
 
 	for i := uint64(start); ; i++ {
-		if _, err := t.index.ReadAt(buf, int64(i*indexEntrySize)); err != nil {
+		if _, err := t.index.ReadAt(buf, int64(i)*t.entrySize); err != nil {
 			break
 		}
 		var entry indexEntry
 		entry.unmarshalBinary(buf)
-		fmt.Printf("|  %03d   |  %03d   |  %03d   | \n", i, entry.filenum, entry.offset)
+		fmt.Printf("|  %03d   |  %03d   |  %03d   | %08x | \n", i, entry.filenum, entry.offset, entry.checksum)
 		if stop > 0 && i >= uint64(stop) {
 			break
 		}
 	}
-	fmt.Printf("|--------------------------|\n")
+	fmt.Printf("|-------------------------------------|\n")
 }
@@ -18,7 +18,9 @@ package rawdb
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -403,6 +405,150 @@ func TestFreezerTruncate(t *testing.T) {
 
 }
 
+// TestFreezerTruncateTail tests discarding items from the tail of the table,
+// which happens in whole data files.
+// Tests that the data files left behind by a crash during a tail truncation are
+// deleted when reopening the table.
+func TestFreezerTruncateTailCrash(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("truncationtailcrash-%d", rand.Uint64())
+	dataFile := func(num int) string {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("%s.%04d.rdat", fname, num))
+	}
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write 15 bytes 30 times, 3 items per file
+	for x := 0; x < 30; x++ {
+		f.Append(uint64(x), getChunk(15, x))
+	}
+	// Keep the data files to be discarded, and restore them after the truncation
+	// as if it crashed right after switching the index over
+	dangling := make([][]byte, 3)
+	for i := range dangling {
+		if dangling[i], err = ioutil.ReadFile(dataFile(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.truncateTail(10); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	for i, blob := range dangling {
+		if err := ioutil.WriteFile(dataFile(i), blob, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx.tmp", fname))
+	if err := ioutil.WriteFile(tmp, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Reopen, the dangling files must be gone and the table intact
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := range dangling {
+		if _, err := os.Stat(dataFile(i)); !os.IsNotExist(err) {
+			t.Errorf("dangling data file %d not deleted: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("dangling index not deleted: %v", err)
+	}
+	if f.items != 30 || f.itemOffset != 9 || f.tailId != 3 {
+		t.Fatalf("expected %d items with tail %d in file %d, got %d with tail %d in file %d", 30, 9, 3, f.items, f.itemOffset, f.tailId)
+	}
+	for y := 9; y < 30; y++ {
+		got, err := f.Retrieve(uint64(y))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := getChunk(15, y); !bytes.Equal(got, exp) {
+			t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+		}
+	}
+}
+
+func TestFreezerTruncateTail(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("truncationtail-%d", rand.Uint64())
+
+	{ // Fill table
+		f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Write 15 bytes 30 times, 3 items per file
+		for x := 0; x < 30; x++ {
+			data := getChunk(15, x)
+			f.Append(uint64(x), data)
+		}
+		// Item 10 resides in the fourth file, together with items 9 and 11
+		if err := f.truncateTail(10); err != nil {
+			t.Fatal(err)
+		}
+		if f.itemOffset != 9 || f.tailId != 3 {
+			t.Fatalf("expected tail %d in file %d, got %d in file %d", 9, 3, f.itemOffset, f.tailId)
+		}
+		if _, err := os.Stat(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0002.rdat", fname))); !os.IsNotExist(err) {
+			t.Fatalf("expected discarded data file to be deleted: %v", err)
+		}
+		f.Close()
+	}
+	// Reopen, check the tail survived and the table is still usable
+	{
+		f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if f.items != 30 || f.itemOffset != 9 {
+			t.Fatalf("expected %d items with tail %d, got %d with tail %d", 30, 9, f.items, f.itemOffset)
+		}
+		if _, err := f.Retrieve(8); err != errOutOfBounds {
+			t.Fatalf("expected out of bounds for discarded item, got %v", err)
+		}
+		if f.has(8) {
+			t.Fatal("discarded item reported as present")
+		}
+		for y := 9; y < 30; y++ {
+			got, err := f.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := getChunk(15, y); !bytes.Equal(got, exp) {
+				t.Fatalf("test %d, got \n%x != \n%x", y, got, exp)
+			}
+		}
+		// Truncating the head must respect the tail
+		if err := f.truncate(20); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Append(20, getChunk(15, 0xFF)); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := f.Retrieve(20); err != nil || !bytes.Equal(got, getChunk(15, 0xFF)) {
+			t.Fatalf("unexpected item after truncation: %x, %v", got, err)
+		}
+		// Discarding everything retains the head file only
+		if err := f.truncateTail(100); err != nil {
+			t.Fatal(err)
+		}
+		if f.tailId != f.headId || f.itemOffset != 18 {
+			t.Fatalf("expected tail %d in head file, got %d in file %d", 18, f.itemOffset, f.tailId)
+		}
+		if _, err := f.Retrieve(20); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 // TestFreezerRepairFirstFile tests a head file with the very first item only half-written.
 // That will rewind the index, and _should_ truncate the head file
 func TestFreezerRepairFirstFile(t *testing.T) {
@@ -628,6 +774,264 @@ func TestOffset(t *testing.T) {
 	checkPresent(1000000)
 }
 
+// TestFreezerChecksum tests that corrupted items are detected by their checksums
+// and can be rewritten with their original content.
+func TestFreezerChecksum(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("checksum-%d", rand.Uint64())
+
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for x := 0; x < 10; x++ {
+		f.Append(uint64(x), getChunk(20, x))
+	}
+	// Flip a byte of item 4, which is the first one of the third data file
+	data, err := os.OpenFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0002.rdat", fname)), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.WriteAt([]byte{0xff}, 5)
+	data.Close()
+
+	if _, err := f.Retrieve(4); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("corrupted item not detected: %v", err)
+	}
+	for _, item := range []uint64{3, 5} {
+		if got, err := f.Retrieve(item); err != nil || !bytes.Equal(got, getChunk(20, int(item))) {
+			t.Fatalf("item %d mismatch: %x, %v", item, got, err)
+		}
+	}
+	// Items of a different size can't be rewritten, the original content can
+	if err := f.rewrite(4, getChunk(21, 4)); err == nil {
+		t.Fatal("rewrote item with different size")
+	}
+	if err := f.rewrite(4, getChunk(20, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Retrieve(4); err != nil || !bytes.Equal(got, getChunk(20, 4)) {
+		t.Fatalf("rewritten item mismatch: %x, %v", got, err)
+	}
+}
+
+// TestFreezerLegacyTable tests that tables created without checksums are upgraded
+// on the next append, checking the items appended from then on.
+func TestFreezerLegacyTable(t *testing.T) {
+	t.Parallel()
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("legacy-%d", rand.Uint64())
+
+	// Create a legacy table with two items of 10 bytes in one data file
+	index := make([]byte, 0, 3*legacyIndexEntrySize)
+	for _, entry := range []indexEntry{{}, {offset: 10}, {offset: 20}} {
+		index = append(index, entry.marshallBinary()[:legacyIndexEntrySize]...)
+	}
+	if err := os.WriteFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx", fname)), index, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0000.rdat", fname)), append(getChunk(10, 1), getChunk(10, 2)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.legacy || f.items != 2 {
+		t.Fatalf("legacy table mismatch: legacy %v, items %d", f.legacy, f.items)
+	}
+	if err := f.Append(2, getChunk(10, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if f.legacy || f.checked != 2 {
+		t.Fatalf("table not upgraded: legacy %v, checked %d", f.legacy, f.checked)
+	}
+	f.Append(3, getChunk(10, 4))
+	f.Close()
+
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.legacy || f.checked != 2 || f.items != 4 {
+		t.Fatalf("upgraded table mismatch: legacy %v, checked %d, items %d", f.legacy, f.checked, f.items)
+	}
+	for item := 0; item < 4; item++ {
+		if got, err := f.Retrieve(uint64(item)); err != nil || !bytes.Equal(got, getChunk(10, item+1)) {
+			t.Fatalf("item %d mismatch: %x, %v", item, got, err)
+		}
+	}
+	f.Close()
+
+	// Corrupt an item from before and one from after the upgrade, only the
+	// latter can be detected
+	data, err := os.OpenFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0000.rdat", fname)), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.WriteAt([]byte{0xff}, 0)
+	data.WriteAt([]byte{0xff}, 20)
+	data.Close()
+
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Retrieve(0); err != nil {
+		t.Fatalf("unchecked item failed: %v", err)
+	}
+	if _, err := f.Retrieve(2); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("corrupted item: have %v, want %v", err, errChecksumMismatch)
+	}
+}
+
+// TestFreezerLegacyTableUpgradeInterrupted tests that an index upgrade interrupted
+// before or after writing the metadata is rolled back or completed on open.
+func TestFreezerLegacyTableUpgradeInterrupted(t *testing.T) {
+	t.Parallel()
+	for _, written := range []bool{false, true} {
+		fname := fmt.Sprintf("legacy-upgrade-%d", rand.Uint64())
+
+		index := make([]byte, 0, 3*legacyIndexEntrySize)
+		upgraded := make([]byte, 0, 3*indexEntrySize)
+		for _, entry := range []indexEntry{{}, {offset: 10}, {offset: 20}} {
+			index = append(index, entry.marshallBinary()[:legacyIndexEntrySize]...)
+			upgraded = append(upgraded, entry.marshallBinary()...)
+		}
+		indexPath := filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx", fname))
+		if err := os.WriteFile(indexPath, index, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(indexPath+".upgrade", upgraded, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0000.rdat", fname)), append(getChunk(10, 1), getChunk(10, 2)...), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if written {
+			if err := writeTableMeta(os.TempDir(), fname, codecNone, &freezerTableMeta{Version: freezerTableVersion, Checked: 2}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := newCustomTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.legacy == written || f.items != 2 {
+			t.Fatalf("metadata written %v: table mismatch: legacy %v, items %d", written, f.legacy, f.items)
+		}
+		for item := 0; item < 2; item++ {
+			if got, err := f.Retrieve(uint64(item)); err != nil || !bytes.Equal(got, getChunk(10, item+1)) {
+				t.Fatalf("metadata written %v: item %d mismatch: %x, %v", written, item, got, err)
+			}
+		}
+		f.Close()
+		if _, err := os.Stat(indexPath + ".upgrade"); !os.IsNotExist(err) {
+			t.Fatalf("metadata written %v: upgraded index left behind: %v", written, err)
+		}
+	}
+}
+
+// TestFreezerTableMixedCodecs tests that compressed tables switching codecs keep
+// their stored items, storing the new ones in new data files of the new codec.
+func TestFreezerTableMixedCodecs(t *testing.T) {
+	t.Parallel()
+	fname := fmt.Sprintf("mixed-%d", rand.Uint64())
+
+	open := func(codec freezerCodec) *freezerTable {
+		t.Helper()
+		f, err := newCodecTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 100, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	check := func(f *freezerTable, tail, items uint64) {
+		t.Helper()
+		if uint64(f.itemOffset) != tail || f.items != items {
+			t.Fatalf("table bounds mismatch: have %d-%d, want %d-%d", f.itemOffset, f.items, tail, items)
+		}
+		for x := tail; x < items; x++ {
+			if got, err := f.Retrieve(x); err != nil || !bytes.Equal(got, getChunk(40, int(x))) {
+				t.Fatalf("item %d mismatch: %x, %v", x, got, err)
+			}
+		}
+	}
+	f := open(codecSnappy)
+	for x := 0; x < 10; x++ {
+		f.Append(uint64(x), getChunk(40, x))
+	}
+	snappyHead := f.headId
+	f.Close()
+
+	// Switch to zstd, the items stored so far are kept
+	if codec, err := resolveTableCodec(os.TempDir(), fname, ""); err != nil || codec != codecSnappy {
+		t.Fatalf("codec mismatch: have %v, %v", codec, err)
+	}
+	if codec, err := resolveTableCodec(os.TempDir(), fname, FreezerCompressionZstd); err != nil || codec != codecZstd {
+		t.Fatalf("codec mismatch: have %v, %v", codec, err)
+	}
+	f = open(codecZstd)
+	check(f, 0, 10)
+	for x := 10; x < 20; x++ {
+		f.Append(uint64(x), getChunk(40, x))
+	}
+	check(f, 0, 20)
+	if f.layout != codecSnappy {
+		t.Fatalf("layout mismatch: have %v, want %v", f.layout, codecSnappy)
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), codecZstd.dataName(fname, snappyHead+1))); err != nil {
+		t.Fatalf("missing zstd data file: %v", err)
+	}
+	// Drop the snappy files from the tail, switch back and truncate the head
+	if err := f.truncateTail(10); err != nil {
+		t.Fatal(err)
+	}
+	tail := uint64(f.itemOffset)
+	f.Close()
+
+	f = open(codecSnappy)
+	defer f.Close()
+	check(f, tail, 20)
+	for x := 20; x < 25; x++ {
+		f.Append(uint64(x), getChunk(40, x))
+	}
+	if err := f.truncate(22); err != nil {
+		t.Fatal(err)
+	}
+	f.Append(22, getChunk(40, 22))
+	check(f, tail, 23)
+}
+
+// TestFreezerCompressionSwitch tests that a freezer opens with a different
+// compression than its tables, compressing the new items with it.
+func TestFreezerCompressionSwitch(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("freezer-compression-%d", rand.Uint64()))
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, "", false, FreezerCompressionSnappy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f, err = newFreezer(dir, "", false, FreezerCompressionZstd)
+	if err != nil {
+		t.Fatalf("failed to open freezer with new compression: %v", err)
+	}
+	defer f.Close()
+
+	for name := range freezerCompressibleTables {
+		if table := f.tables[name]; table.codec != codecZstd || table.layout != codecSnappy {
+			t.Errorf("table %s codec mismatch: have %v/%v, want %v/%v", name, table.codec, table.layout, codecZstd, codecSnappy)
+		}
+	}
+}
+
 // TODO (?)
 // - test that if we remove several head-files, aswell as data last data-file,
 //   the index is truncated accordingly
@@ -0,0 +1,219 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// AncientRepair is a corrupted ancient block body or receipts item, waiting to
+// be fetched from the network again.
+type AncientRepair struct {
+	Number   uint64
+	Receipts bool // Whether the receipts are corrupted, the body otherwise
+}
+
+// WriteAncientRepair schedules the repair of a corrupted ancient item.
+func WriteAncientRepair(db ethdb.KeyValueWriter, repair AncientRepair) {
+	if err := db.Put(ancientRepairKey(repair.Number, repair.Receipts), nil); err != nil {
+		log.Crit("Failed to store ancient repair", "err", err)
+	}
+}
+
+// DeleteAncientRepair removes the scheduled repair of an ancient item.
+func DeleteAncientRepair(db ethdb.KeyValueWriter, repair AncientRepair) {
+	if err := db.Delete(ancientRepairKey(repair.Number, repair.Receipts)); err != nil {
+		log.Crit("Failed to delete ancient repair", "err", err)
+	}
+}
+
+// ReadAncientRepairs retrieves the scheduled repairs of ancient items, ordered
+// by block number.
+func ReadAncientRepairs(db ethdb.Iteratee) []AncientRepair {
+	it := db.NewIterator(ancientRepairPrefix, nil)
+	defer it.Release()
+
+	var repairs []AncientRepair
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(ancientRepairPrefix)+9 {
+			continue
+		}
+		repairs = append(repairs, AncientRepair{
+			Number:   binary.BigEndian.Uint64(key[len(ancientRepairPrefix):]),
+			Receipts: key[len(key)-1] == 1,
+		})
+	}
+	return repairs
+}
+
+// RepairAncientBody overwrites the corrupted ancient body of a block, dropping
+// its scheduled repair. The body must be verified against the header first.
+func RepairAncientBody(db ethdb.Database, number uint64, body *types.Body) error {
+	blob, err := rlp.EncodeToBytes(body)
+	if err != nil {
+		return err
+	}
+	if err := db.RepairAncient(freezerBodiesTable, number, blob); err != nil {
+		return err
+	}
+	DeleteAncientRepair(db, AncientRepair{Number: number})
+	return nil
+}
+
+// RepairAncientReceipts overwrites the corrupted ancient receipts of a block,
+// dropping its scheduled repair. The receipts must be verified against the
+// header first.
+func RepairAncientReceipts(db ethdb.Database, number uint64, receipts types.Receipts) error {
+	storageReceipts := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		storageReceipts[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	blob, err := rlp.EncodeToBytes(storageReceipts)
+	if err != nil {
+		return err
+	}
+	if err := db.RepairAncient(freezerReceiptTable, number, blob); err != nil {
+		return err
+	}
+	DeleteAncientRepair(db, AncientRepair{Number: number, Receipts: true})
+	return nil
+}
+
+// VerifyAncients checks every item of the ancient store. Beyond the checksums
+// verified on retrieval, the headers are checked against the canonical hashes,
+// and the bodies and receipts against the roots in the headers. It returns the
+// number of corrupted items, optionally scheduling the repair of the bodies and
+// receipts of verified headers.
+func VerifyAncients(db ethdb.Database, hasher types.TrieHasher, repair bool) (int, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		corrupted int
+		start     = time.Now()
+		logged    = time.Now()
+	)
+	for number := db.AncientOffSet(); number < frozen; number++ {
+		report := func(kind string, err error) {
+			log.Error("Corrupted ancient item", "table", kind, "number", number, "err", err)
+			corrupted++
+		}
+		// Verify the canonical hash and the header
+		var hash common.Hash
+		blob, err := db.Ancient(freezerHashTable, number)
+		if err == nil && len(blob) != common.HashLength {
+			err = fmt.Errorf("invalid hash length %d", len(blob))
+		}
+		if err != nil {
+			report(freezerHashTable, err)
+		} else {
+			hash = common.BytesToHash(blob)
+		}
+		header := new(types.Header)
+		if blob, err = db.Ancient(freezerHeaderTable, number); err == nil {
+			err = rlp.DecodeBytes(blob, header)
+		}
+		if err == nil && hash != (common.Hash{}) && header.Hash() != hash {
+			err = fmt.Errorf("hash mismatch: have %x, want %x", header.Hash(), hash)
+		}
+		if err != nil {
+			report(freezerHeaderTable, err)
+			header = nil
+		}
+		// Verify the body and the receipts against the header
+		body := new(types.Body)
+		if blob, err = db.Ancient(freezerBodiesTable, number); err == nil {
+			err = rlp.DecodeBytes(blob, body)
+		}
+		if err == nil && header != nil {
+			if txHash := types.DeriveSha(types.Transactions(body.Transactions), hasher); txHash != header.TxHash {
+				err = fmt.Errorf("transaction root mismatch: have %x, want %x", txHash, header.TxHash)
+			} else if uncleHash := types.CalcUncleHash(body.Uncles); uncleHash != header.UncleHash {
+				err = fmt.Errorf("uncle hash mismatch: have %x, want %x", uncleHash, header.UncleHash)
+			}
+		}
+		if err != nil {
+			report(freezerBodiesTable, err)
+			if repair && header != nil {
+				WriteAncientRepair(db, AncientRepair{Number: number})
+			}
+			body = nil
+		}
+		var storageReceipts []*types.ReceiptForStorage
+		if blob, err = db.Ancient(freezerReceiptTable, number); err == nil {
+			err = rlp.DecodeBytes(blob, &storageReceipts)
+		}
+		if err == nil && header != nil && body != nil {
+			err = verifyReceipts(header, body, storageReceipts, hasher)
+		}
+		if err != nil {
+			report(freezerReceiptTable, err)
+			if repair && header != nil {
+				WriteAncientRepair(db, AncientRepair{Number: number, Receipts: true})
+			}
+		}
+		if blob, err = db.Ancient(freezerDifficultyTable, number); err == nil {
+			err = rlp.DecodeBytes(blob, new(big.Int))
+		}
+		if err != nil {
+			report(freezerDifficultyTable, err)
+		}
+		// Optional tables are only checked for retrievability
+		for _, kind := range []string{freezerDiffLayerTable, freezerStateHistoryTable} {
+			if has, _ := db.HasAncient(kind, number); has {
+				if _, err := db.Ancient(kind, number); err != nil {
+					report(kind, err)
+				}
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying ancient store", "number", number, "frozen", frozen, "corrupted", corrupted, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Verified ancient store", "tail", db.AncientOffSet(), "frozen", frozen, "corrupted", corrupted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return corrupted, nil
+}
+
+// verifyReceipts checks the stored receipts of a block against its header.
+func verifyReceipts(header *types.Header, body *types.Body, storageReceipts []*types.ReceiptForStorage, hasher types.TrieHasher) error {
+	if len(storageReceipts) != len(body.Transactions) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(storageReceipts), len(body.Transactions))
+	}
+	// The storage format lacks the fields needed for the consensus encoding
+	receipts := make(types.Receipts, len(storageReceipts))
+	for i, receipt := range storageReceipts {
+		receipts[i] = (*types.Receipt)(receipt)
+		receipts[i].Type = body.Transactions[i].Type()
+		receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+	}
+	if receiptHash := types.DeriveSha(receipts, hasher); receiptHash != header.ReceiptHash {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", receiptHash, header.ReceiptHash)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// verifyHasher is a types.TrieHasher hashing the concatenated items, as the
+// trie can't be used from within this package.
+type verifyHasher struct {
+	data []byte
+}
+
+func (h *verifyHasher) Reset()                   { h.data = nil }
+func (h *verifyHasher) Update(key, value []byte) { h.data = append(append(h.data, key...), value...) }
+func (h *verifyHasher) Hash() common.Hash {
+	if len(h.data) == 0 {
+		return types.EmptyRootHash
+	}
+	return crypto.Keccak256Hash(h.data)
+}
+
+// Tests that corrupted ancient bodies are detected, scheduled for repair and
+// repaired with their original content.
+func TestVerifyAncients(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false, true, false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		hasher = new(verifyHasher)
+		blocks []*types.Block
+	)
+	for i := 0; i < 3; i++ {
+		tx := types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{}}
+		block := types.NewBlock(&types.Header{Number: big.NewInt(int64(i))}, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, hasher)
+		WriteAncientBlock(db, block, types.Receipts{receipt}, big.NewInt(int64(i)))
+		blocks = append(blocks, block)
+	}
+	if corrupted, err := VerifyAncients(db, hasher, true); err != nil || corrupted != 0 {
+		t.Fatalf("intact ancients reported corrupted: %d, %v", corrupted, err)
+	}
+	// Flip the last byte of the bodies, corrupting the body of the last block
+	path := filepath.Join(frdir, codecSnappy.dataName(freezerBodiesTable, 0))
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if corrupted, err := VerifyAncients(db, hasher, true); err != nil || corrupted != 1 {
+		t.Fatalf("corruption mismatch: have %d, %v, want 1", corrupted, err)
+	}
+	repairs := ReadAncientRepairs(db)
+	if len(repairs) != 1 || repairs[0] != (AncientRepair{Number: 2}) {
+		t.Fatalf("scheduled repairs mismatch: %v", repairs)
+	}
+	if err := RepairAncientBody(db, 2, blocks[2].Body()); err != nil {
+		t.Fatalf("failed to repair body: %v", err)
+	}
+	if corrupted, err := VerifyAncients(db, hasher, true); err != nil || corrupted != 0 {
+		t.Fatalf("repaired ancients reported corrupted: %d, %v", corrupted, err)
+	}
+	if repairs := ReadAncientRepairs(db); len(repairs) != 0 {
+		t.Fatalf("repair not cleared: %v", repairs)
+	}
+}
@@ -66,6 +66,15 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// stateIndexTailKey tracks the oldest block whose state can be served from the state index.
+	stateIndexTailKey = []byte("StateIndexTail")
+
+	// traceIndexTailKey tracks the oldest block whose call traces have been indexed by address.
+	traceIndexTailKey = []byte("TraceIndexTail")
+
+	// traceIndexHeadKey tracks the newest block whose call traces have been indexed by address.
+	traceIndexHeadKey = []byte("TraceIndexHead")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -99,11 +108,27 @@ var (
 	// difflayer database
 	diffLayerPrefix = []byte("d") // diffLayerPrefix + hash  -> diffLayer
 
+	trieNodeRefsPrefix     = []byte("R")          // trieNodeRefsPrefix + hash -> trie node reference count
+	retainedTrieRootPrefix = []byte("trie-root-") // retainedTrieRootPrefix + num (uint64 big endian) + hash -> nil
+
+	stateHistoryPrefix      = []byte("sh") // stateHistoryPrefix + num (uint64 big endian) + hash -> reverse state diff
+	stateIndexAccountPrefix = []byte("sA") // stateIndexAccountPrefix + account hash + num (uint64 big endian) -> account before the block
+	stateIndexStoragePrefix = []byte("sS") // stateIndexStoragePrefix + account hash + storage hash + num (uint64 big endian) -> slot before the block
+	stateIndexWipePrefix    = []byte("sW") // stateIndexWipePrefix + account hash + num (uint64 big endian) -> nil, storage wiped by the block
+
+	traceIndexPrefix = []byte("tA") // traceIndexPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> call directions
+
+	logIndexAddressPrefix = []byte("gA") // logIndexAddressPrefix + address + section (uint64 big endian) + hash -> block offsets
+	logIndexTopicPrefix   = []byte("gT") // logIndexTopicPrefix + topic + section (uint64 big endian) + hash -> block offsets
+
+	ancientRepairPrefix = []byte("ancient-repair-") // ancientRepairPrefix + num (uint64 big endian) + kind -> nil, corrupted ancient item
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix       = []byte("iL") // LogIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -124,16 +149,38 @@ const (
 
 	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
 	freezerDifficultyTable = "diffs"
+
+	// freezerDiffLayerTable indicates the name of the freezer diff layer table.
+	freezerDiffLayerTable = "difflayers"
+
+	// freezerStateHistoryTable indicates the name of the freezer state history table.
+	freezerStateHistoryTable = "statehistory"
 )
 
 // FreezerNoSnappy configures whether compression is disabled for the ancient-tables.
 // Hashes and difficulties don't compress well.
 var FreezerNoSnappy = map[string]bool{
-	freezerHeaderTable:     false,
-	freezerHashTable:       true,
-	freezerBodiesTable:     false,
-	freezerReceiptTable:    false,
-	freezerDifficultyTable: true,
+	freezerHeaderTable:       false,
+	freezerHashTable:         true,
+	freezerBodiesTable:       false,
+	freezerReceiptTable:      false,
+	freezerDifficultyTable:   true,
+	freezerDiffLayerTable:    false,
+	freezerStateHistoryTable: false,
+}
+
+// freezerCompressibleTables are the ancient-tables whose compression can be
+// configured, the others keep using the default one.
+var freezerCompressibleTables = map[string]struct{}{
+	freezerBodiesTable:  {},
+	freezerReceiptTable: {},
+}
+
+// freezerOptionalTables are the ancient-tables which may lag behind the others,
+// as their data is not available for every frozen block.
+var freezerOptionalTables = map[string]struct{}{
+	freezerDiffLayerTable:    {},
+	freezerStateHistoryTable: {},
 }
 
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
@@ -186,11 +233,60 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// stateHistoryKey = stateHistoryPrefix + num (uint64 big endian) + hash
+func stateHistoryKey(number uint64, hash common.Hash) []byte {
+	return append(append(stateHistoryPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// stateIndexAccountKey = stateIndexAccountPrefix + account hash + num (uint64 big endian)
+func stateIndexAccountKey(account common.Hash, number uint64) []byte {
+	return append(append(stateIndexAccountPrefix, account.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// stateIndexStorageKey = stateIndexStoragePrefix + account hash + storage hash + num (uint64 big endian)
+func stateIndexStorageKey(account common.Hash, slot common.Hash, number uint64) []byte {
+	return append(append(append(stateIndexStoragePrefix, account.Bytes()...), slot.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// stateIndexWipeKey = stateIndexWipePrefix + account hash + num (uint64 big endian)
+func stateIndexWipeKey(account common.Hash, number uint64) []byte {
+	return append(append(stateIndexWipePrefix, account.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// traceIndexKey = traceIndexPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func traceIndexKey(address common.Address, number uint64, txIndex uint32) []byte {
+	key := make([]byte, len(traceIndexPrefix)+common.AddressLength+12)
+	copy(key, traceIndexPrefix)
+	copy(key[len(traceIndexPrefix):], address.Bytes())
+	binary.BigEndian.PutUint64(key[len(traceIndexPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(traceIndexPrefix)+common.AddressLength+8:], txIndex)
+	return key
+}
+
+// logIndexKey = prefix + address or topic + section (uint64 big endian) + hash
+func logIndexKey(prefix []byte, item []byte, section uint64, hash common.Hash) []byte {
+	key := make([]byte, len(prefix)+len(item)+8+common.HashLength)
+	copy(key, prefix)
+	copy(key[len(prefix):], item)
+	binary.BigEndian.PutUint64(key[len(prefix)+len(item):], section)
+	copy(key[len(prefix)+len(item)+8:], hash.Bytes())
+	return key
+}
+
 // diffLayerKey = diffLayerKeyPrefix + hash
 func diffLayerKey(hash common.Hash) []byte {
 	return append(append(diffLayerPrefix, hash.Bytes()...))
 }
 
+// ancientRepairKey = ancientRepairPrefix + num (uint64 big endian) + kind
+func ancientRepairKey(number uint64, receipts bool) []byte {
+	kind := byte(0)
+	if receipts {
+		kind = 1
+	}
+	return append(append(ancientRepairPrefix, encodeBlockNumber(number)...), kind)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -240,6 +336,16 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// trieNodeRefsKey = trieNodeRefsPrefix + hash
+func trieNodeRefsKey(hash common.Hash) []byte {
+	return append(trieNodeRefsPrefix, hash.Bytes()...)
+}
+
+// retainedTrieRootKey = retainedTrieRootPrefix + num (uint64 big endian) + hash
+func retainedTrieRootKey(number uint64, hash common.Hash) []byte {
+	return append(append(retainedTrieRootPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
@@ -96,6 +96,18 @@ func (t *table) TruncateAncients(items uint64) error {
 	return t.db.TruncateAncients(items)
 }
 
+// TruncateTail is a noop passthrough that just forwards the request to the underlying
+// database.
+func (t *table) TruncateTail(items uint64) error {
+	return t.db.TruncateTail(items)
+}
+
+// RepairAncient is a noop passthrough that just forwards the request to the underlying
+// database.
+func (t *table) RepairAncient(kind string, number uint64, item []byte) error {
+	return t.db.RepairAncient(kind, number, item)
+}
+
 // Sync is a noop passthrough that just forwards the request to the underlying
 // database.
 func (t *table) Sync() error {
@@ -177,6 +189,10 @@ func (t *table) SetDiffStore(diff ethdb.KeyValueStore) {
 	panic("not implement")
 }
 
+func (t *table) FreezeDiffLayers() {
+	panic("not implement")
+}
+
 // tableBatch is a wrapper around a database batch that prefixes each key access
 // with a pre-configured string.
 type tableBatch struct {
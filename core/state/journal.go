@@ -113,6 +113,10 @@ type (
 		account       *common.Address
 		key, prevalue common.Hash
 	}
+	fakeStorageChange struct {
+		account       *common.Address
+		key, prevalue common.Hash
+	}
 	codeChange struct {
 		account            *common.Address
 		prevcode, prevhash []byte
@@ -214,6 +218,14 @@ func (ch storageChange) dirtied() *common.Address {
 	return ch.account
 }
 
+func (ch fakeStorageChange) revert(s *StateDB) {
+	s.getStateObject(*ch.account).fakeStorage[ch.key] = ch.prevalue
+}
+
+func (ch fakeStorageChange) dirtied() *common.Address {
+	return ch.account
+}
+
 func (ch refundChange) revert(s *StateDB) {
 	s.refund = ch.prev
 }
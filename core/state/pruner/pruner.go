@@ -146,6 +146,13 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 	// that the false-positive is low enough(~0.05%). The probablity of the
 	// dangling node is the state root is super low. So the dangling nodes in
 	// theory will never ever be visited again.
+	//
+	// The reference counts of the online state pruning are dropped first, since
+	// they can't account for the deleted nodes. All the remaining nodes are left
+	// untracked by the online pruning afterwards.
+	if err := rawdb.DeleteTrieNodeRefsAll(maindb); err != nil {
+		return err
+	}
 	var (
 		count  int
 		size   common.StorageSize
@@ -294,8 +301,10 @@ func (p *BlockPruner) backUpOldDb(name string, cache, handles int, namespace str
 	}
 	log.Info("the oldOffSet is ", "oldOffSet", oldOffSet)
 
-	// Get the start BlockNumber for pruning.
-	startBlockNumber := oldOffSet + itemsOfAncient - p.BlockAmountReserved
+	// Get the start BlockNumber for pruning. The tail of the old ancientDB may
+	// have been truncated online already, so count the items from its tail.
+	tail := chainDb.AncientOffSet()
+	startBlockNumber := tail + itemsOfAncient - p.BlockAmountReserved
 	log.Info("new offset/new startBlockNumber is ", "new offset", startBlockNumber)
 
 	// Create new ancientdb backup and record the new and last version of offset in kvDB as well.
@@ -326,7 +335,7 @@ func (p *BlockPruner) backUpOldDb(name string, cache, handles int, namespace str
 
 	start := time.Now()
 	// All ancient data after and including startBlockNumber should write into new ancientDB ancient_back.
-	for blockNumber := startBlockNumber; blockNumber < itemsOfAncient+oldOffSet; blockNumber++ {
+	for blockNumber := startBlockNumber; blockNumber < itemsOfAncient+tail; blockNumber++ {
 		blockHash := rawdb.ReadCanonicalHash(chainDb, blockNumber)
 		block := rawdb.ReadBlock(chainDb, blockHash, blockNumber)
 		receipts := rawdb.ReadRawReceipts(chainDb, blockHash, blockNumber)
@@ -0,0 +1,280 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateChunk is a consecutive range of the flat state, as streamed by ExportState
+// and consumed by the StateImporter. The entries are ordered by account hash, the
+// storage slots of an account following the account itself. The storage of large
+// contracts may span multiple chunks.
+type StateChunk struct {
+	Entries []StateEntry
+	Codes   [][]byte // Contract codes first referenced by the accounts of the chunk
+}
+
+// StateEntry is either an account in the slim snapshot format, or a storage slot
+// of the account preceding it.
+type StateEntry struct {
+	Storage bool        // Whether the entry is a storage slot
+	Hash    common.Hash // Hash of the account address or the storage slot
+	Blob    []byte      // Slim account RLP or the storage slot value
+}
+
+// size returns the approximate encoded size of the entry.
+func (e *StateEntry) size() int {
+	return common.HashLength + len(e.Blob) + 4
+}
+
+// ExportState streams the flat state with the given root, together with all the
+// referenced contract codes, in chunks of roughly the given size.
+func ExportState(t *Tree, root common.Hash, codedb ethdb.KeyValueReader, chunkSize int, fn func(*StateChunk) error) error {
+	accIt, err := t.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err // The required snapshot might not exist
+	}
+	defer accIt.Release()
+
+	var (
+		chunk = new(StateChunk)
+		size  int
+		codes = make(map[common.Hash]struct{})
+
+		accounts, slots uint64
+		start           = time.Now()
+		logged          = time.Now()
+	)
+	add := func(entry StateEntry) error {
+		chunk.Entries = append(chunk.Entries, entry)
+		if size += entry.size(); size < chunkSize {
+			return nil
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		chunk, size = new(StateChunk), 0
+		return nil
+	}
+	for accIt.Next() {
+		hash := accIt.Hash()
+		account, err := FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCode {
+			if _, ok := codes[codeHash]; !ok {
+				code := rawdb.ReadCode(codedb, codeHash)
+				if len(code) == 0 {
+					return fmt.Errorf("contract code %#x of account %#x missing", codeHash, hash)
+				}
+				codes[codeHash] = struct{}{}
+				chunk.Codes = append(chunk.Codes, code)
+				size += len(code)
+			}
+		}
+		if err := add(StateEntry{Hash: hash, Blob: common.CopyBytes(accIt.Account())}); err != nil {
+			return err
+		}
+		accounts++
+
+		if common.BytesToHash(account.Root) != emptyRoot {
+			stIt, err := t.StorageIterator(root, hash, common.Hash{})
+			if err != nil {
+				return err
+			}
+			for stIt.Next() {
+				if err := add(StateEntry{Storage: true, Hash: stIt.Hash(), Blob: common.CopyBytes(stIt.Slot())}); err != nil {
+					stIt.Release()
+					return err
+				}
+				slots++
+			}
+			stIt.Release()
+			if err := stIt.Error(); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state snapshot", "at", hash, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	if len(chunk.Entries) > 0 {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+	log.Info("Exported state snapshot", "root", root, "accounts", accounts, "slots", slots, "codes", len(codes), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// StateImporter rebuilds the flat state and the state trie from the chunks of
+// an exported state, verifying every storage root and contract code on the way.
+type StateImporter struct {
+	db    ethdb.KeyValueStore
+	batch ethdb.Batch
+
+	accTrie *trie.StackTrie // Trie of the accounts imported so far
+	stTrie  *trie.StackTrie // Storage trie of the pending account
+
+	pending  *Account    // Account waiting for its storage to be imported
+	account  common.Hash // Hash of the pending account
+	storage  bool        // Whether the pending account has any storage imported
+	last     common.Hash // Hash of the last imported slot, to enforce the ordering
+	codes    map[common.Hash]struct{}
+	accounts uint64
+	slots    uint64
+}
+
+// NewStateImporter creates an importer of an exported state. Any existing state
+// snapshot is wiped from the database, as the imported state replaces it.
+func NewStateImporter(db ethdb.KeyValueStore) (*StateImporter, error) {
+	rawdb.DeleteSnapshotRoot(db)
+	if err := wipeContent(db); err != nil {
+		return nil, err
+	}
+	batch := db.NewBatch()
+	return &StateImporter{
+		db:      db,
+		batch:   batch,
+		accTrie: trie.NewStackTrie(batch),
+		stTrie:  trie.NewStackTrie(batch),
+		codes:   make(map[common.Hash]struct{}),
+	}, nil
+}
+
+// Import adds the next chunk of the exported state.
+func (imp *StateImporter) Import(chunk *StateChunk) error {
+	for _, code := range chunk.Codes {
+		hash := crypto.Keccak256Hash(code)
+		imp.codes[hash] = struct{}{}
+		rawdb.WriteCode(imp.batch, hash, code)
+	}
+	for _, entry := range chunk.Entries {
+		if entry.Storage {
+			if imp.pending == nil {
+				return fmt.Errorf("storage slot %#x without account", entry.Hash)
+			}
+			if imp.storage && bytes.Compare(entry.Hash[:], imp.last[:]) <= 0 {
+				return fmt.Errorf("storage slot %#x of account %#x out of order", entry.Hash, imp.account)
+			}
+			if err := imp.stTrie.TryUpdate(entry.Hash[:], entry.Blob); err != nil {
+				return err
+			}
+			rawdb.WriteStorageSnapshot(imp.batch, imp.account, entry.Hash, entry.Blob)
+			imp.storage, imp.last = true, entry.Hash
+			imp.slots++
+		} else {
+			if imp.accounts > 0 && bytes.Compare(entry.Hash[:], imp.account[:]) <= 0 {
+				return fmt.Errorf("account %#x out of order", entry.Hash)
+			}
+			if err := imp.commitAccount(); err != nil {
+				return err
+			}
+			account, err := FullAccount(entry.Blob)
+			if err != nil {
+				return fmt.Errorf("invalid account %#x: %v", entry.Hash, err)
+			}
+			rawdb.WriteAccountSnapshot(imp.batch, entry.Hash, entry.Blob)
+			imp.pending, imp.account, imp.storage = &account, entry.Hash, false
+			imp.accounts++
+		}
+		if imp.batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := imp.batch.Write(); err != nil {
+				return err
+			}
+			imp.batch.Reset()
+		}
+	}
+	return nil
+}
+
+// commitAccount verifies the storage root and the code of the pending account and
+// inserts it into the account trie.
+func (imp *StateImporter) commitAccount() error {
+	if imp.pending == nil {
+		return nil
+	}
+	root := emptyRoot
+	if imp.storage {
+		var err error
+		if root, err = imp.stTrie.Commit(); err != nil {
+			return err
+		}
+		imp.stTrie = trie.NewStackTrie(imp.batch)
+	}
+	if want := common.BytesToHash(imp.pending.Root); root != want {
+		return fmt.Errorf("storage root mismatch of account %#x: have %#x, want %#x", imp.account, root, want)
+	}
+	if codeHash := common.BytesToHash(imp.pending.CodeHash); codeHash != emptyCode {
+		if _, ok := imp.codes[codeHash]; !ok {
+			return fmt.Errorf("contract code %#x of account %#x missing", codeHash, imp.account)
+		}
+	}
+	blob, err := rlp.EncodeToBytes(imp.pending)
+	if err != nil {
+		return err
+	}
+	imp.pending = nil
+	return imp.accTrie.TryUpdate(imp.account[:], blob)
+}
+
+// Commit finishes the import, verifying the root of the rebuilt state trie against
+// the expected one. Only on a match is the flat state marked as a fully generated
+// snapshot of the root, otherwise the imported flat state is wiped.
+func (imp *StateImporter) Commit(want common.Hash) error {
+	if err := imp.commitAccount(); err != nil {
+		return err
+	}
+	if imp.accounts == 0 {
+		return errors.New("empty state")
+	}
+	root, err := imp.accTrie.Commit()
+	if err != nil {
+		return err
+	}
+	if root != want {
+		imp.batch.Reset()
+		if err := wipeContent(imp.db); err != nil {
+			return err
+		}
+		return fmt.Errorf("state root mismatch: have %#x, want %#x", root, want)
+	}
+	rawdb.WriteSnapshotRoot(imp.batch, root)
+	journalProgress(imp.batch, nil, nil)
+	if err := imp.batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported state snapshot", "root", root, "accounts", imp.accounts, "slots", imp.slots, "codes", len(imp.codes))
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that an exported state can be imported into an empty database, rebuilding
+// the same state trie, and that tampered exports are rejected.
+func TestExportImportState(t *testing.T) {
+	var (
+		helper = newHelper()
+		code   = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+		keys   []string
+		vals   []string
+	)
+	for i := 0; i < 32; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+		vals = append(vals, fmt.Sprintf("val-%d", i))
+	}
+	stRoot := helper.makeStorageTrie(keys, vals)
+	rawdb.WriteCode(helper.diskdb, crypto.Keccak256Hash(code), code)
+
+	helper.addTrieAccount("acc-1", &Account{Balance: big.NewInt(1), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+	helper.addTrieAccount("acc-2", &Account{Balance: big.NewInt(2), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+	helper.addTrieAccount("acc-3", &Account{Balance: big.NewInt(3), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+
+	root, snap := helper.Generate()
+	select {
+	case <-snap.genPending:
+	case <-time.After(time.Second):
+		t.Fatalf("snapshot generation failed")
+	}
+	stop := make(chan *generatorStats)
+	snap.genAbort <- stop
+	<-stop
+
+	tree := &Tree{layers: map[common.Hash]snapshot{root: snap}}
+
+	// Export the state in small chunks, splitting the storage across them
+	var chunks [][]byte
+	if err := ExportState(tree, root, helper.diskdb, 256, func(chunk *StateChunk) error {
+		blob, err := rlp.EncodeToBytes(chunk)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, blob)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to export state: %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("state not chunked: %d chunks", len(chunks))
+	}
+	importState := func(want common.Hash, tamper func(*StateChunk)) (*memorydb.Database, error) {
+		db := memorydb.New()
+		importer, err := NewStateImporter(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range chunks {
+			chunk := new(StateChunk)
+			if err := rlp.DecodeBytes(blob, chunk); err != nil {
+				return nil, err
+			}
+			if tamper != nil {
+				tamper(chunk)
+			}
+			if err := importer.Import(chunk); err != nil {
+				return nil, err
+			}
+		}
+		return db, importer.Commit(want)
+	}
+	db, err := importState(root, nil)
+	if err != nil {
+		t.Fatalf("failed to import state: %v", err)
+	}
+	if rawdb.ReadSnapshotRoot(db) != root {
+		t.Fatalf("snapshot root not written")
+	}
+	stTrie, err := trie.NewSecure(common.BytesToHash(stRoot), trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open imported storage trie: %v", err)
+	}
+	for i, key := range keys {
+		if have := stTrie.Get([]byte(key)); !bytes.Equal(have, []byte(vals[i])) {
+			t.Errorf("slot %s mismatch: have %x, want %x", key, have, vals[i])
+		}
+	}
+	if have := rawdb.ReadCode(db, crypto.Keccak256Hash(code)); !bytes.Equal(have, code) {
+		t.Errorf("code mismatch: have %x, want %x", have, code)
+	}
+	// Tampered storage, missing codes and reordered entries must be rejected
+	for i, tamper := range []func(*StateChunk){
+		func(chunk *StateChunk) {
+			for i := range chunk.Entries {
+				if chunk.Entries[i].Storage {
+					chunk.Entries[i].Blob = []byte("tampered")
+				}
+			}
+		},
+		func(chunk *StateChunk) { chunk.Codes = nil },
+		func(chunk *StateChunk) {
+			if n := len(chunk.Entries); n > 1 {
+				chunk.Entries[0], chunk.Entries[n-1] = chunk.Entries[n-1], chunk.Entries[0]
+			}
+		},
+	} {
+		if _, err := importState(root, tamper); err == nil {
+			t.Errorf("tamper %d: import succeeded", i)
+		}
+	}
+	// A state not matching the expected root must not be left behind as a snapshot
+	db, err = importState(common.Hash{0x01}, nil)
+	if err == nil {
+		t.Fatalf("import of untrusted root succeeded")
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != (common.Hash{}) {
+		t.Errorf("snapshot root of untrusted state written: %#x", have)
+	}
+	if rawdb.ReadSnapshotGenerator(db) != nil {
+		t.Errorf("generator marker of untrusted state written")
+	}
+	it := db.NewIterator(rawdb.SnapshotAccountPrefix, nil)
+	defer it.Release()
+	if it.Next() {
+		t.Errorf("accounts of untrusted state left behind: %x", it.Key())
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// History derives the reverse state diff of the diff layer with the given root
+// from the parent layer it was built upon. The parent layer must still be live,
+// so the history should be derived right after the layer is created.
+func (t *Tree) History(root common.Hash, parentRoot common.Hash) (*types.StateHistory, error) {
+	t.lock.RLock()
+	layer, ok := t.layers[root].(*diffLayer)
+	t.lock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("diff layer [%#x] missing", root)
+	}
+	parent := layer.Parent()
+	if parent.Root() != parentRoot {
+		return nil, fmt.Errorf("diff layer [%#x] parent mismatch: have %#x, want %#x", root, parent.Root(), parentRoot)
+	}
+	layer.lock.RLock()
+	defer layer.lock.RUnlock()
+
+	history := new(types.StateHistory)
+
+	// Record the original value of all the modified and destructed accounts
+	accounts := make(map[common.Hash]struct{}, len(layer.accountData)+len(layer.destructSet))
+	for hash := range layer.destructSet {
+		accounts[hash] = struct{}{}
+	}
+	for hash := range layer.accountData {
+		accounts[hash] = struct{}{}
+	}
+	existed := make(map[common.Hash]bool, len(accounts))
+	for hash := range accounts {
+		blob, err := parent.AccountRLP(hash)
+		if err != nil {
+			return nil, err
+		}
+		history.Accounts = append(history.Accounts, types.HistoryAccount{Account: hash, Blob: common.CopyBytes(blob)})
+		existed[hash] = len(blob) > 0
+	}
+	// Record the whole original storage of the destructed accounts, and the
+	// original value of the modified slots of the others
+	for hash := range layer.destructSet {
+		storage := types.HistoryStorage{Account: hash, Wiped: true}
+		if existed[hash] {
+			it, err := t.StorageIterator(parentRoot, hash, common.Hash{})
+			if err != nil {
+				return nil, err
+			}
+			for it.Next() {
+				storage.Keys = append(storage.Keys, it.Hash())
+				storage.Vals = append(storage.Vals, common.CopyBytes(it.Slot()))
+			}
+			it.Release()
+			if err := it.Error(); err != nil {
+				return nil, err
+			}
+		}
+		history.Storages = append(history.Storages, storage)
+	}
+	for hash, slots := range layer.storageData {
+		if _, destructed := layer.destructSet[hash]; destructed {
+			continue
+		}
+		storage := types.HistoryStorage{Account: hash}
+		for slot := range slots {
+			blob, err := parent.Storage(hash, slot)
+			if err != nil {
+				return nil, err
+			}
+			storage.Keys = append(storage.Keys, slot)
+			storage.Vals = append(storage.Vals, common.CopyBytes(blob))
+		}
+		history.Storages = append(history.Storages, storage)
+	}
+	history.Sort()
+	return history, nil
+}
+
+// HistoryIndex resolves the values of the accounts and storage slots in a
+// historical state. The flags report whether the values were modified since the
+// historical state, otherwise they are unchanged in the current state.
+type HistoryIndex interface {
+	// Account retrieves the account in the snapshot slim data format.
+	Account(hash common.Hash) ([]byte, bool, error)
+
+	// Storage retrieves the storage data of a slot within an account.
+	Storage(accountHash, storageHash common.Hash) ([]byte, bool, error)
+}
+
+// historyLayer is a read only snapshot of a historical state, served by rolling
+// a live snapshot layer back with the values the history index recorded before
+// the modifications since.
+//
+// Historical states are not backed by any trie, so the layer is never marked as
+// verified, but it doesn't block on verification either.
+type historyLayer struct {
+	root  common.Hash  // Root hash of the historical state
+	base  Snapshot     // Live snapshot layer the historical state is rolled back from
+	index HistoryIndex // Index of the modifications since the historical state
+}
+
+// NewHistoryLayer creates a snapshot of the historical state with the given
+// root, rolling the base layer back with the history index.
+func NewHistoryLayer(root common.Hash, base Snapshot, index HistoryIndex) Snapshot {
+	return &historyLayer{
+		root:  root,
+		base:  base,
+		index: index,
+	}
+}
+
+// Root returns the root hash of the historical state.
+func (hl *historyLayer) Root() common.Hash {
+	return hl.root
+}
+
+// WaitAndGetVerifyRes returns immediately, historical states are not verified.
+func (hl *historyLayer) WaitAndGetVerifyRes() bool {
+	return true
+}
+
+// Verified returns false, historical states are not verified.
+func (hl *historyLayer) Verified() bool {
+	return false
+}
+
+// MarkValid is a noop for historical states.
+func (hl *historyLayer) MarkValid() {}
+
+// Account directly retrieves the account associated with a particular hash in
+// the snapshot slim data format.
+func (hl *historyLayer) Account(hash common.Hash) (*Account, error) {
+	data, err := hl.AccountRLP(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 { // can be both nil and []byte{}
+		return nil, nil
+	}
+	account := new(Account)
+	if err := rlp.DecodeBytes(data, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot slim data format.
+func (hl *historyLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	blob, modified, err := hl.index.Account(hash)
+	if err != nil {
+		return nil, err
+	}
+	if modified {
+		return blob, nil
+	}
+	return hl.base.AccountRLP(hash)
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (hl *historyLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	blob, modified, err := hl.index.Storage(accountHash, storageHash)
+	if err != nil {
+		return nil, err
+	}
+	if modified {
+		return blob, nil
+	}
+	return hl.base.Storage(accountHash, storageHash)
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// historyList is a history index scanning a list of state histories, the oldest
+// first.
+type historyList []*types.StateHistory
+
+func (l historyList) Account(hash common.Hash) ([]byte, bool, error) {
+	for _, history := range l {
+		if blob, ok := history.Account(hash); ok {
+			return blob, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (l historyList) Storage(accountHash, storageHash common.Hash) ([]byte, bool, error) {
+	for _, history := range l {
+		if blob, ok := history.Storage(accountHash, storageHash); ok {
+			return blob, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Tests that the historical states rolled back from the head layer with the
+// state histories match the original snapshot layers.
+func TestHistoryLayer(t *testing.T) {
+	// Create an empty base layer and a snapshot tree out of it
+	base := &diskLayer{
+		diskdb: rawdb.NewMemoryDatabase(),
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	// Create two accounts, modify one and destruct the other, then create a
+	// third one and modify the storage of the first again
+	snaps.update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil,
+		randomAccountSet("0xaa", "0xbb"), randomStorageSet([]string{"0xaa", "0xbb"}, [][]string{{"0x01", "0x02"}, {"0x01"}}, nil), nil)
+
+	snaps.update(common.HexToHash("0x03"), common.HexToHash("0x02"), map[common.Hash]struct{}{common.HexToHash("0xbb"): {}},
+		randomAccountSet("0xaa"), randomStorageSet([]string{"0xaa"}, [][]string{{"0x01", "0x03"}}, nil), nil)
+
+	snaps.update(common.HexToHash("0x04"), common.HexToHash("0x03"), nil,
+		randomAccountSet("0xaa", "0xcc"), randomStorageSet([]string{"0xaa"}, [][]string{{"0x02"}}, nil), nil)
+
+	if _, err := snaps.History(common.HexToHash("0x03"), common.HexToHash("0x01")); err == nil {
+		t.Fatalf("parent mismatch not detected")
+	}
+	var histories []*types.StateHistory
+	for _, layer := range [][2]string{{"0x03", "0x02"}, {"0x04", "0x03"}} {
+		root := layer[0]
+		history, err := snaps.History(common.HexToHash(root), common.HexToHash(layer[1]))
+		if err != nil {
+			t.Fatalf("history %s: failed to derive: %v", root, err)
+		}
+		// Round trip the histories through RLP to mimic them being persisted
+		blob, err := rlp.EncodeToBytes(history)
+		if err != nil {
+			t.Fatalf("history %s: failed to encode: %v", root, err)
+		}
+		history = new(types.StateHistory)
+		if err := rlp.DecodeBytes(blob, history); err != nil {
+			t.Fatalf("history %s: failed to decode: %v", root, err)
+		}
+		histories = append(histories, history)
+	}
+	if blob, ok := histories[0].Account(common.HexToHash("0xbb")); !ok || len(blob) == 0 {
+		t.Fatalf("destructed account missing from history")
+	}
+	if _, ok := histories[0].Storage(common.HexToHash("0xbb"), common.HexToHash("0x02")); !ok {
+		t.Fatalf("destructed storage not wiped in history")
+	}
+	head := snaps.Snapshot(common.HexToHash("0x04"))
+	for i, root := range []string{"0x02", "0x03"} {
+		var (
+			want    = snaps.Snapshot(common.HexToHash(root))
+			layer   = NewHistoryLayer(want.Root(), head, historyList(histories[i:]))
+			account = []string{"0xaa", "0xbb", "0xcc", "0xdd"}
+			slots   = []string{"0x01", "0x02", "0x03", "0x04"}
+		)
+		for _, acc := range account {
+			have, err := layer.AccountRLP(common.HexToHash(acc))
+			if err != nil {
+				t.Fatalf("root %s account %s: failed to retrieve: %v", root, acc, err)
+			}
+			exp, _ := want.AccountRLP(common.HexToHash(acc))
+			if !bytes.Equal(have, exp) {
+				t.Errorf("root %s account %s: mismatch: have %x, want %x", root, acc, have, exp)
+			}
+			for _, slot := range slots {
+				have, err := layer.Storage(common.HexToHash(acc), common.HexToHash(slot))
+				if err != nil {
+					t.Fatalf("root %s slot %s/%s: failed to retrieve: %v", root, acc, slot, err)
+				}
+				exp, _ := want.Storage(common.HexToHash(acc), common.HexToHash(slot))
+				if !bytes.Equal(have, exp) {
+					t.Errorf("root %s slot %s/%s: mismatch: have %x, want %x", root, acc, slot, have, exp)
+				}
+			}
+		}
+	}
+}
@@ -271,7 +271,14 @@ func (s *StateObject) GetCommittedState(db Database, key common.Hash) common.Has
 func (s *StateObject) SetState(db Database, key, value common.Hash) {
 	// If the fake storage is set, put the temporary state update here.
 	if s.fakeStorage != nil {
-		s.fakeStorage[key] = value
+		if prev := s.fakeStorage[key]; prev != value {
+			s.db.journal.append(fakeStorageChange{
+				account:  &s.address,
+				key:      key,
+				prevalue: prev,
+			})
+			s.fakeStorage[key] = value
+		}
 		return
 	}
 	// If the new value is the same as old, don't set
@@ -144,10 +144,21 @@ type StateDB struct {
 
 // New creates a new state from a given trie.
 func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
-	return newStateDB(root, db, snaps)
+	var snap snapshot.Snapshot
+	if snaps != nil {
+		snap = snaps.Snapshot(root)
+	}
+	return newStateDB(root, db, snaps, snap)
+}
+
+// NewWithSnapshot creates a new read only state served from the given snapshot
+// layer, which may not be part of any snapshot tree. The state trie is opened if
+// it's available, but it's not required to be.
+func NewWithSnapshot(root common.Hash, db Database, snap snapshot.Snapshot) (*StateDB, error) {
+	return newStateDB(root, db, nil, snap)
 }
 
-func newStateDB(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
+func newStateDB(root common.Hash, db Database, snaps *snapshot.Tree, snap snapshot.Snapshot) (*StateDB, error) {
 	sdb := &StateDB{
 		db:                  db,
 		originalRoot:        root,
@@ -160,12 +171,10 @@ func newStateDB(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB,
 		journal:             newJournal(),
 		hasher:              crypto.NewKeccakState(),
 	}
-	if sdb.snaps != nil {
-		if sdb.snap = sdb.snaps.Snapshot(root); sdb.snap != nil {
-			sdb.snapDestructs = make(map[common.Address]struct{})
-			sdb.snapAccounts = make(map[common.Address][]byte)
-			sdb.snapStorage = make(map[common.Address]map[string][]byte)
-		}
+	if sdb.snap = snap; sdb.snap != nil {
+		sdb.snapDestructs = make(map[common.Address]struct{})
+		sdb.snapAccounts = make(map[common.Address][]byte)
+		sdb.snapStorage = make(map[common.Address]map[string][]byte)
 	}
 
 	snapVerified := sdb.snap != nil && sdb.snap.Verified()
@@ -1300,6 +1309,20 @@ func (s *StateDB) Commit(failPostCommitFunc func(), postCommitFuncs ...func() er
 	var snapUpdated chan struct{}
 	if s.snap != nil {
 		diffLayer = &types.DiffLayer{}
+
+		// Collect the dirty codes before the code writer resets the dirty flags,
+		// so that the diff layer is complete by the time Commit returns, even
+		// if the trie is committed asynchronously.
+		for addr := range s.stateObjectsDirty {
+			if obj := s.stateObjects[addr]; !obj.deleted {
+				if obj.code != nil && obj.dirtyCode {
+					diffLayer.Codes = append(diffLayer.Codes, types.DiffCode{
+						Hash: common.BytesToHash(obj.CodeHash()),
+						Code: obj.code,
+					})
+				}
+			}
+		}
 	}
 	if s.pipeCommit {
 		// async commit the MPT
@@ -1334,19 +1357,6 @@ func (s *StateDB) Commit(failPostCommitFunc func(), postCommitFuncs ...func() er
 				}()
 			}
 
-			if s.snap != nil {
-				for addr := range s.stateObjectsDirty {
-					if obj := s.stateObjects[addr]; !obj.deleted {
-						if obj.code != nil && obj.dirtyCode {
-							diffLayer.Codes = append(diffLayer.Codes, types.DiffCode{
-								Hash: common.BytesToHash(obj.CodeHash()),
-								Code: obj.code,
-							})
-						}
-					}
-				}
-			}
-
 			for addr := range s.stateObjectsDirty {
 				if obj := s.stateObjects[addr]; !obj.deleted {
 					// Write any contract code associated with the state object
@@ -0,0 +1,198 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DiffAccount is the state of an account on one side of a state diff. Only
+// the storage slots which were modified are tracked.
+type DiffAccount struct {
+	Balance *big.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[common.Hash]common.Hash
+}
+
+// AccountDiff holds the state of an account before and after a change. Pre
+// is nil if the account was created by the change and Post is nil if it was
+// destroyed.
+type AccountDiff struct {
+	Pre  *DiffAccount
+	Post *DiffAccount
+}
+
+// StateDiff is the set of accounts modified by a change.
+type StateDiff map[common.Address]*AccountDiff
+
+// journalOrigin collects the first recorded previous value of every account
+// field while walking the journal.
+type journalOrigin struct {
+	seen    bool // Whether the existence of the account before the change is known
+	created bool // Whether the account was missing before the change
+
+	balance *big.Int
+	nonce   *uint64
+	code    []byte
+	hasCode bool
+	storage map[common.Hash]common.Hash
+}
+
+// JournalDiff reconstructs the state changes made since the last Finalise from
+// the state journal. As the journal is cleared upon finalisation, it has to be
+// called after executing a message and before finalising the state; the flag
+// tells which accounts the upcoming Finalise is going to delete.
+func (s *StateDB) JournalDiff(deleteEmptyObjects bool) StateDiff {
+	var (
+		origins = make(map[common.Address]*journalOrigin)
+		order   []common.Address
+	)
+	origin := func(addr common.Address) *journalOrigin {
+		o, ok := origins[addr]
+		if !ok {
+			o = &journalOrigin{storage: make(map[common.Hash]common.Hash)}
+			origins[addr] = o
+			order = append(order, addr)
+		}
+		return o
+	}
+	for _, entry := range s.journal.entries {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			if o := origin(*ch.account); !o.seen {
+				o.seen, o.created = true, true
+			}
+		case resetObjectChange:
+			o := origin(ch.prev.address)
+			o.seen = true
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prev.Balance())
+			}
+			if o.nonce == nil {
+				nonce := ch.prev.Nonce()
+				o.nonce = &nonce
+			}
+			if !o.hasCode {
+				o.code, o.hasCode = ch.prev.Code(s.db), true
+			}
+		case suicideChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prevbalance)
+			}
+		case balanceChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if o.balance == nil {
+				o.balance = new(big.Int).Set(ch.prev)
+			}
+		case nonceChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if o.nonce == nil {
+				nonce := ch.prev
+				o.nonce = &nonce
+			}
+		case codeChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if !o.hasCode {
+				o.code, o.hasCode = ch.prevcode, true
+			}
+		case storageChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if _, ok := o.storage[ch.key]; !ok {
+				o.storage[ch.key] = ch.prevalue
+			}
+		case fakeStorageChange:
+			o := origin(*ch.account)
+			o.seen = true
+			if _, ok := o.storage[ch.key]; !ok {
+				o.storage[ch.key] = ch.prevalue
+			}
+		}
+	}
+	diff := make(StateDiff)
+	for _, addr := range order {
+		o := origins[addr]
+
+		var post *DiffAccount
+		if obj := s.getStateObject(addr); obj != nil && !obj.suicided && !(deleteEmptyObjects && obj.empty()) {
+			post = &DiffAccount{
+				Balance: new(big.Int).Set(obj.Balance()),
+				Nonce:   obj.Nonce(),
+				Code:    obj.Code(s.db),
+				Storage: make(map[common.Hash]common.Hash),
+			}
+		}
+		var pre *DiffAccount
+		if !o.created {
+			pre = &DiffAccount{Storage: make(map[common.Hash]common.Hash)}
+			switch {
+			case o.balance != nil:
+				pre.Balance = o.balance
+			case post != nil:
+				pre.Balance = new(big.Int).Set(post.Balance)
+			default:
+				pre.Balance = new(big.Int)
+			}
+			switch {
+			case o.nonce != nil:
+				pre.Nonce = *o.nonce
+			case post != nil:
+				pre.Nonce = post.Nonce
+			}
+			switch {
+			case o.hasCode:
+				pre.Code = o.code
+			case post != nil:
+				pre.Code = post.Code
+			}
+		}
+		// Only track the slots which ended up with a different value
+		for key, prev := range o.storage {
+			value := s.GetState(addr, key)
+			if post == nil {
+				value = common.Hash{}
+			}
+			if value == prev {
+				continue
+			}
+			if pre != nil {
+				pre.Storage[key] = prev
+			}
+			if post != nil {
+				post.Storage[key] = value
+			}
+		}
+		if pre == nil && post == nil {
+			continue
+		}
+		if pre != nil && post != nil && pre.Balance.Cmp(post.Balance) == 0 && pre.Nonce == post.Nonce &&
+			bytes.Equal(pre.Code, post.Code) && len(post.Storage) == 0 {
+			continue
+		}
+		diff[addr] = &AccountDiff{Pre: pre, Post: post}
+	}
+	return diff
+}
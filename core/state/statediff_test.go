@@ -0,0 +1,75 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// Tests that the journal based state diff reports the values from before the
+// first and after the last modification, and drops no-op changes.
+func TestJournalDiff(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		existing = common.Address{0x01}
+		created  = common.Address{0x02}
+		noop     = common.Address{0x03}
+		slot     = common.Hash{0xaa}
+		other    = common.Hash{0xbb}
+	)
+	state.SetBalance(existing, big.NewInt(100))
+	state.SetNonce(existing, 1)
+	state.SetState(existing, slot, common.Hash{0x01})
+	state.SetBalance(noop, big.NewInt(7))
+	state.Finalise(true)
+
+	state.AddBalance(existing, big.NewInt(10))
+	state.AddBalance(existing, big.NewInt(10))
+	state.SetNonce(existing, 2)
+	state.SetState(existing, slot, common.Hash{0x02})
+	state.SetState(existing, other, common.Hash{0x03})
+	state.SetState(existing, other, common.Hash{})
+	state.SetCode(created, []byte{0x60, 0x00})
+	state.AddBalance(noop, big.NewInt(1))
+	state.SubBalance(noop, big.NewInt(1))
+
+	diff := state.JournalDiff(true)
+	if len(diff) != 2 {
+		t.Fatalf("diff size mismatch: have %d, want 2", len(diff))
+	}
+	acc := diff[existing]
+	if acc == nil || acc.Pre == nil || acc.Post == nil {
+		t.Fatalf("missing pre or post state for modified account: %v", acc)
+	}
+	if acc.Pre.Balance.Int64() != 100 || acc.Post.Balance.Int64() != 120 {
+		t.Errorf("balance mismatch: have %v -> %v, want 100 -> 120", acc.Pre.Balance, acc.Post.Balance)
+	}
+	if acc.Pre.Nonce != 1 || acc.Post.Nonce != 2 {
+		t.Errorf("nonce mismatch: have %d -> %d, want 1 -> 2", acc.Pre.Nonce, acc.Post.Nonce)
+	}
+	if len(acc.Post.Storage) != 1 || acc.Pre.Storage[slot] != (common.Hash{0x01}) || acc.Post.Storage[slot] != (common.Hash{0x02}) {
+		t.Errorf("storage mismatch: have %v -> %v", acc.Pre.Storage, acc.Post.Storage)
+	}
+	if acc := diff[created]; acc == nil || acc.Pre != nil || acc.Post == nil || len(acc.Post.Code) != 2 {
+		t.Errorf("created account mismatch: %v", acc)
+	}
+}
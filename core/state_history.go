@@ -0,0 +1,156 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// buildStateHistory derives the reverse state diff of a freshly processed block
+// from its snapshot diff layer. Nil is returned if the diff layer is unavailable,
+// leaving a gap in the state history.
+func (bc *BlockChain) buildStateHistory(block *types.Block) *types.StateHistory {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil
+	}
+	// Blocks not modifying the state have no snapshot layer of their own
+	if parent.Root == block.Root() {
+		return new(types.StateHistory)
+	}
+	history, err := bc.snaps.History(block.Root(), parent.Root)
+	if err != nil {
+		log.Debug("Failed to derive state history", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return nil
+	}
+	return history
+}
+
+// indexStateHistory adds the state history of a block becoming canonical to the
+// state index. A block without state history cuts the index, as the states
+// before it can no longer be rolled back to. With the chain history pruned, the
+// entries of the blocks falling out of the retained history are discarded.
+//
+// Note, this method assumes that the chain mutex is held!
+func (bc *BlockChain) indexStateHistory(db ethdb.KeyValueWriter, block *types.Block) {
+	number := block.NumberU64()
+
+	history := rawdb.ReadStateHistory(bc.db, block.Hash(), number)
+	if history == nil {
+		if bc.stateIndexTail == nil || *bc.stateIndexTail < number {
+			bc.stateIndexTail = &number
+			rawdb.WriteStateIndexTail(db, number)
+		}
+		return
+	}
+	rawdb.WriteStateIndex(db, number, history)
+	if bc.stateIndexTail == nil {
+		tail := number - 1
+		bc.stateIndexTail = &tail
+		rawdb.WriteStateIndexTail(db, tail)
+	}
+	if bc.historyBlocks > 0 && number > bc.historyBlocks {
+		bc.pruneStateIndex(db, number-bc.historyBlocks)
+	}
+}
+
+// pruneStateIndex discards the state index entries and state histories of the
+// blocks up to the given one, advancing the tail of the state index to it. The
+// number of blocks pruned at once is capped, so an index built before enabling
+// the history pruning is caught up with gradually.
+//
+// Note, this method assumes that the chain mutex is held!
+func (bc *BlockChain) pruneStateIndex(db ethdb.KeyValueWriter, target uint64) {
+	tail := *bc.stateIndexTail
+	if tail >= target {
+		return
+	}
+	if target-tail > stateIndexPruneLimit {
+		target = tail + stateIndexPruneLimit
+	}
+	for number := tail + 1; number <= target; number++ {
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if history := rawdb.ReadStateHistory(bc.db, hash, number); history != nil {
+			rawdb.DeleteStateIndex(db, number, history)
+			rawdb.DeleteStateHistory(db, hash, number)
+		}
+	}
+	bc.stateIndexTail = &target
+	rawdb.WriteStateIndexTail(db, target)
+}
+
+// unindexStateHistory removes the state history of a block no longer canonical
+// from the state index.
+func (bc *BlockChain) unindexStateHistory(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if history := rawdb.ReadStateHistory(bc.db, hash, number); history != nil {
+		rawdb.DeleteStateIndex(db, number, history)
+	}
+}
+
+// stateIndex is a history index resolving the values of the state of a block
+// from the state index, considering the modifications up to the given head.
+type stateIndex struct {
+	db     ethdb.Iteratee
+	number uint64 // Number of the block whose state is resolved
+	head   uint64 // Number of the head block whose state the index rolls back
+}
+
+// Account implements snapshot.HistoryIndex, retrieving an account.
+func (idx *stateIndex) Account(hash common.Hash) ([]byte, bool, error) {
+	blob, modified := rawdb.ReadAccountIndex(idx.db, hash, idx.number, idx.head)
+	return blob, modified, nil
+}
+
+// Storage implements snapshot.HistoryIndex, retrieving a storage slot.
+func (idx *stateIndex) Storage(accountHash, storageHash common.Hash) ([]byte, bool, error) {
+	blob, modified := rawdb.ReadStorageIndex(idx.db, accountHash, storageHash, idx.number, idx.head)
+	return blob, modified, nil
+}
+
+// HistoricState returns a read only state of a canonical block, reconstructed by
+// rolling the snapshot of the current head back with the state index, which
+// resolves every account and storage slot with a single range lookup. It serves
+// the states whose tries were already pruned.
+func (bc *BlockChain) HistoricState(header *types.Header) (*state.StateDB, error) {
+	if !bc.cacheConfig.StateHistory {
+		return nil, ErrStateHistoryUnavailable
+	}
+	var (
+		number = header.Number.Uint64()
+		head   = bc.CurrentBlock()
+	)
+	if number > head.NumberU64() || bc.GetCanonicalHash(number) != header.Hash() {
+		return nil, fmt.Errorf("%w: block #%d [%x…] is not canonical", ErrStateHistoryUnavailable, number, header.Hash().Bytes()[:4])
+	}
+	if tail := rawdb.ReadStateIndexTail(bc.db); tail == nil || number < *tail {
+		return nil, fmt.Errorf("%w: block #%d [%x…] is not indexed", ErrStateHistoryUnavailable, number, header.Hash().Bytes()[:4])
+	}
+	base := bc.snaps.Snapshot(head.Root())
+	if base == nil {
+		return nil, fmt.Errorf("%w: head snapshot unavailable", ErrStateHistoryUnavailable)
+	}
+	index := &stateIndex{db: bc.db, number: number, head: head.NumberU64()}
+	return state.NewWithSnapshot(header.Root, bc.stateCache, snapshot.NewHistoryLayer(header.Root, base, index))
+}
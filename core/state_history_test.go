@@ -0,0 +1,184 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the historical states reconstructed from the state index match the
+// states of the blocks, also after a reorg.
+func TestHistoricState(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		store  = common.HexToAddress("0xc0de")
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				addr:  {Balance: big.NewInt(1000000000000000)},
+				store: {Balance: new(big.Int), Code: common.FromHex("600035600055")}, // sstore(0, calldataload(0))
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	// generate creates a chain transferring to distinct recipients, storing into
+	// the contract every other block
+	generate := func(n int, seed byte) []*types.Block {
+		blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, n, func(i int, b *BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{seed, byte(i)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+			b.AddTx(tx)
+			if i%2 == int(seed)%2 {
+				tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(addr), store, nil, 100000, nil, common.LeftPadBytes([]byte{seed, byte(i)}, 32)), signer, key)
+				b.AddTx(tx)
+			}
+		})
+		return blocks
+	}
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.StateHistory = true
+
+	chain, err := NewBlockChain(db, &cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for _, blocks := range [][]*types.Block{generate(8, 1), generate(10, 2)} {
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		if head := chain.CurrentBlock().Hash(); head != blocks[len(blocks)-1].Hash() {
+			t.Fatalf("chain head mismatch: have %x, want %x", head, blocks[len(blocks)-1].Hash())
+		}
+		accounts := []common.Address{addr, store, blocks[0].Coinbase()}
+		for i := 0; i < 10; i++ {
+			accounts = append(accounts, common.Address{1, byte(i)}, common.Address{2, byte(i)})
+		}
+		for _, block := range append([]*types.Block{genesis}, blocks...) {
+			historic, err := chain.HistoricState(block.Header())
+			if err != nil {
+				t.Fatalf("block %d: failed to reconstruct state: %v", block.NumberU64(), err)
+			}
+			live, err := chain.StateAt(block.Root())
+			if err != nil {
+				t.Fatalf("block %d: failed to open state: %v", block.NumberU64(), err)
+			}
+			for _, account := range accounts {
+				if have, want := historic.Exist(account), live.Exist(account); have != want {
+					t.Errorf("block %d account %x: existence mismatch: have %v, want %v", block.NumberU64(), account, have, want)
+				}
+				if have, want := historic.GetBalance(account), live.GetBalance(account); have.Cmp(want) != 0 {
+					t.Errorf("block %d account %x: balance mismatch: have %v, want %v", block.NumberU64(), account, have, want)
+				}
+				if have, want := historic.GetNonce(account), live.GetNonce(account); have != want {
+					t.Errorf("block %d account %x: nonce mismatch: have %v, want %v", block.NumberU64(), account, have, want)
+				}
+			}
+			if have, want := historic.GetState(store, common.Hash{}), live.GetState(store, common.Hash{}); have != want {
+				t.Errorf("block %d: storage mismatch: have %x, want %x", block.NumberU64(), have, want)
+			}
+			if have := historic.GetCode(store); len(have) == 0 {
+				t.Errorf("block %d: code missing", block.NumberU64())
+			}
+			if err := historic.Error(); err != nil {
+				t.Errorf("block %d: state error: %v", block.NumberU64(), err)
+			}
+		}
+	}
+	// Historical states of non-canonical blocks are not available
+	side := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Extra: []byte("side")})
+	if _, err := chain.HistoricState(side.Header()); err == nil {
+		t.Fatalf("non-canonical state reconstructed")
+	}
+}
+
+// Tests that the state index is pruned along with the chain history, keeping the
+// historical states of the retained blocks servable.
+func TestHistoricStatePruned(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(1000000000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		b.AddTx(tx)
+	})
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.StateHistory = true
+
+	chain, err := NewBlockChain(db, &cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	chain.historyBlocks = 4
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadStateIndexTail(db); tail == nil || *tail != 6 {
+		t.Fatalf("state index tail mismatch: have %v, want %d", tail, 6)
+	}
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		number := block.NumberU64()
+		if number <= 6 {
+			if rawdb.ReadStateHistory(db, block.Hash(), number) != nil {
+				t.Errorf("block %d: pruned state history retained", number)
+			}
+		}
+		historic, err := chain.HistoricState(block.Header())
+		if number < 6 {
+			if err == nil {
+				t.Errorf("block %d: pruned state reconstructed", number)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("block %d: failed to reconstruct state: %v", number, err)
+		}
+		live, err := chain.StateAt(block.Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", number, err)
+		}
+		if have, want := historic.GetBalance(addr), live.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("block %d: balance mismatch: have %v, want %v", number, have, want)
+		}
+	}
+	// The index entries of the pruned blocks are gone
+	if _, ok := rawdb.ReadAccountIndex(db, crypto.Keccak256Hash(addr.Bytes()), 0, 6); ok {
+		t.Errorf("pruned account index retained")
+	}
+}
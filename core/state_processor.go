@@ -85,7 +85,7 @@ func (p *LightStateProcessor) Process(block *types.Block, statedb *state.StateDB
 		allowLightProcess = posa.AllowLightProcess(p.bc, block.Header())
 	}
 	// random fallback to full process
-	if allowLightProcess && block.NumberU64()%fullProcessCheck != uint64(p.check) && len(block.Transactions()) != 0 {
+	if allowLightProcess && !p.sample(block) && len(block.Transactions()) != 0 {
 		var pid string
 		if peer, ok := block.ReceivedFrom.(PeerIDer); ok {
 			pid = peer.ID()
@@ -103,6 +103,9 @@ func (p *LightStateProcessor) Process(block *types.Block, statedb *state.StateDB
 			}
 			time.Sleep(time.Millisecond)
 		}
+		if diffLayer != nil && !p.verifyDiffLayer(block, diffLayer) {
+			diffLayer = nil
+		}
 		if diffLayer != nil {
 			if err := diffLayer.Receipts.DeriveFields(p.bc.chainConfig, block.Hash(), block.NumberU64(), block.Transactions()); err != nil {
 				log.Error("Failed to derive block receipts fields", "hash", block.Hash(), "number", block.NumberU64(), "err", err)
@@ -136,6 +139,39 @@ func (p *LightStateProcessor) Process(block *types.Block, statedb *state.StateDB
 	return p.StateProcessor.Process(block, statedb, cfg)
 }
 
+// sample reports whether the block should be fully processed to check the
+// diff layers received for it.
+func (p *LightStateProcessor) sample(block *types.Block) bool {
+	if rate := p.bc.diffSampleRate; rate > 0 {
+		return rand.Float64() < rate
+	}
+	return block.NumberU64()%fullProcessCheck == uint64(p.check)
+}
+
+// verifyDiffLayer checks that the diff layer is signed by the producer of the
+// block. Layers with an invalid signature are discarded along with everything
+// else received from the peers which sent them. Unsigned layers are skipped,
+// unless accepted for the sake of producers which do not sign them yet.
+func (p *LightStateProcessor) verifyDiffLayer(block *types.Block, diffLayer *types.DiffLayer) bool {
+	posa, ok := p.engine.(consensus.PoSA)
+	if !ok {
+		return true
+	}
+	if len(diffLayer.Signature) == 0 {
+		if p.bc.diffUnsigned {
+			return true
+		}
+		log.Debug("Skipping unsigned diff layer", "number", block.NumberU64(), "hash", block.Hash(), "diff", diffLayer.DiffHash)
+		return false
+	}
+	if err := posa.VerifyDiffLayer(p.bc, block.Header(), diffLayer); err != nil {
+		log.Warn("Discarding invalid diff layer", "number", block.NumberU64(), "hash", block.Hash(), "diff", diffLayer.DiffHash, "err", err)
+		p.bc.removeDiffLayers(diffLayer.DiffHash)
+		return false
+	}
+	return true
+}
+
 func (p *LightStateProcessor) LightProcess(diffLayer *types.DiffLayer, block *types.Block, statedb *state.StateDB) (types.Receipts, []*types.Log, uint64, error) {
 	statedb.MarkLightProcessed()
 	fullDiffCode := make(map[common.Hash][]byte, len(diffLayer.Codes))
@@ -376,6 +412,9 @@ func (p *LightStateProcessor) LightProcess(diffLayer *types.DiffLayer, block *ty
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (*state.StateDB, types.Receipts, []*types.Log, uint64, error) {
+	if p.parallelEnabled(block, cfg) {
+		return p.processParallel(block, statedb, cfg)
+	}
 	var (
 		usedGas = new(uint64)
 		header  = block.Header()
@@ -447,7 +486,12 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	if err != nil {
 		return nil, err
 	}
+	return newReceipt(msg, result, config, statedb, header, tx, usedGas, receiptProcessors...), nil
+}
 
+// newReceipt finalises the state changes of an executed transaction and creates
+// its receipt.
+func newReceipt(msg types.Message, result *ExecutionResult, config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, receiptProcessors ...ReceiptProcessor) *types.Receipt {
 	// Update the state with pending changes.
 	var root []byte
 	if config.IsByzantium(header.Number) {
@@ -470,7 +514,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 
 	// If the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
-		receipt.ContractAddress = crypto.CreateAddress(evm.TxContext.Origin, tx.Nonce())
+		receipt.ContractAddress = crypto.CreateAddress(msg.From(), tx.Nonce())
 	}
 
 	// Set the receipt logs and create the bloom filter.
@@ -481,7 +525,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	for _, receiptProcessor := range receiptProcessors {
 		receiptProcessor.Apply(receipt)
 	}
-	return receipt, err
+	return receipt
 }
 
 // ApplyTransaction attempts to apply a transaction to the given state database
@@ -0,0 +1,337 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// accessSet is a set of state locations touched by transaction execution.
+// Account level fields (balance, nonce, code and existence) are tracked as a
+// whole, storage slots individually.
+type accessSet struct {
+	accounts map[common.Address]struct{}
+	slots    map[common.Address]map[common.Hash]struct{}
+	storages map[common.Address]struct{} // Accounts whose whole storage was iterated (reads) or wiped (writes)
+}
+
+func newAccessSet() *accessSet {
+	return &accessSet{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+		storages: make(map[common.Address]struct{}),
+	}
+}
+
+func (s *accessSet) addAccount(addr common.Address) {
+	s.accounts[addr] = struct{}{}
+}
+
+func (s *accessSet) addSlot(addr common.Address, slot common.Hash) {
+	slots, ok := s.slots[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		s.slots[addr] = slots
+	}
+	slots[slot] = struct{}{}
+}
+
+func (s *accessSet) addStorage(addr common.Address) {
+	s.storages[addr] = struct{}{}
+}
+
+// merge adds all locations of the given set to this one.
+func (s *accessSet) merge(other *accessSet) {
+	for addr := range other.accounts {
+		s.addAccount(addr)
+	}
+	for addr, slots := range other.slots {
+		for slot := range slots {
+			s.addSlot(addr, slot)
+		}
+	}
+	for addr := range other.storages {
+		s.addStorage(addr)
+	}
+}
+
+// conflicts reports whether any location read, as tracked by this set, was
+// modified by the given set of writes.
+func (s *accessSet) conflicts(writes *accessSet) bool {
+	for addr := range s.accounts {
+		if _, ok := writes.accounts[addr]; ok {
+			return true
+		}
+	}
+	for addr, slots := range s.slots {
+		if _, ok := writes.storages[addr]; ok {
+			return true
+		}
+		written := writes.slots[addr]
+		for slot := range slots {
+			if _, ok := written[slot]; ok {
+				return true
+			}
+		}
+	}
+	for addr := range s.storages {
+		if _, ok := writes.storages[addr]; ok {
+			return true
+		}
+		if len(writes.slots[addr]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stateOp is a state mutation recorded during execution, which can be replayed
+// on a different state database. Snapshot identifiers differ between databases,
+// revisions maps the recorded ones to the replayed ones.
+type stateOp func(db *state.StateDB, revisions map[int]int)
+
+// stateRecorder wraps a state database, recording the locations read and
+// written by the EVM. If replay is enabled, the mutations are recorded too.
+//
+// Any value handed out to the EVM is considered a read, as it may influence
+// the course of execution. Blind writes, notably balance changes, are not:
+// replaying the exact same sequence of mutations on a state in which all read
+// locations hold the same values yields the same state as re-executing the
+// transaction would.
+type stateRecorder struct {
+	db     *state.StateDB
+	reads  *accessSet
+	writes *accessSet
+
+	replay bool
+	ops    []stateOp
+}
+
+// newStateRecorder wraps the given state database in a recorder.
+func newStateRecorder(db *state.StateDB, replay bool) *stateRecorder {
+	return &stateRecorder{
+		db:     db,
+		reads:  newAccessSet(),
+		writes: newAccessSet(),
+		replay: replay,
+	}
+}
+
+// record appends a mutation to the replay log, if enabled.
+func (r *stateRecorder) record(op stateOp) {
+	if r.replay {
+		r.ops = append(r.ops, op)
+	}
+}
+
+// apply replays the recorded mutations on the given state database.
+func (r *stateRecorder) apply(db *state.StateDB) {
+	revisions := make(map[int]int)
+	for _, op := range r.ops {
+		op(db, revisions)
+	}
+}
+
+// touchBalance tracks a balance change of an account. Zero value changes only
+// touch the account if it's empty, which makes them depend on its state.
+func (r *stateRecorder) touchBalance(addr common.Address, amount *big.Int) {
+	if amount.Sign() == 0 {
+		r.reads.addAccount(addr)
+		if !r.db.Empty(addr) {
+			return
+		}
+	}
+	r.writes.addAccount(addr)
+}
+
+func (r *stateRecorder) CreateAccount(addr common.Address) {
+	// The balance of the previous account is carried over
+	r.reads.addAccount(addr)
+	r.writes.addAccount(addr)
+	r.writes.addStorage(addr)
+
+	r.db.CreateAccount(addr)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.CreateAccount(addr) })
+}
+
+func (r *stateRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.touchBalance(addr, amount)
+	r.db.SubBalance(addr, amount)
+
+	amount = new(big.Int).Set(amount)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.SubBalance(addr, amount) })
+}
+
+func (r *stateRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.touchBalance(addr, amount)
+	r.db.AddBalance(addr, amount)
+
+	amount = new(big.Int).Set(amount)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddBalance(addr, amount) })
+}
+
+func (r *stateRecorder) GetBalance(addr common.Address) *big.Int {
+	r.reads.addAccount(addr)
+	return r.db.GetBalance(addr)
+}
+
+func (r *stateRecorder) GetNonce(addr common.Address) uint64 {
+	r.reads.addAccount(addr)
+	return r.db.GetNonce(addr)
+}
+
+func (r *stateRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.writes.addAccount(addr)
+	r.db.SetNonce(addr, nonce)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.SetNonce(addr, nonce) })
+}
+
+func (r *stateRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.reads.addAccount(addr)
+	return r.db.GetCodeHash(addr)
+}
+
+func (r *stateRecorder) GetCode(addr common.Address) []byte {
+	r.reads.addAccount(addr)
+	return r.db.GetCode(addr)
+}
+
+func (r *stateRecorder) SetCode(addr common.Address, code []byte) {
+	r.writes.addAccount(addr)
+	r.db.SetCode(addr, code)
+
+	code = common.CopyBytes(code)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.SetCode(addr, code) })
+}
+
+func (r *stateRecorder) GetCodeSize(addr common.Address) int {
+	r.reads.addAccount(addr)
+	return r.db.GetCodeSize(addr)
+}
+
+func (r *stateRecorder) AddRefund(gas uint64) {
+	r.db.AddRefund(gas)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddRefund(gas) })
+}
+
+func (r *stateRecorder) SubRefund(gas uint64) {
+	r.db.SubRefund(gas)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.SubRefund(gas) })
+}
+
+func (r *stateRecorder) GetRefund() uint64 {
+	return r.db.GetRefund()
+}
+
+func (r *stateRecorder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	r.reads.addSlot(addr, key)
+	return r.db.GetCommittedState(addr, key)
+}
+
+func (r *stateRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.reads.addSlot(addr, key)
+	return r.db.GetState(addr, key)
+}
+
+func (r *stateRecorder) SetState(addr common.Address, key, value common.Hash) {
+	r.writes.addAccount(addr)
+	r.writes.addSlot(addr, key)
+	r.db.SetState(addr, key, value)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.SetState(addr, key, value) })
+}
+
+func (r *stateRecorder) Suicide(addr common.Address) bool {
+	r.reads.addAccount(addr)
+	r.writes.addAccount(addr)
+	r.writes.addStorage(addr)
+
+	suicided := r.db.Suicide(addr)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.Suicide(addr) })
+	return suicided
+}
+
+func (r *stateRecorder) HasSuicided(addr common.Address) bool {
+	r.reads.addAccount(addr)
+	return r.db.HasSuicided(addr)
+}
+
+func (r *stateRecorder) Exist(addr common.Address) bool {
+	r.reads.addAccount(addr)
+	return r.db.Exist(addr)
+}
+
+func (r *stateRecorder) Empty(addr common.Address) bool {
+	r.reads.addAccount(addr)
+	return r.db.Empty(addr)
+}
+
+func (r *stateRecorder) PrepareAccessList(sender common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	r.db.PrepareAccessList(sender, dest, precompiles, txAccesses)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.PrepareAccessList(sender, dest, precompiles, txAccesses) })
+}
+
+func (r *stateRecorder) AddressInAccessList(addr common.Address) bool {
+	return r.db.AddressInAccessList(addr)
+}
+
+func (r *stateRecorder) SlotInAccessList(addr common.Address, slot common.Hash) (bool, bool) {
+	return r.db.SlotInAccessList(addr, slot)
+}
+
+func (r *stateRecorder) AddAddressToAccessList(addr common.Address) {
+	r.db.AddAddressToAccessList(addr)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddAddressToAccessList(addr) })
+}
+
+func (r *stateRecorder) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	r.db.AddSlotToAccessList(addr, slot)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddSlotToAccessList(addr, slot) })
+}
+
+func (r *stateRecorder) RevertToSnapshot(id int) {
+	r.db.RevertToSnapshot(id)
+	r.record(func(db *state.StateDB, revisions map[int]int) { db.RevertToSnapshot(revisions[id]) })
+}
+
+func (r *stateRecorder) Snapshot() int {
+	id := r.db.Snapshot()
+	r.record(func(db *state.StateDB, revisions map[int]int) { revisions[id] = db.Snapshot() })
+	return id
+}
+
+func (r *stateRecorder) AddLog(log *types.Log) {
+	r.db.AddLog(log)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddLog(log) })
+}
+
+func (r *stateRecorder) AddPreimage(hash common.Hash, preimage []byte) {
+	r.db.AddPreimage(hash, preimage)
+	r.record(func(db *state.StateDB, _ map[int]int) { db.AddPreimage(hash, preimage) })
+}
+
+func (r *stateRecorder) ForEachStorage(addr common.Address, cb func(common.Hash, common.Hash) bool) error {
+	r.reads.addStorage(addr)
+	return r.db.ForEachStorage(addr, cb)
+}
+
+// Ensure the recorder can be handed to the EVM in place of the state database.
+var _ vm.StateDB = (*stateRecorder)(nil)
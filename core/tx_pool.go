@@ -52,6 +52,20 @@ const (
 
 	// txReannoMaxNum is the maximum number of transactions a reannounce action can include.
 	txReannoMaxNum = 1024
+
+	// bundleMaxTxs is the maximum number of transactions a single bundle can contain.
+	bundleMaxTxs = 64
+
+	// bundlePoolSize is the maximum number of bundles the pool keeps track of.
+	bundlePoolSize = 4096
+
+	// bundleMaxFutureBlocks is the maximum distance from the chain head of the
+	// block a bundle may target.
+	bundleMaxFutureBlocks = 25
+
+	// bundleMaxPerSender is the maximum number of bundles the pool keeps track of
+	// per sender of any of the bundled transactions.
+	bundleMaxPerSender = 16
 )
 
 var (
@@ -86,6 +100,30 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrBundleEmpty is returned if a bundle without any transactions is submitted.
+	ErrBundleEmpty = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// the pool is willing to simulate.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleStale is returned if a bundle targets a block which has already
+	// been imported.
+	ErrBundleStale = errors.New("bundle targets a past block")
+
+	// ErrBundleTooFar is returned if a bundle targets a block too far ahead of
+	// the chain head.
+	ErrBundleTooFar = errors.New("bundle targets a block too far in the future")
+
+	// ErrBundleSenderLimit is returned if the sender of a bundle already has the
+	// maximum number of bundles tracked by the pool.
+	ErrBundleSenderLimit = errors.New("too many bundles from sender")
+
+	// ErrBundlePoolFull is returned if the bundle pool can't accept any more
+	// bundles until some of the tracked ones expire, and the bundle doesn't
+	// outrank any of them.
+	ErrBundlePoolFull = errors.New("bundle pool is full")
 )
 
 var (
@@ -119,6 +157,7 @@ var (
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
+	bundleGauge  = metrics.NewRegisteredGauge("txpool/bundles", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -257,6 +296,10 @@ type TxPool struct {
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
+	bundleMu      sync.RWMutex                  // Lock protecting the bundle set, separate from mu to keep the miner off the reorg path
+	bundles       map[common.Hash]*types.Bundle // Atomic transaction bundles keyed by bundle hash
+	bundleSenders map[common.Address]int        // Number of tracked bundles per sender of any of their transactions
+
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -287,6 +330,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		bundles:         make(map[common.Hash]*types.Bundle),
+		bundleSenders:   make(map[common.Address]int),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -847,6 +892,162 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// AddBundle adds an atomic transaction bundle to the pool. Bundles are not
+// propagated to the network, they are kept only until their target block is
+// imported and are handed to the miner through Bundles.
+func (pool *TxPool) AddBundle(bundle *types.Bundle) error {
+	if len(bundle.Txs) == 0 {
+		return ErrBundleEmpty
+	}
+	if len(bundle.Txs) > bundleMaxTxs {
+		return ErrBundleTooLarge
+	}
+	head := pool.chain.CurrentBlock().Number()
+	if bundle.BlockNumber.Cmp(head) <= 0 {
+		return ErrBundleStale
+	}
+	if bundle.BlockNumber.Cmp(new(big.Int).Add(head, big.NewInt(bundleMaxFutureBlocks))) > 0 {
+		return ErrBundleTooFar
+	}
+	for _, tx := range bundle.Txs {
+		if tx.Size() > txMaxSize {
+			return ErrOversizedData
+		}
+		if tx.Value().Sign() < 0 {
+			return ErrNegativeValue
+		}
+		if _, err := types.Sender(pool.signer, tx); err != nil {
+			return ErrInvalidSender
+		}
+	}
+	// Reject the bundles which can't be executed on top of the current state
+	if err := pool.validateBundleState(bundle); err != nil {
+		return err
+	}
+	hash := bundle.Hash()
+	senders := pool.bundleSendersOf(bundle)
+
+	pool.bundleMu.Lock()
+	defer pool.bundleMu.Unlock()
+
+	if _, ok := pool.bundles[hash]; ok {
+		return ErrAlreadyKnown
+	}
+	for _, sender := range senders {
+		if pool.bundleSenders[sender] >= bundleMaxPerSender {
+			return ErrBundleSenderLimit
+		}
+	}
+	if len(pool.bundles) >= bundlePoolSize {
+		// Make room by evicting the bundle targeting the farthest block, or the
+		// cheapest one among those, if the new bundle outranks it
+		var (
+			worstHash common.Hash
+			worst     *types.Bundle
+		)
+		for h, b := range pool.bundles {
+			if worst == nil || bundleOutranks(worst, b) {
+				worstHash, worst = h, b
+			}
+		}
+		if !bundleOutranks(bundle, worst) {
+			return ErrBundlePoolFull
+		}
+		pool.removeBundle(worstHash)
+	}
+	pool.bundles[hash] = bundle
+	for _, sender := range senders {
+		pool.bundleSenders[sender]++
+	}
+	bundleGauge.Update(int64(len(pool.bundles)))
+	return nil
+}
+
+// validateBundleState checks the nonces and balances of the senders of a bundle
+// against the current state. Each transaction must be affordable on its own, as
+// funds moved within the bundle can't be accounted for without executing it.
+func (pool *TxPool) validateBundleState(bundle *types.Bundle) error {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	for _, tx := range bundle.Txs {
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		if pool.currentState.GetNonce(from) > tx.Nonce() {
+			return ErrNonceTooLow
+		}
+		if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+			return ErrInsufficientFunds
+		}
+	}
+	return nil
+}
+
+// bundleSendersOf returns the distinct senders of the transactions of a bundle.
+func (pool *TxPool) bundleSendersOf(bundle *types.Bundle) []common.Address {
+	var (
+		senders []common.Address
+		seen    = make(map[common.Address]bool)
+	)
+	for _, tx := range bundle.Txs {
+		from, _ := types.Sender(pool.signer, tx)
+		if !seen[from] {
+			seen[from] = true
+			senders = append(senders, from)
+		}
+	}
+	return senders
+}
+
+// removeBundle drops a tracked bundle. The caller must hold bundleMu.
+func (pool *TxPool) removeBundle(hash common.Hash) {
+	bundle := pool.bundles[hash]
+	delete(pool.bundles, hash)
+
+	for _, sender := range pool.bundleSendersOf(bundle) {
+		if pool.bundleSenders[sender]--; pool.bundleSenders[sender] <= 0 {
+			delete(pool.bundleSenders, sender)
+		}
+	}
+}
+
+// bundleOutranks reports whether bundle a is preferred over bundle b when the
+// bundle pool is full: bundles targeting nearer blocks are preferred, followed
+// by bundles with a higher minimum gas price.
+func bundleOutranks(a, b *types.Bundle) bool {
+	if cmp := a.BlockNumber.Cmp(b.BlockNumber); cmp != 0 {
+		return cmp < 0
+	}
+	return a.MinGasPrice().Cmp(b.MinGasPrice()) > 0
+}
+
+// Bundles retrieves all the bundles which are valid for the block with the
+// given number and timestamp.
+func (pool *TxPool) Bundles(number *big.Int, timestamp uint64) []*types.Bundle {
+	pool.bundleMu.RLock()
+	defer pool.bundleMu.RUnlock()
+
+	var bundles []*types.Bundle
+	for _, bundle := range pool.bundles {
+		if bundle.ValidAt(number, timestamp) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// pruneBundles drops all the bundles targeting the given block or older ones.
+func (pool *TxPool) pruneBundles(number *big.Int) {
+	pool.bundleMu.Lock()
+	defer pool.bundleMu.Unlock()
+
+	for hash, bundle := range pool.bundles {
+		if bundle.BlockNumber.Cmp(number) <= 0 {
+			pool.removeBundle(hash)
+		}
+	}
+	bundleGauge.Update(int64(len(pool.bundles)))
+}
+
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
@@ -1245,6 +1446,9 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Bundles are only valid for a single block, drop the ones that missed it
+	pool.pruneBundles(newHead.Number)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -1974,6 +1974,103 @@ func TestTransactionPendingReannouce(t *testing.T) {
 	}
 }
 
+// bundleTestChain is a test blockchain with a head block at a given number.
+type bundleTestChain struct {
+	*testBlockChain
+	number int64
+}
+
+func (bc *bundleTestChain) CurrentBlock() *types.Block {
+	return types.NewBlock(&types.Header{
+		Number:   big.NewInt(bc.number),
+		GasLimit: bc.gasLimit,
+	}, nil, nil, nil, trie.NewStackTrie(nil))
+}
+
+// Tests that the bundle pool bounds the targets of the bundles and the bundles
+// per sender, rejects bundles not executable on the current state, and that it
+// makes room for better bundles when full.
+func TestBundlePoolLimits(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &bundleTestChain{&testBlockChain{statedb, 10000000, new(event.Feed)}, 100}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	bundle := func(target int64, price int64, key *ecdsa.PrivateKey, nonce uint64) *types.Bundle {
+		return &types.Bundle{
+			Txs:         types.Transactions{pricedTransaction(nonce, 100000, big.NewInt(price), key)},
+			BlockNumber: big.NewInt(target),
+		}
+	}
+	fund := func(key *ecdsa.PrivateKey) {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+	}
+	key, _ := crypto.GenerateKey()
+	fund(key)
+
+	if err := pool.AddBundle(bundle(100, 1, key, 0)); err != ErrBundleStale {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, ErrBundleStale)
+	}
+	if err := pool.AddBundle(bundle(100+bundleMaxFutureBlocks+1, 1, key, 0)); err != ErrBundleTooFar {
+		t.Fatalf("far bundle error mismatch: have %v, want %v", err, ErrBundleTooFar)
+	}
+	// Bundles with transactions not executable on the current state are rejected
+	poor, _ := crypto.GenerateKey()
+	if err := pool.AddBundle(bundle(110, 1, poor, 0)); err != ErrInsufficientFunds {
+		t.Fatalf("unfunded bundle error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	fund(poor)
+	pool.currentState.SetNonce(crypto.PubkeyToAddress(poor.PublicKey), 2)
+	if err := pool.AddBundle(bundle(110, 1, poor, 1)); err != ErrNonceTooLow {
+		t.Fatalf("stale nonce bundle error mismatch: have %v, want %v", err, ErrNonceTooLow)
+	}
+	// Fill the pool up with bundles from many senders, each up to its limit
+	for i := 0; i < bundlePoolSize/bundleMaxPerSender; i++ {
+		key, _ := crypto.GenerateKey()
+		fund(key)
+		for j := 0; j < bundleMaxPerSender; j++ {
+			if err := pool.AddBundle(bundle(110, 1, key, uint64(j))); err != nil {
+				t.Fatalf("bundle %d/%d: failed to add: %v", i, j, err)
+			}
+		}
+		if i == 0 {
+			if err := pool.AddBundle(bundle(110, 1, key, bundleMaxPerSender)); err != ErrBundleSenderLimit {
+				t.Fatalf("sender limit error mismatch: have %v, want %v", err, ErrBundleSenderLimit)
+			}
+			// The limit applies to the senders of all the bundled transactions
+			mixed := bundle(110, 1, poor, 2)
+			mixed.Txs = append(mixed.Txs, pricedTransaction(bundleMaxPerSender, 100000, big.NewInt(1), key))
+			if err := pool.AddBundle(mixed); err != ErrBundleSenderLimit {
+				t.Fatalf("trailing sender limit error mismatch: have %v, want %v", err, ErrBundleSenderLimit)
+			}
+		}
+	}
+	// Bundles not outranking any tracked one are rejected, others evict the worst
+	if err := pool.AddBundle(bundle(120, 10, key, 0)); err != ErrBundlePoolFull {
+		t.Fatalf("full pool error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.AddBundle(bundle(110, 1, key, 1)); err != ErrBundlePoolFull {
+		t.Fatalf("full pool error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.AddBundle(bundle(105, 1, key, 2)); err != nil {
+		t.Fatalf("failed to add nearer bundle: %v", err)
+	}
+	if err := pool.AddBundle(bundle(110, 2, key, 3)); err != nil {
+		t.Fatalf("failed to add pricier bundle: %v", err)
+	}
+	if have := len(pool.Bundles(big.NewInt(110), 0)); have != bundlePoolSize-1 {
+		t.Fatalf("bundles at target mismatch: have %d, want %d", have, bundlePoolSize-1)
+	}
+	// Pruning bundles releases the sender allowances
+	pool.pruneBundles(big.NewInt(110))
+	if len(pool.bundles) != 0 || len(pool.bundleSenders) != 0 {
+		t.Fatalf("bundles left after pruning: %d bundles, %d senders", len(pool.bundles), len(pool.bundleSenders))
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -380,6 +380,11 @@ type DiffLayer struct {
 	Accounts  []DiffAccount
 	Storages  []DiffStorage
 
+	// Signature is the signature of the block producer over the diff layer
+	// hash, bound to the block hash and state root. It is empty for layers
+	// produced locally by non-validators or received from legacy peers.
+	Signature []byte
+
 	DiffHash common.Hash
 }
 
@@ -391,6 +396,7 @@ type extDiffLayer struct {
 	Destructs []common.Address
 	Accounts  []DiffAccount
 	Storages  []DiffStorage
+	Signature []byte `rlp:"optional"`
 }
 
 // DecodeRLP decodes the Ethereum
@@ -399,8 +405,8 @@ func (d *DiffLayer) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&ed); err != nil {
 		return err
 	}
-	d.BlockHash, d.Number, d.Codes, d.Destructs, d.Accounts, d.Storages =
-		ed.BlockHash, ed.Number, ed.Codes, ed.Destructs, ed.Accounts, ed.Storages
+	d.BlockHash, d.Number, d.Codes, d.Destructs, d.Accounts, d.Storages, d.Signature =
+		ed.BlockHash, ed.Number, ed.Codes, ed.Destructs, ed.Accounts, ed.Storages, ed.Signature
 
 	d.Receipts = make([]*Receipt, len(ed.Receipts))
 	for i, storageReceipt := range ed.Receipts {
@@ -423,9 +429,28 @@ func (d *DiffLayer) EncodeRLP(w io.Writer) error {
 		Destructs: d.Destructs,
 		Accounts:  d.Accounts,
 		Storages:  d.Storages,
+		Signature: d.Signature,
 	})
 }
 
+// Hash returns the keccak256 hash of the RLP encoding of the diff layer,
+// excluding its signature.
+func (d *DiffLayer) Hash() common.Hash {
+	unsigned := *d
+	unsigned.Signature = nil
+	return rlpHash(&unsigned)
+}
+
+// DiffLayerSigData returns the data signed by the block producer to vouch for
+// the diff layer of a block, binding the layer to the block and its state root.
+func DiffLayerSigData(blockHash common.Hash, root common.Hash, diffHash common.Hash) []byte {
+	data := make([]byte, 0, 4+3*common.HashLength)
+	data = append(data, "diff"...)
+	data = append(data, blockHash[:]...)
+	data = append(data, root[:]...)
+	return append(data, diffHash[:]...)
+}
+
 func (d *DiffLayer) Validate() error {
 	if d.BlockHash == (common.Hash{}) {
 		return errors.New("blockHash can't be empty")
@@ -0,0 +1,90 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/sha3"
+)
+
+// Bundle is an ordered list of signed transactions which has to be included
+// atomically at the top of the block with the given number, or not at all.
+type Bundle struct {
+	Txs          Transactions
+	BlockNumber  *big.Int
+	MinTimestamp uint64 // Earliest block timestamp the bundle is valid for, 0 if unbounded
+	MaxTimestamp uint64 // Latest block timestamp the bundle is valid for, 0 if unbounded
+}
+
+// Hash returns the identifier of the bundle, which is the keccak256 hash of
+// the concatenated transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, tx := range b.Txs {
+		hasher.Write(tx.Hash().Bytes())
+	}
+	var h common.Hash
+	hasher.Sum(h[:0])
+	return h
+}
+
+// ValidAt reports whether the bundle targets the block with the given number
+// and timestamp.
+func (b *Bundle) ValidAt(number *big.Int, timestamp uint64) bool {
+	if b.BlockNumber.Cmp(number) != 0 {
+		return false
+	}
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// MinGasPrice returns the lowest gas price of the transactions of the bundle.
+func (b *Bundle) MinGasPrice() *big.Int {
+	price := b.Txs[0].GasPrice()
+	for _, tx := range b.Txs[1:] {
+		if tx.GasPrice().Cmp(price) < 0 {
+			price = tx.GasPrice()
+		}
+	}
+	return price
+}
+
+// SimulatedBundle is a bundle which has been executed against the pending
+// state, along with the figures the miner uses to rank it.
+type SimulatedBundle struct {
+	Bundle         *Bundle
+	GasUsed        uint64
+	TotalFees      *big.Int // Fees and direct payments received by the block producer
+	EffectivePrice *big.Int // TotalFees divided by GasUsed
+}
+
+// BundlesByPrice implements the sort interface, allowing simulated
+// bundles to be sorted by effective gas price in descending order.
+type BundlesByPrice []*SimulatedBundle
+
+func (s BundlesByPrice) Len() int { return len(s) }
+func (s BundlesByPrice) Less(i, j int) bool {
+	return s[i].EffectivePrice.Cmp(s[j].EffectivePrice) > 0
+}
+func (s BundlesByPrice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
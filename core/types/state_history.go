@@ -0,0 +1,110 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StateHistory is the reverse state diff of a block: the values of all the
+// accounts and storage slots modified by the block, as they were before it.
+// Applying the histories of the blocks above a state in descending order to
+// that later state yields the earlier one.
+//
+// Accounts and storage slots are keyed by hash and hold values in the snapshot
+// slim format, empty values meaning nonexistence. The entries are sorted by
+// key, allowing lookups without decoding them into maps.
+type StateHistory struct {
+	Accounts []HistoryAccount
+	Storages []HistoryStorage
+}
+
+// HistoryAccount is the value of an account before a block modified it.
+type HistoryAccount struct {
+	Account common.Hash
+	Blob    []byte
+}
+
+// HistoryStorage is the value of the storage slots of an account before a block
+// modified them. If the account was destructed by the block, its whole storage
+// is recorded and the storage is marked as wiped.
+type HistoryStorage struct {
+	Account common.Hash
+	Wiped   bool
+	Keys    []common.Hash
+	Vals    [][]byte
+}
+
+// Sort orders the entries of the state history by key.
+func (h *StateHistory) Sort() {
+	sort.Slice(h.Accounts, func(i, j int) bool {
+		return bytes.Compare(h.Accounts[i].Account[:], h.Accounts[j].Account[:]) < 0
+	})
+	sort.Slice(h.Storages, func(i, j int) bool {
+		return bytes.Compare(h.Storages[i].Account[:], h.Storages[j].Account[:]) < 0
+	})
+	for i := range h.Storages {
+		sort.Sort(historySlots(h.Storages[i]))
+	}
+}
+
+// Account retrieves the value of an account before the block. The boolean flag
+// reports whether the account was modified by the block at all.
+func (h *StateHistory) Account(hash common.Hash) ([]byte, bool) {
+	i := sort.Search(len(h.Accounts), func(i int) bool {
+		return bytes.Compare(h.Accounts[i].Account[:], hash[:]) >= 0
+	})
+	if i < len(h.Accounts) && h.Accounts[i].Account == hash {
+		return h.Accounts[i].Blob, true
+	}
+	return nil, false
+}
+
+// Storage retrieves the value of a storage slot before the block. The boolean
+// flag reports whether the value is determined by the history, which is the case
+// for the slots modified by the block and for all the slots of wiped storages.
+func (h *StateHistory) Storage(account common.Hash, slot common.Hash) ([]byte, bool) {
+	i := sort.Search(len(h.Storages), func(i int) bool {
+		return bytes.Compare(h.Storages[i].Account[:], account[:]) >= 0
+	})
+	if i == len(h.Storages) || h.Storages[i].Account != account {
+		return nil, false
+	}
+	storage := h.Storages[i]
+	j := sort.Search(len(storage.Keys), func(j int) bool {
+		return bytes.Compare(storage.Keys[j][:], slot[:]) >= 0
+	})
+	if j < len(storage.Keys) && storage.Keys[j] == slot {
+		return storage.Vals[j], true
+	}
+	return nil, storage.Wiped
+}
+
+// historySlots implements sort.Interface to order the slots of a storage.
+type historySlots HistoryStorage
+
+func (s historySlots) Len() int { return len(s.Keys) }
+func (s historySlots) Less(i, j int) bool {
+	return bytes.Compare(s.Keys[i][:], s.Keys[j][:]) < 0
+}
+func (s historySlots) Swap(i, j int) {
+	s.Keys[i], s.Keys[j] = s.Keys[j], s.Keys[i]
+	s.Vals[i], s.Vals[j] = s.Vals[j], s.Vals[i]
+}
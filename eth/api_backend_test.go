@@ -0,0 +1,141 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// prunedDatabase is a database hiding the ancient items below a tail, as the
+// freezer only discards whole data files.
+type prunedDatabase struct {
+	ethdb.Database
+	tail uint64
+}
+
+func (db *prunedDatabase) AncientOffSet() uint64 { return db.tail }
+
+func (db *prunedDatabase) HasAncient(kind string, number uint64) (bool, error) {
+	if number < db.tail {
+		return false, nil
+	}
+	return db.Database.HasAncient(kind, number)
+}
+
+func (db *prunedDatabase) Ancient(kind string, number uint64) ([]byte, error) {
+	if number < db.tail {
+		return nil, errors.New("pruned")
+	}
+	return db.Database.Ancient(kind, number)
+}
+
+// Tests that the blocks discarded by the history pruning are reported as such
+// through the API, while the retained and the unknown ones are not.
+func TestHistoryPrunedAPI(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	ancientDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "", false, false, false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer ancientDb.Close()
+
+	var (
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(ancientDb)
+	)
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 64, nil)
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	chain, _ := core.NewBlockChain(ancientDb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if n, err := chain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, uint64(len(blocks))); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	chain.Stop()
+
+	// Reopen the chain with the history below block 32 pruned
+	db := &prunedDatabase{Database: ancientDb, tail: 32}
+	chain, _ = core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	var (
+		ctx     = context.Background()
+		backend = &EthAPIBackend{eth: &Ethereum{chainDb: db, blockchain: chain}}
+	)
+	for _, block := range blocks {
+		var (
+			number = rpc.BlockNumber(block.NumberU64())
+			hash   = block.Hash()
+			want   error
+		)
+		if number < 32 {
+			want = core.ErrHistoryPruned
+		}
+		if _, err := backend.HeaderByNumber(ctx, number); err != want {
+			t.Errorf("block %d: HeaderByNumber error mismatch: have %v, want %v", number, err, want)
+		}
+		if _, err := backend.HeaderByHash(ctx, hash); err != want {
+			t.Errorf("block %d: HeaderByHash error mismatch: have %v, want %v", number, err, want)
+		}
+		if _, err := backend.BlockByNumber(ctx, number); err != want {
+			t.Errorf("block %d: BlockByNumber error mismatch: have %v, want %v", number, err, want)
+		}
+		if _, err := backend.BlockByHash(ctx, hash); err != want {
+			t.Errorf("block %d: BlockByHash error mismatch: have %v, want %v", number, err, want)
+		}
+		if _, err := backend.BlockByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(hash, false)); err != want {
+			t.Errorf("block %d: BlockByNumberOrHash error mismatch: have %v, want %v", number, err, want)
+		}
+		receipts, err := backend.GetReceipts(ctx, hash)
+		if err != want {
+			t.Errorf("block %d: GetReceipts error mismatch: have %v, want %v", number, err, want)
+		}
+		if want == nil && receipts == nil {
+			t.Errorf("block %d: retained receipts missing", number)
+		}
+	}
+	// Unknown blocks are not reported as pruned
+	if header, err := backend.HeaderByHash(ctx, common.Hash{0x01}); header != nil || err != nil {
+		t.Errorf("unknown block: have %v, %v, want nil, nil", header, err)
+	}
+	if block, err := backend.BlockByNumber(ctx, 100); block != nil || err != nil {
+		t.Errorf("unknown block: have %v, %v, want nil, nil", block, err)
+	}
+}
@@ -83,6 +83,7 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer        *core.ChainIndexer             // Address/topic log indexer operating during block imports, nil if disabled
 	closeBloomHandler chan struct{}
 
 	APIBackend *EthAPIBackend
@@ -109,6 +110,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.LogIndex && config.BlockHistory != 0 {
+		return nil, fmt.Errorf("%w, can't prune the block history", core.ErrLogIndexHistoryPruned)
+	}
 	if config.Miner.GasPrice == nil || config.Miner.GasPrice.Cmp(common.Big0) <= 0 {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", ethconfig.Defaults.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(ethconfig.Defaults.Miner.GasPrice)
@@ -126,10 +130,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 
 	// Assemble the Ethereum object
 	chainDb, err := stack.OpenAndMergeDatabase("chaindata", config.DatabaseCache, config.DatabaseHandles,
-		config.DatabaseFreezer, config.DatabaseDiff, "eth/db/chaindata/", false, config.PersistDiff)
+		config.DatabaseFreezer, config.DatabaseDiff, "eth/db/chaindata/", false, config.PersistDiff, config.PersistDiff && config.FreezeDiff)
 	if err != nil {
 		return nil, err
 	}
+	if tail := chainDb.AncientOffSet(); config.LogIndex && tail > 0 {
+		return nil, fmt.Errorf("%w, blocks below #%d are pruned", core.ErrLogIndexHistoryPruned, tail)
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideBerlin)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -192,18 +199,35 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:      config.TrieTimeout,
 			SnapshotLimit:      config.SnapshotCache,
 			TriesInMemory:      config.TriesInMemory,
+			StateRetention:     config.StateRetention,
+			StateHistory:       config.StateHistory,
 			Preimages:          config.Preimages,
 		}
 	)
 	bcOps := make([]core.BlockChainOption, 0)
 	if config.DiffSync {
 		bcOps = append(bcOps, core.EnableLightProcessor)
+		if config.DiffSyncSample > 0 {
+			bcOps = append(bcOps, core.EnableDiffSampling(config.DiffSyncSample))
+		}
+		if config.DiffSyncUnsigned {
+			bcOps = append(bcOps, core.EnableUnsignedDiffLayers)
+		}
 	}
 	if config.PipeCommit {
 		bcOps = append(bcOps, core.EnablePipelineCommit)
 	}
 	if config.PersistDiff {
 		bcOps = append(bcOps, core.EnablePersistDiff(config.DiffBlock))
+		if config.FreezeDiff {
+			bcOps = append(bcOps, core.EnableFreezeDiff)
+		}
+	}
+	if config.ParallelTxs {
+		bcOps = append(bcOps, core.EnableParallelProcessor(config.ParallelTxNum))
+	}
+	if config.BlockHistory != 0 {
+		bcOps = append(bcOps, core.EnableHistoryPruning(config.BlockHistory))
 	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit, bcOps...)
 	if err != nil {
@@ -216,6 +240,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = core.NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		eth.logIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -238,6 +266,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		Whitelist:              config.Whitelist,
 		DirectBroadcast:        config.DirectBroadcast,
 		DiffSync:               config.DiffSync,
+		DiffSyncHorizon:        config.DiffSyncHorizon,
 		DisablePeerTxBroadcast: config.DisablePeerTxBroadcast,
 	}); err != nil {
 		return nil, err
@@ -336,7 +365,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.RangeLimit),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.RangeLimit, filters.LogLimits{BlockRange: s.config.RPCLogBlockRange, Results: s.config.RPCLogResultCap}),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -580,6 +609,9 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Stop()
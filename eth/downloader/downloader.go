@@ -56,6 +56,9 @@ var (
 	diffFetchTick  = 10 * time.Millisecond
 	diffFetchLimit = 5
 
+	maxDiffCatchUpFetch = 128             // Amount of diff layers to request and import at once during catch-up
+	diffCatchUpTimeout  = 5 * time.Second // Maximum time to wait for a batch of diff layers during catch-up
+
 	qosTuningPeers   = 5    // Number of peers to tune based on (best peers)
 	qosConfidenceCap = 10   // Number of peers above which not to modify RTT confidence
 	qosTuningImpact  = 0.25 // Impact that a new tuning target has on the previous value
@@ -168,6 +171,11 @@ type Downloader struct {
 	bodyFetchHook    func([]*types.Header)               // Method to call upon starting a block body fetch
 	receiptFetchHook func([]*types.Header)               // Method to call upon starting a receipt fetch
 	chainInsertHook  func([]*fetchResult, chan struct{}) // Method to call upon inserting a chain of blocks (possibly in multiple invocations)
+
+	// Diff layer catch-up
+	diffChain   DiffBlockChain // Chain to import blocks beyond the trust horizon into by their diff layers
+	diffPeers   IPeerSet       // Set of peers to request the diff layers from
+	diffHorizon time.Duration  // Age beyond which blocks are imported without execution
 }
 
 // LightChain encapsulates functions required to synchronise a light chain.
@@ -223,6 +231,16 @@ type BlockChain interface {
 	Snapshots() *snapshot.Tree
 }
 
+// DiffBlockChain encapsulates functions required to import a blockchain by
+// applying diff layers instead of executing the blocks.
+type DiffBlockChain interface {
+	// GetUnTrustedDiffLayer retrieves a diff layer delivered by the given peer.
+	GetUnTrustedDiffLayer(common.Hash, string) *types.DiffLayer
+
+	// InsertChainWithDiffs inserts a batch of blocks by applying their diff layers.
+	InsertChainWithDiffs(types.Blocks, []*types.DiffLayer) (int, error)
+}
+
 type DownloadOption func(downloader *Downloader) *Downloader
 
 type IDiffPeer interface {
@@ -268,6 +286,20 @@ func EnableDiffFetchOp(peers IPeerSet) DownloadOption {
 	}
 }
 
+// EnableDiffCatchUp makes the full sync import the blocks older than horizon by
+// fetching their diff layers in batches and applying them in bulk, instead of
+// executing the blocks one by one.
+func EnableDiffCatchUp(peers IPeerSet, horizon time.Duration) DownloadOption {
+	return func(dl *Downloader) *Downloader {
+		if chain, ok := dl.blockchain.(DiffBlockChain); ok && horizon > 0 {
+			dl.diffChain = chain
+			dl.diffPeers = peers
+			dl.diffHorizon = horizon
+		}
+		return dl
+	}
+}
+
 // New creates a new downloader to fetch hashes and blocks from remote peers.
 func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn, options ...DownloadOption) *Downloader {
 	if lightchain == nil {
@@ -1770,6 +1802,15 @@ func (d *Downloader) processFullSyncContent() error {
 		if len(results) == 0 {
 			return nil
 		}
+		if d.diffChain != nil {
+			var err error
+			if results, err = d.importDiffResults(results); err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				continue
+			}
+		}
 		stop := make(chan struct{})
 		if d.chainInsertHook != nil {
 			d.chainInsertHook(results, stop)
@@ -1817,6 +1858,108 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 	return nil
 }
 
+// importDiffResults imports the leading results older than the trust horizon by
+// applying their diff layers in bulk, and returns the results left for regular
+// import. Blocks without a usable diff layer are imported by execution.
+func (d *Downloader) importDiffResults(results []*fetchResult) ([]*fetchResult, error) {
+	horizon := uint64(time.Now().Add(-d.diffHorizon).Unix())
+	for len(results) > 0 && results[0].Header.Time < horizon {
+		// Gather the next batch of blocks beyond the horizon
+		batch := results
+		if len(batch) > maxDiffCatchUpFetch {
+			batch = batch[:maxDiffCatchUpFetch]
+		}
+		for i, result := range batch {
+			if result.Header.Time >= horizon {
+				batch = batch[:i]
+				break
+			}
+		}
+		results = results[len(batch):]
+
+		diffs := d.fetchDiffLayers(batch)
+		for len(batch) > 0 {
+			select {
+			case <-d.quitCh:
+				return nil, errCancelContentProcessing
+			default:
+			}
+			// Apply the diff layers of as many leading blocks as possible
+			n := 0
+			for n < len(batch) && diffs[n] != nil {
+				n++
+			}
+			if n > 0 {
+				blocks := make([]*types.Block, n)
+				for i, result := range batch[:n] {
+					blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
+				}
+				if index, err := d.diffChain.InsertChainWithDiffs(blocks, diffs[:n]); err != nil {
+					log.Debug("Diff layer import failed, falling back to execution", "number", batch[index].Header.Number, "hash", batch[index].Header.Hash(), "err", err)
+					n = index
+				}
+			}
+			// Execute the first block the diff layer import stopped at
+			if n == 0 {
+				if err := d.importBlockResults(batch[:1]); err != nil {
+					return nil, err
+				}
+				n = 1
+			}
+			batch, diffs = batch[n:], diffs[n:]
+		}
+	}
+	return results, nil
+}
+
+// fetchDiffLayers requests the diff layers of a batch of blocks from the peers
+// that delivered their bodies and waits until they arrive. Entries that didn't
+// arrive in time, or belong to blocks without transactions, are left nil.
+func (d *Downloader) fetchDiffLayers(results []*fetchResult) []*types.DiffLayer {
+	requests := make(map[string][]common.Hash)
+	for _, result := range results {
+		if len(result.Transactions) > 0 {
+			requests[result.pid] = append(requests[result.pid], result.Header.Hash())
+		}
+	}
+	for pid, hashes := range requests {
+		if peer := d.diffPeers.GetDiffPeer(pid); peer != nil {
+			if err := peer.RequestDiffLayers(hashes); err != nil {
+				log.Debug("Failed to request diff layers", "peer", pid, "err", err)
+			}
+		}
+	}
+	diffs := make([]*types.DiffLayer, len(results))
+
+	ticker := time.NewTicker(diffFetchTick)
+	defer ticker.Stop()
+	timeout := time.NewTimer(diffCatchUpTimeout)
+	defer timeout.Stop()
+
+	for {
+		missing := 0
+		for i, result := range results {
+			if diffs[i] != nil || len(result.Transactions) == 0 {
+				continue
+			}
+			if diffs[i] = d.diffChain.GetUnTrustedDiffLayer(result.Header.Hash(), result.pid); diffs[i] == nil {
+				missing++
+			}
+		}
+		if missing == 0 {
+			return diffs
+		}
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			log.Debug("Timed out waiting for diff layers", "missing", missing, "count", len(results))
+			return diffs
+		case <-d.quitCh:
+			return diffs
+		}
+	}
+}
+
 // processFastSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processFastSyncContent() error {
@@ -99,12 +99,18 @@ type Config struct {
 
 	NoPruning           bool // Whether to disable pruning and flush everything to disk
 	DirectBroadcast     bool
-	DisableSnapProtocol bool //Whether disable snap protocol
-	DiffSync            bool // Whether support diff sync
+	DisableSnapProtocol bool          //Whether disable snap protocol
+	DiffSync            bool          // Whether support diff sync
+	DiffSyncSample      float64       // Fraction of diff synced blocks fully executed, 0 for the default
+	DiffSyncHorizon     time.Duration // Age beyond which blocks are imported by applying diff layers, 0 to disable
+	DiffSyncUnsigned    bool          // Whether unsigned diff layers are accepted
 	PipeCommit          bool
+	ParallelTxs         bool // Whether to execute block transactions in parallel
+	ParallelTxNum       int  // Number of parallel transaction workers, 0 for the number of CPUs
 	RangeLimit          bool
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	BlockHistory  uint64 `toml:",omitempty"` // The number of recent blocks retained in the ancient store, 0 to retain all.
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
@@ -131,6 +137,7 @@ type Config struct {
 	DatabaseDiff       string
 	PersistDiff        bool
 	DiffBlock          uint64
+	FreezeDiff         bool // Whether to freeze persisted diff layers into the ancient store
 
 	TrieCleanCache          int
 	TrieCleanCacheJournal   string        `toml:",omitempty"` // Disk journal directory for trie cache to survive node restarts
@@ -139,6 +146,10 @@ type Config struct {
 	TrieTimeout             time.Duration
 	SnapshotCache           int
 	TriesInMemory           uint64
+	StateRetention          uint64 `toml:",omitempty"` // Number of blocks whose tries are kept on disk, 0 to disable online state pruning
+	StateHistory            bool   `toml:",omitempty"` // Whether to persist reverse state diffs to serve historical states
+	TraceIndex              bool   `toml:",omitempty"` // Whether to index the addresses of internal calls for trace_filter
+	LogIndex                bool   `toml:",omitempty"` // Whether to index the blocks by log address and topics for log filtering
 	Preimages               bool
 
 	// Mining options
@@ -169,6 +180,14 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCLogBlockRange is the maximum number of blocks a log query may span,
+	// longer ranges are rejected or paginated. Zero means no limit.
+	RPCLogBlockRange uint64 `toml:",omitempty"`
+
+	// RPCLogResultCap is the maximum number of logs a log query may return,
+	// further logs are rejected or paginated. Zero means no limit.
+	RPCLogResultCap uint64 `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
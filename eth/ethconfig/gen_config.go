@@ -3,6 +3,7 @@
 package ethconfig
 
 import (
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -23,8 +24,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               bool
-		NoPrefetch              bool
+		DirectBroadcast         bool
+		DisableSnapProtocol     bool
+		DiffSync                bool
+		DiffSyncSample          float64
+		DiffSyncHorizon         time.Duration
+		DiffSyncUnsigned        bool
+		PipeCommit              bool
+		ParallelTxs             bool
+		ParallelTxNum           int
+		RangeLimit              bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		BlockHistory            uint64                 `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -41,16 +52,21 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseCache           int
 		DatabaseFreezer         string
 		DatabaseDiff            string
+		PersistDiff             bool
+		DiffBlock               uint64
+		FreezeDiff              bool
 		TrieCleanCache          int
 		TrieCleanCacheJournal   string        `toml:",omitempty"`
 		TrieCleanCacheRejournal time.Duration `toml:",omitempty"`
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
-		TriesInMemory           uint64 `toml:",omitempty"`
 		SnapshotCache           int
+		TriesInMemory           uint64
+		StateRetention          uint64 `toml:",omitempty"`
+		StateHistory            bool   `toml:",omitempty"`
+		TraceIndex              bool   `toml:",omitempty"`
+		LogIndex                bool   `toml:",omitempty"`
 		Preimages               bool
-		PersistDiff             bool
-		DiffBlock               uint64 `toml:",omitempty"`
 		Miner                   miner.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -58,10 +74,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
 		EVMInterpreter          string
-		RPCGasCap               uint64                         `toml:",omitempty"`
-		RPCTxFeeCap             float64                        `toml:",omitempty"`
+		RPCGasCap               uint64
+		RPCTxFeeCap             float64
+		RPCLogBlockRange        uint64                         `toml:",omitempty"`
+		RPCLogResultCap         uint64                         `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideBerlin          *big.Int                       `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -71,7 +90,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
+	enc.DirectBroadcast = c.DirectBroadcast
+	enc.DisableSnapProtocol = c.DisableSnapProtocol
+	enc.DiffSync = c.DiffSync
+	enc.DiffSyncSample = c.DiffSyncSample
+	enc.DiffSyncHorizon = c.DiffSyncHorizon
+	enc.DiffSyncUnsigned = c.DiffSyncUnsigned
+	enc.PipeCommit = c.PipeCommit
+	enc.ParallelTxs = c.ParallelTxs
+	enc.ParallelTxNum = c.ParallelTxNum
+	enc.RangeLimit = c.RangeLimit
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.BlockHistory = c.BlockHistory
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -88,16 +118,21 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
 	enc.DatabaseDiff = c.DatabaseDiff
+	enc.PersistDiff = c.PersistDiff
+	enc.DiffBlock = c.DiffBlock
+	enc.FreezeDiff = c.FreezeDiff
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieCleanCacheJournal = c.TrieCleanCacheJournal
 	enc.TrieCleanCacheRejournal = c.TrieCleanCacheRejournal
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
-	enc.TriesInMemory = c.TriesInMemory
 	enc.SnapshotCache = c.SnapshotCache
+	enc.TriesInMemory = c.TriesInMemory
+	enc.StateRetention = c.StateRetention
+	enc.StateHistory = c.StateHistory
+	enc.TraceIndex = c.TraceIndex
+	enc.LogIndex = c.LogIndex
 	enc.Preimages = c.Preimages
-	enc.PersistDiff = c.PersistDiff
-	enc.DiffBlock = c.DiffBlock
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -107,8 +142,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EVMInterpreter = c.EVMInterpreter
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCLogBlockRange = c.RPCLogBlockRange
+	enc.RPCLogResultCap = c.RPCLogResultCap
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
+	enc.OverrideBerlin = c.OverrideBerlin
 	return &enc, nil
 }
 
@@ -122,8 +160,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               *bool
-		NoPrefetch              *bool
+		DirectBroadcast         *bool
+		DisableSnapProtocol     *bool
+		DiffSync                *bool
+		DiffSyncSample          *float64
+		DiffSyncHorizon         *time.Duration
+		DiffSyncUnsigned        *bool
+		PipeCommit              *bool
+		ParallelTxs             *bool
+		ParallelTxNum           *int
+		RangeLimit              *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		BlockHistory            *uint64                `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -141,14 +189,19 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseFreezer         *string
 		DatabaseDiff            *string
 		PersistDiff             *bool
-		DiffBlock               *uint64 `toml:",omitempty"`
+		DiffBlock               *uint64
+		FreezeDiff              *bool
 		TrieCleanCache          *int
 		TrieCleanCacheJournal   *string        `toml:",omitempty"`
 		TrieCleanCacheRejournal *time.Duration `toml:",omitempty"`
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
-		TriesInMemory           *uint64 `toml:",omitempty"`
 		SnapshotCache           *int
+		TriesInMemory           *uint64
+		StateRetention          *uint64 `toml:",omitempty"`
+		StateHistory            *bool   `toml:",omitempty"`
+		TraceIndex              *bool   `toml:",omitempty"`
+		LogIndex                *bool   `toml:",omitempty"`
 		Preimages               *bool
 		Miner                   *miner.Config
 		TxPool                  *core.TxPoolConfig
@@ -157,10 +210,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
-		RPCGasCap               *uint64                        `toml:",omitempty"`
-		RPCTxFeeCap             *float64                       `toml:",omitempty"`
+		RPCGasCap               *uint64
+		RPCTxFeeCap             *float64
+		RPCLogBlockRange        *uint64                        `toml:",omitempty"`
+		RPCLogResultCap         *uint64                        `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
+		OverrideBerlin          *big.Int                       `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -187,9 +243,42 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.DirectBroadcast != nil {
+		c.DirectBroadcast = *dec.DirectBroadcast
+	}
+	if dec.DisableSnapProtocol != nil {
+		c.DisableSnapProtocol = *dec.DisableSnapProtocol
+	}
+	if dec.DiffSync != nil {
+		c.DiffSync = *dec.DiffSync
+	}
+	if dec.DiffSyncSample != nil {
+		c.DiffSyncSample = *dec.DiffSyncSample
+	}
+	if dec.DiffSyncHorizon != nil {
+		c.DiffSyncHorizon = *dec.DiffSyncHorizon
+	}
+	if dec.DiffSyncUnsigned != nil {
+		c.DiffSyncUnsigned = *dec.DiffSyncUnsigned
+	}
+	if dec.PipeCommit != nil {
+		c.PipeCommit = *dec.PipeCommit
+	}
+	if dec.ParallelTxs != nil {
+		c.ParallelTxs = *dec.ParallelTxs
+	}
+	if dec.ParallelTxNum != nil {
+		c.ParallelTxNum = *dec.ParallelTxNum
+	}
+	if dec.RangeLimit != nil {
+		c.RangeLimit = *dec.RangeLimit
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.BlockHistory != nil {
+		c.BlockHistory = *dec.BlockHistory
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
@@ -244,6 +333,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DiffBlock != nil {
 		c.DiffBlock = *dec.DiffBlock
 	}
+	if dec.FreezeDiff != nil {
+		c.FreezeDiff = *dec.FreezeDiff
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -259,11 +351,23 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
 	if dec.TriesInMemory != nil {
 		c.TriesInMemory = *dec.TriesInMemory
 	}
-	if dec.SnapshotCache != nil {
-		c.SnapshotCache = *dec.SnapshotCache
+	if dec.StateRetention != nil {
+		c.StateRetention = *dec.StateRetention
+	}
+	if dec.StateHistory != nil {
+		c.StateHistory = *dec.StateHistory
+	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
 	}
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
@@ -295,11 +399,20 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCLogBlockRange != nil {
+		c.RPCLogBlockRange = *dec.RPCLogBlockRange
+	}
+	if dec.RPCLogResultCap != nil {
+		c.RPCLogResultCap = *dec.RPCLogResultCap
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
 	if dec.CheckpointOracle != nil {
 		c.CheckpointOracle = dec.CheckpointOracle
 	}
+	if dec.OverrideBerlin != nil {
+		c.OverrideBerlin = dec.OverrideBerlin
+	}
 	return nil
 }
@@ -46,6 +46,48 @@ type filter struct {
 	s        *Subscription // associated subscription in event system
 }
 
+// errcodeLimitExceeded is the JSON-RPC error code of log queries exceeding the
+// configured limits, as defined by EIP-1474.
+const errcodeLimitExceeded = -32005
+
+// LogLimits bounds the work done by a single log query.
+type LogLimits struct {
+	BlockRange uint64 // Maximum number of blocks a range query may span, 0 = unlimited
+	Results    uint64 // Maximum number of logs a query may return, 0 = unlimited
+}
+
+// LogCursor is the position of a log within the chain, from where a log query
+// can be resumed.
+type LogCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// LogsPage is a page of the logs matching a query, along with the cursor to get
+// the next page with. The cursor is nil if no more logs match the query.
+type LogsPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *LogCursor   `json:"cursor"`
+}
+
+// limitExceededError is returned if a log query exceeds the configured limits.
+// If some logs were found, the error data carries the cursor of the first log
+// omitted, from where the query can be continued with eth_getLogsPage.
+type limitExceededError struct {
+	msg    string
+	cursor *LogCursor
+}
+
+func (e *limitExceededError) Error() string  { return e.msg }
+func (e *limitExceededError) ErrorCode() int { return errcodeLimitExceeded }
+
+func (e *limitExceededError) ErrorData() interface{} {
+	if e.cursor == nil {
+		return nil
+	}
+	return e.cursor
+}
+
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
@@ -58,10 +100,11 @@ type PublicFilterAPI struct {
 	filters    map[rpc.ID]*filter
 	timeout    time.Duration
 	rangeLimit bool
+	limits     LogLimits
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, rangeLimit bool) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, rangeLimit bool, limits LogLimits) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend:    backend,
 		chainDb:    backend.ChainDb(),
@@ -69,6 +112,7 @@ func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration,
 		filters:    make(map[rpc.ID]*filter),
 		timeout:    timeout,
 		rangeLimit: rangeLimit,
+		limits:     limits,
 	}
 	go api.timeoutLoop(timeout)
 
@@ -330,10 +374,43 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
+// Queries exceeding the configured block range or result limits are rejected,
+// the latter along with the cursor to continue with using eth_getLogsPage.
 //
 // https://eth.wiki/json-rpc/API#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	var filter *Filter
+	logs, cursor, err := api.queryLogs(ctx, crit, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		return nil, &limitExceededError{fmt.Sprintf("query returned more than %d results", api.limits.Results), cursor}
+	}
+	return returnLogs(logs), nil
+}
+
+// GetLogsPage returns a page of the logs matching the given argument, starting
+// at the given cursor or at the beginning of the queried range if omitted. Pages
+// span at most the configured block range and contain at most the configured
+// number of logs, the cursor of the returned page continues the query.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *LogCursor) (*LogsPage, error) {
+	logs, next, err := api.queryLogs(ctx, crit, cursor, true)
+	if err != nil {
+		return nil, err
+	}
+	return &LogsPage{Logs: returnLogs(logs), Cursor: next}, nil
+}
+
+// queryLogs retrieves the logs matching the given criteria from the cursor on,
+// bounded by the configured limits. If more logs match the criteria than the
+// results limit allows, the cursor of the first log omitted is returned. Queries
+// spanning more blocks than permitted are rejected, unless paginated, in which
+// case they are cut short and the cursor of the next block is returned.
+func (api *PublicFilterAPI) queryLogs(ctx context.Context, crit FilterCriteria, cursor *LogCursor, paginate bool) ([]*types.Log, *LogCursor, error) {
+	var (
+		filter *Filter
+		next   *LogCursor
+	)
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
 		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
@@ -347,15 +424,52 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		if crit.ToBlock != nil {
 			end = crit.ToBlock.Int64()
 		}
+		// Resolve the range if it needs to be bounded
+		if cursor != nil || api.limits.BlockRange > 0 {
+			if begin < 0 || end < 0 {
+				header, _ := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+				if header == nil {
+					return nil, nil, nil
+				}
+				if begin < 0 {
+					begin = header.Number.Int64()
+				}
+				if end < 0 {
+					end = header.Number.Int64()
+				}
+			}
+			if cursor != nil {
+				if int64(cursor.BlockNumber) < begin || int64(cursor.BlockNumber) > end {
+					return nil, nil, errors.New("cursor outside of the queried range")
+				}
+				begin = int64(cursor.BlockNumber)
+			}
+			if limit := api.limits.BlockRange; limit > 0 && end >= begin && uint64(end-begin) >= limit {
+				if !paginate {
+					return nil, nil, &limitExceededError{fmt.Sprintf("query exceeds max block range %d", limit), nil}
+				}
+				end = begin + int64(limit) - 1
+				next = &LogCursor{BlockNumber: hexutil.Uint64(end + 1)}
+			}
+		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics, api.rangeLimit)
 	}
-	// Run the filter and return all the logs
+	if cursor != nil {
+		filter.skipBlock, filter.skipIndex = uint64(cursor.BlockNumber), uint(cursor.LogIndex)
+	}
+	if limit := api.limits.Results; limit > 0 {
+		filter.limit = int(limit)
+	}
 	logs, err := filter.Logs(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return returnLogs(logs), err
+	if limit := api.limits.Results; limit > 0 && uint64(len(logs)) > limit {
+		next = &LogCursor{BlockNumber: hexutil.Uint64(logs[limit].BlockNumber), LogIndex: hexutil.Uint(logs[limit].Index)}
+		logs = logs[:limit]
+	}
+	return logs, next, nil
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -387,30 +501,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if !found || f.typ != LogsSubscription {
 		return nil, fmt.Errorf("filter not found")
 	}
-
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics, api.rangeLimit)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), nil
+	return api.GetLogs(ctx, f.crit)
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -48,6 +49,10 @@ type Backend interface {
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	// LogIndexStatus returns the section size and the number of sections of
+	// the address/topic log index, zero if the index is not maintained.
+	LogIndexStatus() (uint64, uint64)
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -64,6 +69,12 @@ type Filter struct {
 	matcher *bloombits.Matcher
 
 	rangeLimit bool
+
+	limit int // Number of logs after which range filtering stops at the next block boundary, 0 = unlimited
+	found int // Number of logs gathered so far by range filtering
+
+	skipBlock uint64 // Block whose logs before skipIndex are dropped, used to resume at a cursor
+	skipIndex uint   // Index of the first log delivered from skipBlock, 0 = none dropped
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -151,19 +162,23 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.rangeLimit && (int64(end)-f.begin) > maxFilterBlockRange {
 		return nil, fmt.Errorf("exceed maximum block range: %d", maxFilterBlockRange)
 	}
-	// Gather all indexed logs, and finish with non indexed ones
-	var (
-		logs []*types.Log
-		err  error
-	)
+	// Gather all indexed logs, and finish with non indexed ones. The log index
+	// is preferred over the bloom bits as it yields no false positives.
+	var logs []*types.Log
+	if size, sections := f.backend.LogIndexStatus(); f.hasCriteria() {
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			found, err := f.logIndexedLogs(ctx, size, minUint64(indexed-1, end))
+			logs = append(logs, found...)
+			if err != nil || f.full() {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
-		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
-		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
-		}
-		if err != nil {
+		found, err := f.indexedLogs(ctx, minUint64(indexed-1, end))
+		logs = append(logs, found...)
+		if err != nil || f.full() {
 			return logs, err
 		}
 	}
@@ -213,12 +228,107 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			}
 			logs = append(logs, found...)
 
+			f.found += len(found)
+			if f.full() {
+				return logs, nil
+			}
+
 		case <-ctx.Done():
 			return logs, ctx.Err()
 		}
 	}
 }
 
+// logIndexedLogs returns the logs matching the filter criteria based on the
+// address/topic log index, whose sections are of the given size.
+func (f *Filter) logIndexedLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section*size <= end; section++ {
+		head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+		for _, offset := range f.logIndexMatches(section, head) {
+			number := section*size + offset
+			if number < uint64(f.begin) {
+				continue
+			}
+			if number > end {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return logs, err
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			f.begin = int64(number) + 1
+
+			f.found += len(found)
+			if f.full() {
+				return logs, nil
+			}
+		}
+		f.begin = int64(minUint64((section+1)*size-1, end)) + 1
+	}
+	return logs, nil
+}
+
+// logIndexMatches returns the offsets of the blocks within a log index section
+// that contain logs satisfying every address and topic clause of the filter.
+func (f *Filter) logIndexMatches(section uint64, head common.Hash) []uint64 {
+	var clauses [][]uint64
+	if len(f.addresses) > 0 {
+		var blocks []uint64
+		for _, address := range f.addresses {
+			blocks = unionBlocks(blocks, rawdb.ReadLogIndexAddress(f.db, address, section, head))
+		}
+		clauses = append(clauses, blocks)
+	}
+	for _, topics := range f.topics {
+		if len(topics) == 0 {
+			continue // wildcard
+		}
+		var blocks []uint64
+		for _, topic := range topics {
+			blocks = unionBlocks(blocks, rawdb.ReadLogIndexTopic(f.db, topic, section, head))
+		}
+		clauses = append(clauses, blocks)
+	}
+	if len(clauses) == 0 {
+		return nil
+	}
+	matches := clauses[0]
+	for _, blocks := range clauses[1:] {
+		matches = intersectBlocks(matches, blocks)
+	}
+	return matches
+}
+
+// full reports whether range filtering gathered more logs than the limit of the
+// filter, in which case it stops after the block exceeding the limit.
+func (f *Filter) full() bool {
+	return f.limit > 0 && f.found > f.limit
+}
+
+// hasCriteria reports whether the filter restricts the log address or any of the
+// log topics, without which the log index cannot narrow down the blocks.
+func (f *Filter) hasCriteria() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -234,6 +344,12 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 			return logs, err
 		}
 		logs = append(logs, found...)
+
+		f.found += len(found)
+		if f.full() {
+			f.begin++
+			return logs, nil
+		}
 	}
 	return logs, nil
 }
@@ -276,11 +392,56 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			}
 			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 		}
+		// Drop the logs preceding the position range filtering resumes at
+		if f.skipIndex > 0 && header.Number.Uint64() == f.skipBlock {
+			for len(logs) > 0 && logs[0].Index < f.skipIndex {
+				logs = logs[1:]
+			}
+		}
 		return logs, nil
 	}
 	return nil, nil
 }
 
+// unionBlocks merges two ascending lists of block offsets.
+func unionBlocks(a, b []uint64) []uint64 {
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			merged, a = append(merged, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	return merged
+}
+
+// intersectBlocks returns the block offsets present in both ascending lists.
+func intersectBlocks(a, b []uint64) []uint64 {
+	var shared []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case b[0] < a[0]:
+			b = b[1:]
+		default:
+			shared, a, b = append(shared, a[0]), a[1:], b[1:]
+		}
+	}
+	return shared
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
@@ -28,6 +28,7 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -44,14 +45,16 @@ var (
 )
 
 type testBackend struct {
-	mux             *event.TypeMux
-	db              ethdb.Database
-	sections        uint64
-	txFeed          event.Feed
-	logsFeed        event.Feed
-	rmLogsFeed      event.Feed
-	pendingLogsFeed event.Feed
-	chainFeed       event.Feed
+	mux              *event.TypeMux
+	db               ethdb.Database
+	sections         uint64
+	logIndexSize     uint64
+	logIndexSections uint64
+	txFeed           event.Feed
+	logsFeed         event.Feed
+	rmLogsFeed       event.Feed
+	pendingLogsFeed  event.Feed
+	chainFeed        event.Feed
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
@@ -130,6 +133,10 @@ func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
 
+func (b *testBackend) LogIndexStatus() (uint64, uint64) {
+	return b.logIndexSize, b.logIndexSections
+}
+
 func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	requests := make(chan chan *bloombits.Retrieval)
 
@@ -168,7 +175,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false, deadline, false)
+		api         = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -220,7 +227,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -275,7 +282,7 @@ func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -319,7 +326,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 	)
 
 	// different situations where log filter creation should fail.
@@ -341,7 +348,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false, deadline, false)
+		api       = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -359,6 +366,107 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	}
 }
 
+// TestGetLogsLimits tests that log queries are bounded by the configured limits,
+// and that paginated queries deliver every matching log exactly once.
+func TestGetLogsLimits(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		addr    = common.HexToAddress("0x1")
+		topic   = common.HexToHash("0x2")
+	)
+	// Emit two logs in block 2, three in block 4 and one in block 7
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 9, func(i int, gen *core.BlockGen) {
+		counts := map[int]int{1: 2, 3: 3, 6: 1}
+		if counts[i] == 0 {
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		for j := 0; j < counts[i]; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: addr, Topics: []common.Hash{topic}})
+		}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	all := []LogCursor{{2, 0}, {2, 1}, {4, 0}, {4, 1}, {4, 2}, {7, 0}}
+	crit := FilterCriteria{FromBlock: big.NewInt(0), Addresses: []common.Address{addr}}
+
+	// Queries exceeding the limits are rejected
+	tests := []struct {
+		limits LogLimits
+		cursor interface{}
+	}{
+		{LogLimits{Results: 3}, &LogCursor{4, 1}},
+		{LogLimits{BlockRange: 4}, nil},
+	}
+	for i, tt := range tests {
+		api := NewPublicFilterAPI(backend, false, deadline, false, tt.limits)
+		_, err := api.GetLogs(context.Background(), crit)
+		if err == nil {
+			t.Fatalf("test %d: query exceeding limits succeeded", i)
+		}
+		if code := err.(rpc.Error).ErrorCode(); code != errcodeLimitExceeded {
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, code, errcodeLimitExceeded)
+		}
+		if data := err.(rpc.DataError).ErrorData(); !reflect.DeepEqual(data, tt.cursor) {
+			t.Errorf("test %d: error data mismatch: have %v, want %v", i, data, tt.cursor)
+		}
+	}
+	api := NewPublicFilterAPI(backend, false, deadline, false, LogLimits{BlockRange: 10, Results: 6})
+	if logs, err := api.GetLogs(context.Background(), crit); err != nil || len(logs) != len(all) {
+		t.Fatalf("query within limits failed: %d logs, %v", len(logs), err)
+	}
+	// Paginated queries resume at the cursor until exhausted
+	for i, limits := range []LogLimits{{Results: 3}, {Results: 1}, {BlockRange: 4}, {BlockRange: 3, Results: 2}, {}} {
+		var (
+			api    = NewPublicFilterAPI(backend, false, deadline, false, limits)
+			have   []LogCursor
+			cursor *LogCursor
+		)
+		for pages := 0; ; pages++ {
+			if pages > 20 {
+				t.Fatalf("test %d: pagination not terminating", i)
+			}
+			page, err := api.GetLogsPage(context.Background(), crit, cursor)
+			if err != nil {
+				t.Fatalf("test %d: failed to query page: %v", i, err)
+			}
+			if limits.Results > 0 && uint64(len(page.Logs)) > limits.Results {
+				t.Fatalf("test %d: page too large: %d logs", i, len(page.Logs))
+			}
+			for _, log := range page.Logs {
+				have = append(have, LogCursor{hexutil.Uint64(log.BlockNumber), hexutil.Uint(log.Index)})
+			}
+			if cursor = page.Cursor; cursor == nil {
+				break
+			}
+		}
+		if !reflect.DeepEqual(have, all) {
+			t.Errorf("test %d: paginated logs mismatch: have %v, want %v", i, have, all)
+		}
+	}
+	// Cursors past the last log of a block resume at the next block
+	page, err := NewPublicFilterAPI(backend, false, deadline, false, LogLimits{Results: 2}).GetLogsPage(context.Background(), crit, &LogCursor{4, ^hexutil.Uint(0)})
+	if err != nil {
+		t.Fatalf("failed to query page past the last log: %v", err)
+	}
+	if len(page.Logs) != 1 || page.Logs[0].BlockNumber != 7 || page.Cursor != nil {
+		t.Errorf("page past the last log mismatch: logs %v, cursor %v", page.Logs, page.Cursor)
+	}
+	// Cursors must lie within the queried range
+	crit.ToBlock = big.NewInt(5)
+	if _, err := api.GetLogsPage(context.Background(), crit, &LogCursor{BlockNumber: 7}); err == nil {
+		t.Fatal("cursor outside of the range accepted")
+	}
+}
+
 // TestLogFilter tests whether log filters match the correct logs that are posted to the event feed.
 func TestLogFilter(t *testing.T) {
 	t.Parallel()
@@ -366,7 +474,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -480,7 +588,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		api     = NewPublicFilterAPI(backend, false, deadline, false, LogLimits{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -616,7 +724,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, timeout, false)
+		api     = NewPublicFilterAPI(backend, false, timeout, false, LogLimits{})
 		done    = make(chan struct{})
 	)
 
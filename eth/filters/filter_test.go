@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -223,3 +224,89 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// Tests that range filters use the address/topic log index when available,
+// trusting it over the bloom filters of the headers.
+func TestLogIndexedFilters(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db, logIndexSize: 100}
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = common.HexToAddress("0x2")
+
+		hash1 = common.BytesToHash([]byte("topic1"))
+		hash2 = common.BytesToHash([]byte("topic2"))
+		hash3 = common.BytesToHash([]byte("topic3"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 250, func(i int, gen *core.BlockGen) {
+		var log *types.Log
+		switch i {
+		case 10:
+			log = &types.Log{Address: addr1, Topics: []common.Hash{hash1}}
+		case 120:
+			log = &types.Log{Address: addr1, Topics: []common.Hash{hash1, hash2}}
+		case 150:
+			log = &types.Log{Address: addr2, Topics: []common.Hash{hash2, hash1}}
+		case 240:
+			log = &types.Log{Address: addr1, Topics: []common.Hash{hash3}}
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{log}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the first two sections, leaving the rest to the unindexed scan
+	if err := core.RebuildLogIndex(db, 100, 50, nil); err != nil {
+		t.Fatalf("failed to build log index: %v", err)
+	}
+	backend.logIndexSections = 2
+
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       []uint64
+	}{
+		{0, -1, []common.Address{addr1}, nil, []uint64{11, 121, 241}},
+		{0, -1, nil, [][]common.Hash{{hash1}}, []uint64{11, 121}},
+		{0, -1, nil, [][]common.Hash{nil, {hash1}}, []uint64{151}},
+		{0, -1, []common.Address{addr1, addr2}, [][]common.Hash{{hash1, hash2}, {hash1, hash2}}, []uint64{121, 151}},
+		{12, 200, []common.Address{addr1}, nil, []uint64{121}},
+		{0, 120, []common.Address{addr1}, nil, []uint64{11}},
+		{150, 250, nil, [][]common.Hash{{hash2, hash3}}, []uint64{151, 241}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{{hash1}}, nil},
+	}
+	for i, tt := range tests {
+		logs, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, tt.topics, false).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter logs: %v", i, err)
+		}
+		var have []uint64
+		for _, log := range logs {
+			have = append(have, log.BlockNumber)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Drop the index entry of addr1 in the first section, it must be trusted
+	rawdb.WriteLogIndexAddress(db, addr1, 0, rawdb.ReadCanonicalHash(db, 99), nil)
+
+	logs, err := NewRangeFilter(backend, 0, -1, []common.Address{addr1}, nil, false).Logs(context.Background())
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].BlockNumber != 121 {
+		t.Fatalf("log index not used: have %d logs", len(logs))
+	}
+}
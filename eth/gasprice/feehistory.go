@@ -0,0 +1,163 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks a single fee history request
+// can cover.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// txGasAndPrice is the gas used and the price paid by a single transaction.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+type txGasAndPriceSorter []txGasAndPrice
+
+func (s txGasAndPriceSorter) Len() int           { return len(s) }
+func (s txGasAndPriceSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s txGasAndPriceSorter) Less(i, j int) bool { return s[i].price.Cmp(s[j].price) < 0 }
+
+// processBlock computes the gas used ratio of a block and the gas prices paid
+// at the requested percentiles, weighted by the gas used by each transaction.
+// Consensus system transactions are excluded from both, otherwise the nearly
+// empty blocks of a low traffic chain would look busy and free at once.
+func (gpo *Oracle) processBlock(block *types.Block, receipts types.Receipts, percentiles []float64) ([]*big.Int, float64) {
+	var (
+		header  = block.Header()
+		sorter  = make(txGasAndPriceSorter, 0, len(block.Transactions()))
+		gasUsed uint64
+	)
+	for i, tx := range block.Transactions() {
+		if i >= len(receipts) {
+			break
+		}
+		if gpo.isSystemTx(tx, header) {
+			continue
+		}
+		sorter = append(sorter, txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()})
+		gasUsed += receipts[i].GasUsed
+	}
+	var ratio float64
+	if header.GasLimit > 0 {
+		ratio = float64(gasUsed) / float64(header.GasLimit)
+	}
+	if len(percentiles) == 0 {
+		return nil, ratio
+	}
+	reward := make([]*big.Int, len(percentiles))
+	if len(sorter) == 0 {
+		// Return an all zero row if there are no transactions to gather data from
+		for i := range reward {
+			reward[i] = new(big.Int)
+		}
+		return reward, ratio
+	}
+	sort.Stable(sorter)
+
+	var txIndex int
+	sumGasUsed := sorter[0].gasUsed
+
+	for i, p := range percentiles {
+		thresholdGasUsed := uint64(float64(gasUsed) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(sorter)-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		reward[i] = sorter[txIndex].price
+	}
+	return reward, ratio
+}
+
+// FeeHistory returns the gas used ratio and the gas prices paid at the given
+// percentiles for a range of blocks ending at lastBlock, along with the number
+// of the oldest block in the range. The range is shortened if it would reach
+// beyond the genesis block.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return new(big.Int), nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return new(big.Int), nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, percentiles[i-1], i, p)
+		}
+	}
+	// The pending block can't be retrieved by number, report the head instead
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return new(big.Int), nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock != rpc.LatestBlockNumber {
+		if uint64(lastBlock) > last {
+			return new(big.Int), nil, nil, fmt.Errorf("%w: requested %d, head %d", errRequestBeyondHead, lastBlock, last)
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	var (
+		oldest = last + 1 - uint64(blocks)
+		reward = make([][]*big.Int, blocks)
+		ratio  = make([]float64, blocks)
+	)
+	for i := 0; i < blocks; i++ {
+		number := oldest + uint64(i)
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block #%d not found", number)
+			}
+			return new(big.Int), nil, nil, err
+		}
+		receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return new(big.Int), nil, nil, err
+		}
+		reward[i], ratio[i] = gpo.processBlock(block, receipts, percentiles)
+	}
+	if len(percentiles) == 0 {
+		reward = nil
+	}
+	return new(big.Int).SetUint64(oldest), reward, ratio, nil
+}
@@ -23,6 +23,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -31,6 +32,11 @@ import (
 
 const sampleNumber = 3 // Number of transactions sampled in a block
 
+const (
+	slowPercentile = 20 // Sample percentile used for the slow price suggestion
+	fastPercentile = 90 // Sample percentile used for the fast price suggestion
+)
+
 var DefaultMaxPrice = big.NewInt(500 * params.GWei)
 
 type Config struct {
@@ -45,18 +51,28 @@ type Config struct {
 type OracleBackend interface {
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
+	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	ChainConfig() *params.ChainConfig
+	Engine() consensus.Engine
+}
+
+// PriceSuggestion is a set of gas prices with increasing inclusion chances.
+type PriceSuggestion struct {
+	Slow     *big.Int
+	Standard *big.Int
+	Fast     *big.Int
 }
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend   OracleBackend
-	lastHead  common.Hash
-	lastPrice *big.Int
-	maxPrice  *big.Int
-	cacheLock sync.RWMutex
-	fetchLock sync.Mutex
+	backend    OracleBackend
+	lastHead   common.Hash
+	lastPrice  *big.Int
+	lastPrices *PriceSuggestion
+	maxPrice   *big.Int
+	cacheLock  sync.RWMutex
+	fetchLock  sync.Mutex
 
 	defaultPrice      *big.Int
 	sampleTxThreshold int
@@ -90,6 +106,7 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	return &Oracle{
 		backend:           backend,
 		lastPrice:         params.Default,
+		lastPrices:        &PriceSuggestion{Slow: params.Default, Standard: params.Default, Fast: params.Default},
 		maxPrice:          maxPrice,
 		checkBlocks:       blocks,
 		percentile:        percent,
@@ -101,25 +118,35 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 // SuggestPrice returns a gasprice so that newly created transaction can
 // have a very high chance to be included in the following blocks.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	prices, err := gpo.SuggestPrices(ctx)
+	if err != nil {
+		return prices.Standard, err
+	}
+	return prices.Standard, nil
+}
+
+// SuggestPrices returns slow, standard and fast gas price suggestions sampled
+// from the same recent blocks, the standard one being the configured percentile.
+func (gpo *Oracle) SuggestPrices(ctx context.Context) (*PriceSuggestion, error) {
 	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 
 	// If the latest gasprice is still available, return it.
 	gpo.cacheLock.RLock()
-	lastHead, lastPrice := gpo.lastHead, gpo.lastPrice
+	lastHead, lastPrice, lastPrices := gpo.lastHead, gpo.lastPrice, gpo.lastPrices
 	gpo.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastPrices, nil
 	}
 	gpo.fetchLock.Lock()
 	defer gpo.fetchLock.Unlock()
 
 	// Try checking the cache again, maybe the laest fetch fetched what we need
 	gpo.cacheLock.RLock()
-	lastHead, lastPrice = gpo.lastHead, gpo.lastPrice
+	lastHead, lastPrice, lastPrices = gpo.lastHead, gpo.lastPrice, gpo.lastPrices
 	gpo.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return lastPrice, nil
+		return lastPrices, nil
 	}
 
 	var (
@@ -140,12 +167,12 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		res := <-result
 		if res.err != nil {
 			close(quit)
-			return lastPrice, res.err
+			return lastPrices, res.err
 		}
 		exp--
 
 		// Nothing returned. There are two special cases here:
-		// - The block is empty
+		// - The block is empty, or only contains consensus system transactions
 		// - All the transactions included are sent by the miner itself.
 		// In these cases, use the latest calculated price for samping.
 		if len(res.prices) == 0 {
@@ -164,21 +191,35 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		}
 		txPrices = append(txPrices, res.prices...)
 	}
-	price := lastPrice
+	// On low traffic chains the recent blocks may carry no priced transactions
+	// at all, in which case anything at the configured default gets included.
+	prices := &PriceSuggestion{Slow: gpo.defaultPrice, Standard: gpo.defaultPrice, Fast: gpo.defaultPrice}
 	if len(txPrices) > 0 && totalTxSamples > gpo.sampleTxThreshold {
 		sort.Sort(bigIntArray(txPrices))
-		price = txPrices[(len(txPrices)-1)*gpo.percentile/100]
-	} else {
-		price = gpo.defaultPrice
+
+		fast := fastPercentile
+		if fast < gpo.percentile {
+			fast = gpo.percentile
+		}
+		slow := slowPercentile
+		if slow > gpo.percentile {
+			slow = gpo.percentile
+		}
+		prices.Slow = txPrices[(len(txPrices)-1)*slow/100]
+		prices.Standard = txPrices[(len(txPrices)-1)*gpo.percentile/100]
+		prices.Fast = txPrices[(len(txPrices)-1)*fast/100]
 	}
-	if price.Cmp(gpo.maxPrice) > 0 {
-		price = new(big.Int).Set(gpo.maxPrice)
+	for _, price := range []**big.Int{&prices.Slow, &prices.Standard, &prices.Fast} {
+		if (*price).Cmp(gpo.maxPrice) > 0 {
+			*price = new(big.Int).Set(gpo.maxPrice)
+		}
 	}
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
-	gpo.lastPrice = price
+	gpo.lastPrice = prices.Standard
+	gpo.lastPrices = prices
 	gpo.cacheLock.Unlock()
-	return price, nil
+	return prices, nil
 }
 
 type getBlockPricesResult struct {
@@ -194,9 +235,10 @@ func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPriceCmp(t[j]) < 0 }
 
 // getBlockPrices calculates the lowest transaction gas price in a given block
-// and sends it to the result channel. If the block is empty or all transactions
-// are sent by the miner itself(it doesn't make any sense to include this kind of
-// transaction prices for sampling), nil gasprice is returned.
+// and sends it to the result channel. If the block is empty, only contains
+// consensus system transactions or all transactions are sent by the miner
+// itself(it doesn't make any sense to include this kind of transaction prices
+// for sampling), nil gasprice is returned.
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, limit int, result chan getBlockPricesResult, quit chan struct{}) {
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
@@ -213,7 +255,10 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 
 	var prices []*big.Int
 	for _, tx := range txs {
-		if tx.GasPriceIntCmp(common.Big1) <= 0 {
+		if tx.GasPriceIntCmp(gpo.minSamplePrice()) < 0 {
+			continue
+		}
+		if gpo.isSystemTx(tx, block.Header()) {
 			continue
 		}
 		sender, err := types.Sender(signer, tx)
@@ -230,6 +275,27 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	}
 }
 
+// minSamplePrice returns the lowest gas price a transaction needs to count as
+// a sample. Zero and one wei transactions are usually special purpose ones and
+// are ignored, unless the node itself is configured for a zero-fee chain.
+func (gpo *Oracle) minSamplePrice() *big.Int {
+	if gpo.defaultPrice != nil && gpo.defaultPrice.Sign() == 0 {
+		return common.Big0
+	}
+	return common.Big2
+}
+
+// isSystemTx reports whether the transaction is a consensus system transaction,
+// which the validator includes for free in every block.
+func (gpo *Oracle) isSystemTx(tx *types.Transaction, header *types.Header) bool {
+	posa, ok := gpo.backend.Engine().(consensus.PoSA)
+	if !ok {
+		return false
+	}
+	isSystemTx, err := posa.IsSystemTransaction(tx, header)
+	return err == nil && isSystemTx
+}
+
 type bigIntArray []*big.Int
 
 func (s bigIntArray) Len() int           { return len(s) }
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -51,10 +52,18 @@ func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber)
 	return b.chain.GetBlockByNumber(uint64(number)), nil
 }
 
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
 
+func (b *testBackend) Engine() consensus.Engine {
+	return b.chain.Engine()
+}
+
 func newTestBackend(t *testing.T) *testBackend {
 	var (
 		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
@@ -116,3 +125,75 @@ func TestSuggestPrice(t *testing.T) {
 		t.Fatalf("Gas price mismatch, want %d, got %d", expect, got)
 	}
 }
+
+func TestSuggestPrices(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	}
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, config)
+
+	// The gas price sampled is: 32G, 31G, 30G, 29G, 28G, 27G
+	got, err := oracle.SuggestPrices(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas prices: %v", err)
+	}
+	for _, tt := range []struct {
+		name string
+		have *big.Int
+		want int64
+	}{
+		{"slow", got.Slow, 28},
+		{"standard", got.Standard, 30},
+		{"fast", got.Fast, 31},
+	} {
+		if want := big.NewInt(params.GWei * tt.want); tt.have.Cmp(want) != 0 {
+			t.Errorf("%s gas price mismatch, want %d, got %d", tt.name, want, tt.have)
+		}
+	}
+}
+
+func TestFeeHistory(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	}
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, config)
+
+	// Every block holds a single 21000 gas transaction priced at its number in gwei
+	oldest, reward, ratio, err := oracle.FeeHistory(context.Background(), 4, 10, []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("Failed to retrieve fee history: %v", err)
+	}
+	if oldest.Uint64() != 7 {
+		t.Fatalf("oldest block mismatch, want %d, got %d", 7, oldest)
+	}
+	if len(reward) != 4 || len(ratio) != 4 {
+		t.Fatalf("history length mismatch, want 4, got %d rewards and %d ratios", len(reward), len(ratio))
+	}
+	for i := range reward {
+		want := big.NewInt(int64(7+i) * params.GWei)
+		for j, have := range reward[i] {
+			if have.Cmp(want) != 0 {
+				t.Errorf("block %d percentile %d reward mismatch, want %d, got %d", 7+i, j, want, have)
+			}
+		}
+		if ratio[i] <= 0 || ratio[i] >= 1 {
+			t.Errorf("block %d gas used ratio out of range: %f", 7+i, ratio[i])
+		}
+	}
+	// Requests beyond the genesis are shortened, beyond the head rejected
+	if oldest, _, _, err := oracle.FeeHistory(context.Background(), 10, 2, nil); err != nil || oldest.Uint64() != 0 {
+		t.Errorf("shortened history mismatch: oldest %v, err %v", oldest, err)
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 100, nil); err == nil {
+		t.Errorf("expected error for history beyond head")
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, 10, []float64{50, 10}); err == nil {
+		t.Errorf("expected error for unsorted percentiles")
+	}
+}
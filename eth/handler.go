@@ -88,6 +88,7 @@ type handlerConfig struct {
 	Network                uint64                    // Network identifier to adfvertise
 	Sync                   downloader.SyncMode       // Whether to fast or full sync
 	DiffSync               bool                      // Whether to diff sync
+	DiffSyncHorizon        time.Duration             // Age beyond which blocks are imported by their diff layers
 	BloomCache             uint64                    // Megabytes to alloc for fast sync bloom
 	EventMux               *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint             *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
@@ -119,6 +120,7 @@ type handler struct {
 	stateBloom   *trie.SyncBloom
 	blockFetcher *fetcher.BlockFetcher
 	txFetcher    *fetcher.TxFetcher
+	repairer     *ancientRepairer
 	peers        *peerSet
 
 	eventMux      *event.TypeMux
@@ -204,6 +206,9 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	var downloadOptions []downloader.DownloadOption
 	if h.diffSync {
 		downloadOptions = append(downloadOptions, downloader.EnableDiffFetchOp(h.peers))
+		if config.DiffSyncHorizon > 0 {
+			downloadOptions = append(downloadOptions, downloader.EnableDiffCatchUp(h.peers, config.DiffSyncHorizon))
+		}
 	}
 	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.stateBloom, h.eventMux, h.chain, nil, h.removePeer, downloadOptions...)
 
@@ -250,6 +255,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		return p.RequestTxs(hashes)
 	}
 	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
+	h.repairer = newAncientRepairer(h.database, h.peers)
 	h.chainSync = newChainSyncer(h)
 	return h, nil
 }
@@ -453,6 +459,13 @@ func (h *handler) Start(maxPeers int) {
 	h.wg.Add(2)
 	go h.chainSync.loop()
 	go h.txsyncLoop64() // TODO(karalabe): Legacy initial tx echange, drop with eth/64.
+
+	// re-fetch corrupted ancient items scheduled for repair
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.repairer.loop(h.quitSync)
+	}()
 }
 
 func (h *handler) Stop() {
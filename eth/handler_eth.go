@@ -70,6 +70,9 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 
 	case *eth.BlockBodiesPacket:
 		txset, uncleset := packet.Unpack()
+		if h.repairer.deliverBodies(peer.ID(), txset, uncleset) {
+			return nil
+		}
 		return h.handleBodies(peer, txset, uncleset)
 
 	case *eth.NodeDataPacket:
@@ -79,6 +82,9 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 		return nil
 
 	case *eth.ReceiptsPacket:
+		if h.repairer.deliverReceipts(peer.ID(), *packet) {
+			return nil
+		}
 		if err := h.downloader.DeliverReceipts(peer.ID(), *packet); err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
@@ -121,6 +121,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
 		diffs := answerDiffLayersQuery(backend, res)
+		if peer.version < Diff2 {
+			diffs = stripSignatures(diffs)
+		}
 
 		p2p.Send(peer.rw, FullDiffLayerMsg, &FullDiffLayersPacket{
 			RequestId:        res.RequestId,
@@ -95,6 +95,9 @@ func (p *Peer) RequestDiffLayers(hashes []common.Hash) error {
 }
 
 func (p *Peer) SendDiffLayers(diffs []rlp.RawValue) error {
+	if p.version < Diff2 {
+		diffs = stripSignatures(diffs)
+	}
 	return p2p.Send(p.rw, DiffLayerMsg, diffs)
 }
 
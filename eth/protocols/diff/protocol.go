@@ -30,6 +30,7 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	Diff1 = 1
+	Diff2 = 2 // Diff layers carry the signature of the block producer
 )
 
 // ProtocolName is the official short name of the `diff` protocol used during
@@ -38,11 +39,11 @@ const ProtocolName = "diff"
 
 // ProtocolVersions are the supported versions of the `diff` protocol (first
 // is primary).
-var ProtocolVersions = []uint{Diff1}
+var ProtocolVersions = []uint{Diff2, Diff1}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{Diff1: 4}
+var protocolLengths = map[uint]uint64{Diff2: 4, Diff1: 4}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -97,6 +98,29 @@ func (p *DiffLayersPacket) Unpack() ([]*types.DiffLayer, error) {
 	return diffLayers, nil
 }
 
+// stripSignatures re-encodes the given diff layers without the signature of
+// the block producer, which legacy peers are unable to decode.
+func stripSignatures(diffs []rlp.RawValue) []rlp.RawValue {
+	stripped := make([]rlp.RawValue, 0, len(diffs))
+	for _, rawData := range diffs {
+		var diff types.DiffLayer
+		if err := rlp.DecodeBytes(rawData, &diff); err != nil {
+			continue
+		}
+		if diff.Signature == nil {
+			stripped = append(stripped, rawData)
+			continue
+		}
+		diff.Signature = nil
+		data, err := rlp.EncodeToBytes(&diff)
+		if err != nil {
+			continue
+		}
+		stripped = append(stripped, data)
+	}
+	return stripped
+}
+
 type DiffCapPacket struct {
 	DiffSync bool
 	Extra    rlp.RawValue // for extension
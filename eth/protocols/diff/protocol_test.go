@@ -129,3 +129,31 @@ func TestDiffMessages(t *testing.T) {
 		}
 	}
 }
+
+// Tests that signed diff layers are re-encoded in the legacy format for peers
+// which can't decode the signature, keeping the diff layer hash intact.
+func TestStripSignatures(t *testing.T) {
+	diff := &types.DiffLayer{
+		BlockHash: common.HexToHash("0x1e9624dcd0874958723aa3dae1fe299861e93ef32b980143d798c428bdd7a20a"),
+		Number:    10479133,
+		Accounts: []types.DiffAccount{{
+			Account: common.HexToAddress("0x18b2a687610328590bc8f2e5fedde3b582a49cda"),
+			Blob:    []byte{2, 3, 4, 5},
+		}},
+	}
+	unsigned, err := rlp.EncodeToBytes(diff)
+	assert.NoError(t, err)
+
+	diff.Signature = bytes.Repeat([]byte{0x01}, 65)
+	signed, err := rlp.EncodeToBytes(diff)
+	assert.NoError(t, err)
+
+	stripped := stripSignatures([]rlp.RawValue{signed, unsigned})
+	if len(stripped) != 2 || !bytes.Equal(stripped[0], unsigned) || !bytes.Equal(stripped[1], unsigned) {
+		t.Fatalf("signature not stripped: have %x, want %x", stripped, unsigned)
+	}
+	var decoded types.DiffLayer
+	assert.NoError(t, rlp.DecodeBytes(signed, &decoded))
+	assert.Equal(t, diff.Signature, decoded.Signature)
+	assert.Equal(t, diff.Hash(), decoded.Hash())
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// ancientRepairInterval is the interval of checking for scheduled repairs of
+	// corrupted ancient items.
+	ancientRepairInterval = 30 * time.Second
+
+	// ancientRepairTimeout is the time a peer has to deliver the requested items,
+	// before they are requested from another one.
+	ancientRepairTimeout = 10 * time.Second
+
+	// ancientRepairBatch is the maximum number of items requested at once.
+	ancientRepairBatch = 16
+)
+
+// repairRequest is a request of corrupted ancient items sent to a peer.
+type repairRequest struct {
+	receipts bool            // Whether receipts are requested, bodies otherwise
+	headers  []*types.Header // Headers of the requested items, in request order
+	time     time.Time       // Time the request was sent
+}
+
+// ancientRepairer fetches the corrupted ancient block bodies and receipts scheduled
+// for repair by `geth db verify-ancients --repair` from the network again, and
+// overwrites them in the ancient store once verified against their headers.
+type ancientRepairer struct {
+	db    ethdb.Database
+	peers *peerSet
+
+	requests map[string]*repairRequest // Outstanding requests, keyed by peer id
+	lock     sync.Mutex
+}
+
+// newAncientRepairer creates a repairer of the corrupted items of the given
+// database's ancient store.
+func newAncientRepairer(db ethdb.Database, peers *peerSet) *ancientRepairer {
+	return &ancientRepairer{
+		db:       db,
+		peers:    peers,
+		requests: make(map[string]*repairRequest),
+	}
+}
+
+// loop periodically requests the scheduled repairs from the connected peers.
+func (r *ancientRepairer) loop(quit chan struct{}) {
+	ticker := time.NewTicker(ancientRepairInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.request()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// request sends the scheduled repairs which aren't pending already to idle peers.
+func (r *ancientRepairer) request() {
+	repairs := rawdb.ReadAncientRepairs(r.db)
+	if len(repairs) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Drop the timed out requests, the items are requested again below
+	pending := make(map[rawdb.AncientRepair]struct{})
+	for id, req := range r.requests {
+		if time.Since(req.time) > ancientRepairTimeout {
+			delete(r.requests, id)
+			continue
+		}
+		for _, header := range req.headers {
+			pending[rawdb.AncientRepair{Number: header.Number.Uint64(), Receipts: req.receipts}] = struct{}{}
+		}
+	}
+	var bodies, receipts []*types.Header
+	for _, repair := range repairs {
+		if _, ok := pending[repair]; ok {
+			continue
+		}
+		header := rawdb.ReadHeader(r.db, rawdb.ReadCanonicalHash(r.db, repair.Number), repair.Number)
+		if header == nil {
+			log.Warn("Unable to repair ancient item without header", "number", repair.Number, "receipts", repair.Receipts)
+			continue
+		}
+		if repair.Receipts {
+			receipts = append(receipts, header)
+		} else {
+			bodies = append(bodies, header)
+		}
+	}
+	// Send the requests to idle peers, a batch of items each
+	for _, peer := range r.peers.headPeers(uint(r.peers.len())) {
+		if len(bodies) == 0 && len(receipts) == 0 {
+			return
+		}
+		if _, busy := r.requests[peer.ID()]; busy {
+			continue
+		}
+		req := &repairRequest{receipts: len(bodies) == 0, time: time.Now()}
+		if req.receipts {
+			req.headers, receipts = splitHeaders(receipts)
+		} else {
+			req.headers, bodies = splitHeaders(bodies)
+		}
+		hashes := make([]common.Hash, len(req.headers))
+		for i, header := range req.headers {
+			hashes[i] = header.Hash()
+		}
+		var err error
+		if req.receipts {
+			err = peer.RequestReceipts(hashes)
+		} else {
+			err = peer.RequestBodies(hashes)
+		}
+		if err != nil {
+			log.Debug("Failed to request ancient repair", "peer", peer.ID(), "err", err)
+			continue
+		}
+		log.Debug("Requested ancient repair", "peer", peer.ID(), "receipts", req.receipts, "items", len(hashes))
+		r.requests[peer.ID()] = req
+	}
+}
+
+// splitHeaders splits the headers of a request batch from the rest.
+func splitHeaders(headers []*types.Header) ([]*types.Header, []*types.Header) {
+	if len(headers) > ancientRepairBatch {
+		return headers[:ancientRepairBatch], headers[ancientRepairBatch:]
+	}
+	return headers, nil
+}
+
+// deliverBodies repairs the ancient bodies with the ones delivered by a peer,
+// if they are the response to an outstanding request. It returns whether the
+// bodies were consumed.
+func (r *ancientRepairer) deliverBodies(peer string, txs [][]*types.Transaction, uncles [][]*types.Header) bool {
+	req := r.response(peer, false, len(txs))
+	if req == nil {
+		return false
+	}
+	// The response must match the requested headers in order, anything else is
+	// left to the other consumers of bodies
+	hasher := trie.NewStackTrie(nil)
+	for i := range txs {
+		header := req.headers[i]
+		if types.DeriveSha(types.Transactions(txs[i]), hasher) != header.TxHash || types.CalcUncleHash(uncles[i]) != header.UncleHash {
+			return false
+		}
+	}
+	r.finish(peer)
+	for i := range txs {
+		number := req.headers[i].Number.Uint64()
+		if err := rawdb.RepairAncientBody(r.db, number, &types.Body{Transactions: txs[i], Uncles: uncles[i]}); err != nil {
+			log.Error("Failed to repair ancient body", "number", number, "err", err)
+			continue
+		}
+		log.Info("Repaired ancient block body", "number", number, "hash", req.headers[i].Hash(), "peer", peer)
+	}
+	return true
+}
+
+// deliverReceipts repairs the ancient receipts with the ones delivered by a
+// peer, if they are the response to an outstanding request. It returns whether
+// the receipts were consumed.
+func (r *ancientRepairer) deliverReceipts(peer string, receipts [][]*types.Receipt) bool {
+	req := r.response(peer, true, len(receipts))
+	if req == nil {
+		return false
+	}
+	hasher := trie.NewStackTrie(nil)
+	for i := range receipts {
+		if types.DeriveSha(types.Receipts(receipts[i]), hasher) != req.headers[i].ReceiptHash {
+			return false
+		}
+	}
+	r.finish(peer)
+	for i := range receipts {
+		number := req.headers[i].Number.Uint64()
+		if err := rawdb.RepairAncientReceipts(r.db, number, receipts[i]); err != nil {
+			log.Error("Failed to repair ancient receipts", "number", number, "err", err)
+			continue
+		}
+		log.Info("Repaired ancient receipts", "number", number, "hash", req.headers[i].Hash(), "peer", peer)
+	}
+	return true
+}
+
+// response returns the outstanding request of a peer which a delivery of the
+// given kind and size may answer. Peers may deliver less items than requested.
+func (r *ancientRepairer) response(peer string, receipts bool, items int) *repairRequest {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	req := r.requests[peer]
+	if req == nil || req.receipts != receipts || items == 0 || items > len(req.headers) {
+		return nil
+	}
+	return req
+}
+
+// finish marks the outstanding request of a peer as answered. Items not
+// delivered are requested again in the next round.
+func (r *ancientRepairer) finish(peer string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.requests, peer)
+}
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -86,6 +87,7 @@ type Backend interface {
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	exports *ExportService // Runner of chain trace exports, nil if unavailable
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -171,6 +173,12 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// Config specific to given tracer. Note struct logger
+	// config are historically embedded in main object.
+	TracerConfig json.RawMessage
+	// If set, the account and storage changes of each transaction are reported
+	// along with the trace result.
+	StateDiff bool
 }
 
 // TraceCallConfig is the config for traceCall API. It holds one more
@@ -181,6 +189,8 @@ type TraceCallConfig struct {
 	Timeout        *string
 	Reexec         *uint64
 	StateOverrides *ethapi.StateOverride
+	TracerConfig   json.RawMessage
+	StateDiff      bool
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -192,8 +202,30 @@ type StdTraceConfig struct {
 
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
-	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
-	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+	Result         interface{}                               `json:"result,omitempty"`         // Trace results produced by the tracer
+	Error          string                                    `json:"error,omitempty"`          // Trace failure produced by the tracer
+	SystemTx       bool                                      `json:"systemTx,omitempty"`       // Whether the transaction is applied by the consensus engine
+	BalanceChanges []*balanceChange                          `json:"balanceChanges,omitempty"` // Balances modified by the consensus engine ahead of the transaction
+	StateDiff      map[common.Address]*ethapi.RPCAccountDiff `json:"stateDiff,omitempty"`      // Accounts changed by the transaction, if requested
+}
+
+// response returns the result of a single transaction trace as served over RPC.
+// That is the bare trace, unless the state diff was requested or the transaction
+// is a system transaction, which are kept alongside the trace.
+func (res *txTraceResult) response(config *TraceConfig) interface{} {
+	if res.SystemTx || (config != nil && config.StateDiff) {
+		return res
+	}
+	return res.Result
+}
+
+// balanceChange is a balance modification applied by the consensus engine
+// directly, outside of the EVM.
+type balanceChange struct {
+	Address common.Address `json:"address"`
+	Prev    *hexutil.Big   `json:"prev"`
+	New     *hexutil.Big   `json:"new"`
+	Reason  string         `json:"reason"`
 }
 
 // blockTraceTask represents a single block trace task when an entire chain is
@@ -248,6 +280,20 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 	}
 	sub := notifier.CreateSubscription()
 
+	api.traceChainBlocks(start, end, config, notifier.Closed(), func(result *blockTraceResult) {
+		// Stream only the blocks with transactions to the user, and the last one
+		if len(result.Traces) > 0 || uint64(result.Block) == end.NumberU64() {
+			notifier.Notify(sub.ID, result)
+		}
+	})
+	return sub, nil
+}
+
+// traceChainBlocks traces the blocks between start (exclusive) and end in
+// parallel, delivering the results of every block in order to the callback. The
+// tracing is aborted when the closed channel is closed. The returned channel
+// reports the outcome of the tracing once all the results are delivered.
+func (api *API) traceChainBlocks(start, end *types.Block, config *TraceConfig, closed <-chan interface{}, deliver func(*blockTraceResult)) <-chan error {
 	// Prepare all the states for tracing. Note this procedure can take very
 	// long time. Timeout mechanism is necessary.
 	reexec := defaultTraceReexec
@@ -290,12 +336,12 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 					task.statedb.Finalise(api.backend.ChainConfig().IsEIP158(task.block.Number()))
-					task.results[i] = &txTraceResult{Result: res}
+					task.results[i] = res
 				}
 				// Stream the result back to the user or abort on teardown
 				select {
 				case results <- task:
-				case <-notifier.Closed():
+				case <-closed:
 					return
 				}
 			}
@@ -306,6 +352,8 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 		begin     = time.Now()
 		derefTodo []common.Hash // list of hashes to dereference from the db
 		derefsMu  sync.Mutex    // mutex for the derefs
+		failed    error         // failure of the feeding, reported after the last result
+		outcome   = make(chan error, 1)
 	)
 
 	gopool.Submit(func() {
@@ -313,7 +361,6 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 			logged  time.Time
 			number  uint64
 			traced  uint64
-			failed  error
 			parent  common.Hash
 			statedb *state.StateDB
 		)
@@ -327,6 +374,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 				log.Warn("Chain tracing failed", "start", start.NumberU64(), "end", end.NumberU64(), "transactions", traced, "elapsed", time.Since(begin), "err", failed)
 			case number < end.NumberU64():
 				log.Warn("Chain tracing aborted", "start", start.NumberU64(), "end", end.NumberU64(), "abort", number, "transactions", traced, "elapsed", time.Since(begin))
+				failed = errors.New("chain tracing aborted")
 			default:
 				log.Info("Chain tracing finished", "start", start.NumberU64(), "end", end.NumberU64(), "transactions", traced, "elapsed", time.Since(begin))
 			}
@@ -337,7 +385,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 		for number = start.NumberU64(); number < end.NumberU64(); number++ {
 			// Stop tracing if interruption was requested
 			select {
-			case <-notifier.Closed():
+			case <-closed:
 				return
 			default:
 			}
@@ -393,7 +441,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 			txs := next.Transactions()
 			select {
 			case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: next, rootref: block.Root(), results: make([]*txTraceResult, len(txs))}:
-			case <-notifier.Closed():
+			case <-closed:
 				return
 			}
 			traced += uint64(len(txs))
@@ -418,17 +466,16 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 			derefsMu.Lock()
 			derefTodo = append(derefTodo, res.rootref)
 			derefsMu.Unlock()
-			// Stream completed traces to the user, aborting on the first error
+			// Deliver the completed traces in order
 			for result, ok := done[next]; ok; result, ok = done[next] {
-				if len(result.Traces) > 0 || next == end.NumberU64() {
-					notifier.Notify(sub.ID, result)
-				}
+				deliver(result)
 				delete(done, next)
 				next++
 			}
 		}
+		outcome <- failed
 	})
-	return sub, nil
+	return outcome
 }
 
 // TraceBlockByNumber returns the structured logs created during the execution of
@@ -535,11 +582,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 
 		if posa, ok := api.backend.Engine().(consensus.PoSA); ok {
 			if isSystem, _ := posa.IsSystemTransaction(tx, block.Header()); isSystem {
-				balance := statedb.GetBalance(consensus.SystemAddress)
-				if balance.Cmp(common.Big0) > 0 {
-					statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
-					statedb.AddBalance(vmctx.Coinbase, balance)
-				}
+				distributeIncoming(statedb, vmctx.Coinbase, nil)
 			}
 		}
 
@@ -578,6 +621,13 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requestd tracer.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	return api.traceBlockTxs(ctx, block, config, nil)
+}
+
+// traceBlockTxs is like traceBlock, but only traces the transactions at the given
+// indexes, leaving the results of the others nil. The transactions following the
+// last requested one are not executed at all. A nil set traces all transactions.
+func (api *API) traceBlockTxs(ctx context.Context, block *types.Block, config *TraceConfig, indexes map[int]bool) ([]*txTraceResult, error) {
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
@@ -601,10 +651,16 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 
 		pend = new(sync.WaitGroup)
 		jobs = make(chan *txTraceTask, len(txs))
+		last = len(txs) - 1
 	)
+	if indexes != nil {
+		for last >= 0 && !indexes[last] {
+			last--
+		}
+	}
 	threads := runtime.NumCPU()
-	if threads > len(txs) {
-		threads = len(txs)
+	if threads > last+1 {
+		threads = last + 1
 	}
 	blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 	blockHash := block.Hash()
@@ -627,27 +683,28 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 					results[task.index] = &txTraceResult{Error: err.Error()}
 					continue
 				}
-				results[task.index] = &txTraceResult{Result: res}
+				results[task.index] = res
 			}
 		})
 	}
 	// Feed the transactions into the tracers and return
 	var failed error
 	blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	for i, tx := range txs {
+	for i, tx := range txs[:last+1] {
 		// Send the trace task over for execution
-		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
+		if indexes == nil || indexes[i] {
+			jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
+		}
+		if indexes != nil && i == last {
+			break
+		}
 
 		// Generate the next state snapshot fast without tracing
 		msg, _ := tx.AsMessage(signer)
 
 		if posa, ok := api.backend.Engine().(consensus.PoSA); ok {
 			if isSystem, _ := posa.IsSystemTransaction(tx, block.Header()); isSystem {
-				balance := statedb.GetBalance(consensus.SystemAddress)
-				if balance.Cmp(common.Big0) > 0 {
-					statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
-					statedb.AddBalance(block.Header().Coinbase, balance)
-				}
+				distributeIncoming(statedb, block.Header().Coinbase, nil)
 			}
 		}
 
@@ -767,11 +824,7 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 		vmenv := vm.NewEVM(vmctx, txContext, statedb, chainConfig, vmConf)
 		if posa, ok := api.backend.Engine().(consensus.PoSA); ok {
 			if isSystem, _ := posa.IsSystemTransaction(tx, block.Header()); isSystem {
-				balance := statedb.GetBalance(consensus.SystemAddress)
-				if balance.Cmp(common.Big0) > 0 {
-					statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
-					statedb.AddBalance(vmctx.Coinbase, balance)
-				}
+				distributeIncoming(statedb, vmctx.Coinbase, nil)
 			}
 		}
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
@@ -810,7 +863,9 @@ func containsTx(block *types.Block, hash common.Hash) bool {
 }
 
 // TraceTransaction returns the structured logs created during the execution of EVM
-// and returns them as a JSON object.
+// and returns them as a JSON object. If the state diff is requested, or the
+// transaction is a system transaction, the trace is returned wrapped instead as
+// {result, stateDiff, systemTx, balanceChanges}, omitting the empty fields.
 func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (interface{}, error) {
 	_, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
 	if err != nil {
@@ -837,13 +892,19 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 		TxIndex:   int(index),
 		TxHash:    hash,
 	}
-	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	res, err := api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	if err != nil {
+		return nil, err
+	}
+	return res.response(config), nil
 }
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs
 // created during the execution of EVM if the given transaction was added on
 // top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
+// If the state diff is requested, the trace is returned wrapped instead as
+// {result, stateDiff}, with the diff relative to the overridden state.
 func (api *API) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
 	// Try to retrieve the specified block
 	var (
@@ -882,19 +943,26 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHa
 	var traceConfig *TraceConfig
 	if config != nil {
 		traceConfig = &TraceConfig{
-			LogConfig: config.LogConfig,
-			Tracer:    config.Tracer,
-			Timeout:   config.Timeout,
-			Reexec:    config.Reexec,
+			LogConfig:    config.LogConfig,
+			Tracer:       config.Tracer,
+			Timeout:      config.Timeout,
+			Reexec:       config.Reexec,
+			TracerConfig: config.TracerConfig,
+			StateDiff:    config.StateDiff,
 		}
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	res, err := api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	if err != nil {
+		return nil, err
+	}
+	return res.response(traceConfig), nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
-// executes the given message in the provided environment. The return value will
-// be tracer dependent.
-func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+// executes the given message in the provided environment. The result of the trace
+// will be tracer dependent, while system transactions applied by the consensus
+// engine are flagged along with the balance changes the engine makes ahead.
+func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (*txTraceResult, error) {
 	// Assemble the structured logger or the JavaScript tracer
 	var (
 		tracer    vm.EVMLogger
@@ -912,7 +980,7 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 				return nil, err
 			}
 		}
-		if t, err := New(*config.Tracer, txctx); err != nil {
+		if t, err := New(*config.Tracer, txctx, config.TracerConfig); err != nil {
 			return nil, err
 		} else {
 			deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -931,15 +999,22 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer})
 
+	res := new(txTraceResult)
 	if posa, ok := api.backend.Engine().(consensus.PoSA); ok && message.From() == vmctx.Coinbase &&
 		posa.IsSystemContract(message.To()) && message.GasPrice().Cmp(big.NewInt(0)) == 0 {
-		balance := statedb.GetBalance(consensus.SystemAddress)
-		if balance.Cmp(common.Big0) > 0 {
-			statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
-			statedb.AddBalance(vmctx.Coinbase, balance)
+		res.SystemTx = true
+		if tracer, ok := tracer.(SystemTracer); ok {
+			tracer.CaptureSystemTx()
 		}
+		res.BalanceChanges = distributeIncoming(statedb, vmctx.Coinbase, tracer)
 	}
 
+	// Seal any changes made ahead of the transaction, so that the journal only
+	// holds the ones made by the transaction to derive the state diff from.
+	// Empty accounts are kept as state overrides might have set them up.
+	if config != nil && config.StateDiff {
+		statedb.Finalise(false)
+	}
 	// Call Prepare to clear out the statedb access list
 	statedb.Prepare(txctx.TxHash, txctx.BlockHash, txctx.TxIndex)
 
@@ -947,6 +1022,10 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
+	if config != nil && config.StateDiff {
+		deleteEmptyObjects := api.backend.ChainConfig().IsEIP158(vmctx.BlockNumber)
+		res.StateDiff = ethapi.NewRPCStateDiff(statedb.JournalDiff(deleteEmptyObjects))
+	}
 
 	// Depending on the tracer type, format and return the output.
 	switch tracer := tracer.(type) {
@@ -956,29 +1035,67 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 		if len(result.Revert()) > 0 {
 			returnVal = fmt.Sprintf("%x", result.Revert())
 		}
-		return &ethapi.ExecutionResult{
+		res.Result = &ethapi.ExecutionResult{
 			Gas:         result.UsedGas,
 			Failed:      result.Failed(),
 			ReturnValue: returnVal,
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
+		}
+		return res, nil
 
 	case Tracer:
-		return tracer.GetResult()
+		if res.Result, err = tracer.GetResult(); err != nil {
+			return nil, err
+		}
+		return res, nil
 
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
 }
 
-// APIs return the collection of RPC services the tracer package offers.
-func APIs(backend Backend) []rpc.API {
+// distributeIncoming moves the fees collected at the system address over to the
+// coinbase, as Parlia does before distributing them with its system transactions.
+// The balance changes are returned and reported to the tracer if it's interested.
+func distributeIncoming(statedb *state.StateDB, coinbase common.Address, tracer vm.EVMLogger) []*balanceChange {
+	balance := new(big.Int).Set(statedb.GetBalance(consensus.SystemAddress))
+	if balance.Cmp(common.Big0) <= 0 {
+		return nil
+	}
+	prev := new(big.Int).Set(statedb.GetBalance(coinbase))
+	changes := []*balanceChange{
+		{Address: consensus.SystemAddress, Prev: (*hexutil.Big)(balance), New: (*hexutil.Big)(new(big.Int)), Reason: "distributeIncoming"},
+		{Address: coinbase, Prev: (*hexutil.Big)(prev), New: (*hexutil.Big)(new(big.Int).Add(prev, balance)), Reason: "distributeIncoming"},
+	}
+	if tracer, ok := tracer.(SystemTracer); ok {
+		for _, change := range changes {
+			tracer.CaptureBalanceChange(change.Address, change.Prev.ToInt(), change.New.ToInt(), change.Reason)
+		}
+	}
+	statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
+	statedb.AddBalance(coinbase, balance)
+	return changes
+}
+
+// APIs return the collection of RPC services the tracer package offers, with
+// chain trace exports run by the given service.
+func APIs(backend Backend, exports *ExportService) []rpc.API {
+	api := &API{backend: backend, exports: exports}
+	if exports != nil {
+		exports.api = api
+	}
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
 			Version:   "1.0",
-			Service:   NewAPI(backend),
+			Service:   api,
+			Public:    false,
+		},
+		{
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewTraceAPI(backend),
 			Public:    false,
 		},
 	}
@@ -416,6 +416,283 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+// testPoSA is a PoSA engine treating transactions of the coinbase to a single
+// contract as system transactions.
+type testPoSA struct {
+	consensus.Engine
+	contract common.Address
+}
+
+func (p *testPoSA) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	if err != nil {
+		return false, err
+	}
+	return sender == header.Coinbase && p.IsSystemContract(tx.To()) && tx.GasPrice().Sign() == 0, nil
+}
+
+func (p *testPoSA) IsSystemContract(to *common.Address) bool { return to != nil && *to == p.contract }
+
+func (p *testPoSA) EnoughDistance(chain consensus.ChainReader, header *types.Header) bool {
+	return true
+}
+
+func (p *testPoSA) IsLocalBlock(header *types.Header) bool { return false }
+
+func (p *testPoSA) AllowLightProcess(chain consensus.ChainReader, currentHeader *types.Header) bool {
+	return false
+}
+
+func (p *testPoSA) SignDiffLayer(header *types.Header, diff *types.DiffLayer) error { return nil }
+
+func (p *testPoSA) VerifyDiffLayer(chain consensus.ChainHeaderReader, header *types.Header, diff *types.DiffLayer) error {
+	return nil
+}
+
+// Tests that system transactions are flagged in block traces, along with the
+// fees the consensus engine moves to the coinbase ahead of them.
+func TestTraceBlockSystemTx(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	contract := common.Address{0x10, 0x00}
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr:        {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr:        {Balance: big.NewInt(params.Ether)},
+		consensus.SystemAddress: {Balance: big.NewInt(1000)},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(accounts[0].addr)
+
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[0].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[1].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(0, contract, big.NewInt(0), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	backend.engine = &testPoSA{Engine: backend.engine, contract: contract}
+
+	results, err := NewAPI(backend).TraceBlockByNumber(context.Background(), rpc.BlockNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(results))
+	}
+	if results[0].SystemTx || results[0].BalanceChanges != nil {
+		t.Errorf("user transaction flagged as system transaction: %+v", results[0])
+	}
+	if !results[1].SystemTx {
+		t.Errorf("system transaction not flagged")
+	}
+	coinbase := new(big.Int).Add(big.NewInt(params.Ether), big.NewInt(1000))
+	want := []*balanceChange{
+		{Address: consensus.SystemAddress, Prev: (*hexutil.Big)(big.NewInt(1000)), New: (*hexutil.Big)(new(big.Int)), Reason: "distributeIncoming"},
+		{Address: accounts[0].addr, Prev: (*hexutil.Big)(coinbase), New: (*hexutil.Big)(new(big.Int).Add(coinbase, big.NewInt(1000))), Reason: "distributeIncoming"},
+	}
+	have, _ := json.Marshal(results[1].BalanceChanges)
+	if wantJSON, _ := json.Marshal(want); !bytes.Equal(have, wantJSON) {
+		t.Errorf("balance changes mismatch: have %s, want %s", have, wantJSON)
+	}
+}
+
+// Tests that single system transaction traces carry their flag and the balance
+// changes of the consensus engine, while user transactions trace bare.
+func TestTraceTransactionSystemTx(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	contract := common.Address{0x10, 0x00}
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr:        {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr:        {Balance: big.NewInt(params.Ether)},
+		consensus.SystemAddress: {Balance: big.NewInt(1000)},
+	}}
+	signer := types.HomesteadSigner{}
+	var hashes []common.Hash
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(accounts[0].addr)
+
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[0].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[1].key)
+		b.AddTx(tx)
+		hashes = append(hashes, tx.Hash())
+		tx, _ = types.SignTx(types.NewTransaction(0, contract, big.NewInt(0), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		hashes = append(hashes, tx.Hash())
+	})
+	backend.engine = &testPoSA{Engine: backend.engine, contract: contract}
+	api := NewAPI(backend)
+
+	result, err := api.TraceTransaction(context.Background(), hashes[0], nil)
+	if err != nil {
+		t.Fatalf("failed to trace user transaction: %v", err)
+	}
+	if _, ok := result.(*ethapi.ExecutionResult); !ok {
+		t.Errorf("user transaction trace wrapped: %T", result)
+	}
+	result, err = api.TraceTransaction(context.Background(), hashes[1], nil)
+	if err != nil {
+		t.Fatalf("failed to trace system transaction: %v", err)
+	}
+	res, ok := result.(*txTraceResult)
+	if !ok {
+		t.Fatalf("system transaction trace not wrapped: %T", result)
+	}
+	if !res.SystemTx || len(res.BalanceChanges) != 2 {
+		t.Errorf("system transaction trace mismatch: flag %v, balance changes %d", res.SystemTx, len(res.BalanceChanges))
+	}
+	if _, ok := res.Result.(*ethapi.ExecutionResult); !ok {
+		t.Errorf("system transaction trace result mismatch: %T", res.Result)
+	}
+}
+
+// Tests that the state diff of a transaction covers the balance, nonce and
+// storage changes it made.
+func TestTraceStateDiff(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	contract := common.Address{0xc0}
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		contract:         {Balance: common.Big0, Code: common.FromHex("600160005500")}, // sstore(0, 1)
+	}}
+	signer := types.HomesteadSigner{}
+	api := NewAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[1].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	}))
+	ether := big.NewInt(params.Ether)
+
+	// Trace a transfer included in the chain
+	block, _ := api.backend.BlockByNumber(context.Background(), 1)
+	res, err := api.TraceTransaction(context.Background(), block.Transactions()[0].Hash(), &TraceConfig{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	want := map[common.Address]*ethapi.RPCAccountDiff{
+		accounts[0].addr: {
+			Pre:  &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(ether)},
+			Post: &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(new(big.Int).Sub(ether, big.NewInt(1000))), Nonce: 1},
+		},
+		accounts[1].addr: {
+			Post: &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(big.NewInt(1000))},
+		},
+	}
+	have, _ := json.Marshal(res.(*txTraceResult).StateDiff)
+	if wantJSON, _ := json.Marshal(want); !bytes.Equal(have, wantJSON) {
+		t.Errorf("transaction state diff mismatch: have %s, want %s", have, wantJSON)
+	}
+	// Trace a call writing to storage on top of the chain
+	res, err = api.TraceCall(context.Background(), ethapi.CallArgs{
+		From:  &accounts[1].addr,
+		To:    &contract,
+		Value: (*hexutil.Big)(big.NewInt(10)),
+	}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &TraceCallConfig{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	code := hexutil.Bytes(common.FromHex("600160005500"))
+	want = map[common.Address]*ethapi.RPCAccountDiff{
+		accounts[1].addr: {
+			Pre:  &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(big.NewInt(1000))},
+			Post: &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(big.NewInt(990)), Nonce: 1},
+		},
+		contract: {
+			Pre: &ethapi.RPCDiffAccount{
+				Balance: (*hexutil.Big)(new(big.Int)),
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{{}: {}},
+			},
+			Post: &ethapi.RPCDiffAccount{
+				Balance: (*hexutil.Big)(big.NewInt(10)),
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{{}: common.BigToHash(common.Big1)},
+			},
+		},
+	}
+	have, _ = json.Marshal(res.(*txTraceResult).StateDiff)
+	if wantJSON, _ := json.Marshal(want); !bytes.Equal(have, wantJSON) {
+		t.Errorf("call state diff mismatch: have %s, want %s", have, wantJSON)
+	}
+}
+
+func TestTraceCallStateDiffWithOverrides(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	api := NewAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+
+	// Override a caller without funds and a contract with preset storage, the
+	// overridden values must be reported as the pre state, not as changes
+	var (
+		contract = common.Address{0xc0}
+		code     = hexutil.Bytes(common.FromHex("600160005500")) // sstore(0, 1)
+		slot     = common.BigToHash(common.Big1)
+		preset   = common.BigToHash(common.Big2)
+	)
+	config := &TraceCallConfig{
+		StateDiff: true,
+		StateOverrides: &ethapi.StateOverride{
+			accounts[1].addr: ethapi.OverrideAccount{Balance: newRPCBalance(big.NewInt(1000))},
+			contract: ethapi.OverrideAccount{
+				Code:  &code,
+				State: &map[common.Hash]common.Hash{{}: preset, slot: preset},
+			},
+		},
+	}
+	call := ethapi.CallArgs{
+		From:  &accounts[1].addr,
+		To:    &contract,
+		Value: (*hexutil.Big)(big.NewInt(10)),
+	}
+	res, err := api.TraceCall(context.Background(), call, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	result, ok := res.(*txTraceResult)
+	if !ok {
+		t.Fatalf("state diff trace not wrapped: have %T", res)
+	}
+	if result.Result == nil {
+		t.Fatalf("missing trace result")
+	}
+	want := map[common.Address]*ethapi.RPCAccountDiff{
+		accounts[1].addr: {
+			Pre:  &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(big.NewInt(1000))},
+			Post: &ethapi.RPCDiffAccount{Balance: (*hexutil.Big)(big.NewInt(990)), Nonce: 1},
+		},
+		contract: {
+			Pre: &ethapi.RPCDiffAccount{
+				Balance: (*hexutil.Big)(new(big.Int)),
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{{}: preset},
+			},
+			Post: &ethapi.RPCDiffAccount{
+				Balance: (*hexutil.Big)(big.NewInt(10)),
+				Code:    code,
+				Storage: map[common.Hash]common.Hash{{}: common.BigToHash(common.Big1)},
+			},
+		},
+	}
+	have, _ := json.Marshal(result.StateDiff)
+	if wantJSON, _ := json.Marshal(want); !bytes.Equal(have, wantJSON) {
+		t.Errorf("state diff mismatch: have %s, want %s", have, wantJSON)
+	}
+	// Without the state diff, the bare trace is returned
+	config.StateDiff = false
+	res, err = api.TraceCall(context.Background(), call, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if _, ok := res.(*txTraceResult); ok {
+		t.Fatalf("plain trace wrapped in result object")
+	}
+}
+
 func TestTracingWithOverrides(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -0,0 +1,334 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// flatCallTracer is the name of the native tracer producing the call frames
+	// in the format of the `trace` namespace.
+	flatCallTracer = "flatCallTracer"
+
+	// maxTraceFilterUnindexed is the maximum number of blocks trace_filter is
+	// willing to re-execute in full, because they are not covered by the trace
+	// index or the filter has no addresses to look up.
+	maxTraceFilterUnindexed = 1000
+)
+
+// TraceAPI is the collection of Parity compatible tracing APIs exposed over the
+// `trace` namespace. All of them produce flat call traces.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the `trace` namespace.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// TraceFilterArgs represents the arguments of trace_filter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// TraceResults is the result of replaying a transaction with trace_replay*.
+type TraceResults struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       interface{}       `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VmTrace         interface{}       `json:"vmTrace"`
+	TransactionHash common.Hash       `json:"transactionHash"`
+}
+
+// flatTrace is the subset of the fields of a flat call trace needed to filter
+// and summarize it.
+type flatTrace struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+		Output  hexutil.Bytes   `json:"output"`
+	} `json:"result"`
+	Type string `json:"type"`
+}
+
+// Block returns the flat call traces of all the transactions in a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.traceBlock(ctx, block, nil)
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, txTraces := range results {
+		traces = append(traces, txTraces...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the flat call traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	tracer := flatCallTracer
+	res, err := api.api.TraceTransaction(ctx, hash, &TraceConfig{Tracer: &tracer})
+	if err != nil {
+		return nil, err
+	}
+	var traces []json.RawMessage
+	if err := json.Unmarshal(res.(json.RawMessage), &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// ReplayBlockTransactions replays all the transactions in a block, returning
+// the requested traces of each. Only the "trace" type is supported.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	for _, typ := range traceTypes {
+		if typ != "trace" {
+			return nil, fmt.Errorf("unsupported trace type %q", typ)
+		}
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	results, err := api.traceBlock(ctx, block, nil)
+	if err != nil {
+		return nil, err
+	}
+	replays := make([]*TraceResults, len(results))
+	for i, txTraces := range results {
+		replays[i] = &TraceResults{
+			Output:          hexutil.Bytes{},
+			Trace:           []json.RawMessage{},
+			TransactionHash: block.Transactions()[i].Hash(),
+		}
+		if len(txTraces) > 0 {
+			var top flatTrace
+			if err := json.Unmarshal(txTraces[0], &top); err != nil {
+				return nil, err
+			}
+			if top.Result != nil && top.Type == "call" {
+				replays[i].Output = top.Result.Output
+			}
+		}
+		if len(traceTypes) > 0 {
+			replays[i].Trace = txTraces
+		}
+	}
+	return replays, nil
+}
+
+// Filter returns the flat call traces within a block range matching the given
+// sender and recipient addresses. If the background trace indexer is enabled,
+// only the transactions recorded in the index as involving the addresses are
+// re-executed for the indexed part of the range.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.blockNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, errors.New("invalid block range")
+	}
+	if from == 0 {
+		from = 1 // genesis is not traceable, but has no traces either
+	}
+	var (
+		fromAddrs = make(map[common.Address]bool)
+		toAddrs   = make(map[common.Address]bool)
+	)
+	for _, addr := range args.FromAddress {
+		fromAddrs[addr] = true
+	}
+	for _, addr := range args.ToAddress {
+		toAddrs[addr] = true
+	}
+	// Look up the candidate transactions of the indexed part of the range
+	var (
+		db         = api.api.backend.ChainDb()
+		tail, head = rawdb.ReadTraceIndexTail(db), rawdb.ReadTraceIndexHead(db)
+		indexed    = func(number uint64) bool { return false }
+		candidates = make(map[uint64]map[int]bool)
+		useIndex   = tail != nil && head != nil && (len(fromAddrs) > 0 || len(toAddrs) > 0)
+	)
+	if useIndex {
+		indexed = func(number uint64) bool { return number >= *tail && number <= *head }
+
+		// Any trace matching the filter is sent by one of the senders, so it's
+		// enough to look up either side
+		lookup, direction := fromAddrs, rawdb.TraceIndexFrom
+		if len(lookup) == 0 {
+			lookup, direction = toAddrs, rawdb.TraceIndexTo
+		}
+		for addr := range lookup {
+			for _, entry := range rawdb.ReadTraceIndex(db, addr, from, to) {
+				if entry.Directions&direction == 0 {
+					continue
+				}
+				if candidates[entry.Number] == nil {
+					candidates[entry.Number] = make(map[int]bool)
+				}
+				candidates[entry.Number][int(entry.TxIndex)] = true
+			}
+		}
+	}
+	unindexed := to - from + 1
+	if useIndex && from <= *head && to >= *tail {
+		lo, hi := from, to
+		if lo < *tail {
+			lo = *tail
+		}
+		if hi > *head {
+			hi = *head
+		}
+		unindexed -= hi - lo + 1
+	}
+	if unindexed > maxTraceFilterUnindexed {
+		return nil, fmt.Errorf("too many unindexed blocks to trace: %d, limit %d", unindexed, maxTraceFilterUnindexed)
+	}
+	// Trace the candidate transactions and filter their call frames
+	var (
+		traces = []json.RawMessage{}
+		after  uint64
+	)
+	if args.After != nil {
+		after = *args.After
+	}
+	for number := from; number <= to; number++ {
+		var txs map[int]bool
+		if indexed(number) {
+			if txs = candidates[number]; txs == nil {
+				continue
+			}
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		results, err := api.traceBlock(ctx, block, txs)
+		if err != nil {
+			return nil, err
+		}
+		for _, txTraces := range results {
+			for _, trace := range txTraces {
+				var frame flatTrace
+				if err := json.Unmarshal(trace, &frame); err != nil {
+					return nil, err
+				}
+				if !frame.matches(fromAddrs, toAddrs) {
+					continue
+				}
+				if after > 0 {
+					after--
+					continue
+				}
+				traces = append(traces, trace)
+				if args.Count != nil && uint64(len(traces)) >= *args.Count {
+					return traces, nil
+				}
+			}
+		}
+	}
+	return traces, nil
+}
+
+// traceBlock traces the given transactions of a block with the flat call tracer,
+// returning the call frames of each. A nil set traces all transactions.
+func (api *TraceAPI) traceBlock(ctx context.Context, block *types.Block, txs map[int]bool) ([][]json.RawMessage, error) {
+	tracer := flatCallTracer
+	results, err := api.api.traceBlockTxs(ctx, block, &TraceConfig{Tracer: &tracer}, txs)
+	if err != nil {
+		return nil, err
+	}
+	traces := make([][]json.RawMessage, len(results))
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d of block %d failed: %s", i, block.NumberU64(), result.Error)
+		}
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &traces[i]); err != nil {
+			return nil, err
+		}
+	}
+	return traces, nil
+}
+
+// blockNumber resolves a block number of the filter, defaulting to the latest.
+func (api *TraceAPI) blockNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number != nil && *number >= 0 {
+		return uint64(*number), nil
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
+
+// matches returns whether the call frame is sent by one of the senders and
+// received by one of the recipients. An empty set matches any address.
+func (t *flatTrace) matches(fromAddrs, toAddrs map[common.Address]bool) bool {
+	var from, to *common.Address
+	switch t.Type {
+	case "suicide":
+		from, to = t.Action.Address, t.Action.RefundAddress
+	case "create":
+		from = t.Action.From
+		if t.Result != nil {
+			to = t.Result.Address
+		}
+	default:
+		from, to = t.Action.From, t.Action.To
+	}
+	if len(fromAddrs) > 0 && (from == nil || !fromAddrs[*from]) {
+		return false
+	}
+	if len(toAddrs) > 0 && (to == nil || !toAddrs[*to]) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func init() {
+	// The native flat call tracer can't be imported here, register a minimal
+	// stand-in producing the fields the trace API relies on.
+	RegisterLookup(false, func(name string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		if name != flatCallTracer {
+			return nil, errors.New("not found")
+		}
+		return &testFlatTracer{addressCollector: newAddressCollector()}, nil
+	})
+}
+
+type testFlatTracer struct {
+	*addressCollector
+	number uint64
+	frames []map[string]interface{}
+}
+
+func (t *testFlatTracer) frame(from, to common.Address) {
+	t.frames = append(t.frames, map[string]interface{}{
+		"action":      map[string]interface{}{"from": from, "to": to},
+		"blockNumber": t.number,
+		"type":        "call",
+	})
+}
+
+func (t *testFlatTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.number = env.Context.BlockNumber.Uint64()
+	t.frame(from, to)
+}
+
+func (t *testFlatTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.frame(from, to)
+}
+
+func (t *testFlatTracer) GetResult() (json.RawMessage, error) { return json.Marshal(t.frames) }
+func (t *testFlatTracer) Stop(err error)                      {}
+
+// Tests that the trace indexer records the addresses of internal calls, and
+// that trace_filter finds the matching calls both within and outside of the
+// indexed range.
+func TestTraceFilter(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	var (
+		caller = common.Address{0xca}
+		target = common.Address{0xdd}
+	)
+	// The caller contract calls the target without any data or value
+	code := append(common.FromHex("60006000600060006000"), append(append([]byte{byte(vm.PUSH20)}, target.Bytes()...), byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))...)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		caller:           {Code: code, Balance: common.Big0},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, traceIndexConfirmations+10, genesis, func(i int, b *core.BlockGen) {
+		// Blocks with an even number call the caller contract, the others
+		// transfer to the second account.
+		to, gas := accounts[1].addr, params.TxGas
+		if (i+1)%2 == 0 {
+			to, gas = caller, 100000
+		}
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(0), gas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	// Index the blocks from genesis on, stopping at the confirmation depth
+	db := backend.ChainDb()
+	rawdb.WriteTraceIndexHead(db, 0)
+	if err := NewTraceIndexer(backend).index(); err != nil {
+		t.Fatalf("failed to index: %v", err)
+	}
+	if tail, head := rawdb.ReadTraceIndexTail(db), rawdb.ReadTraceIndexHead(db); tail == nil || *tail != 1 || head == nil || *head != 10 {
+		t.Fatalf("index range mismatch: have %v-%v, want 1-10", tail, head)
+	}
+	entries := rawdb.ReadTraceIndex(db, target, 1, 10)
+	if len(entries) != 5 {
+		t.Fatalf("target entries mismatch: have %d, want 5", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Number != uint64(2*i+2) || entry.TxIndex != 0 || entry.Directions != rawdb.TraceIndexTo {
+			t.Errorf("entry %d mismatch: %+v", i, entry)
+		}
+	}
+	// Drop an entry of the target from the index, filtering by the target must
+	// rely on the index and skip it, unlike filtering by the caller
+	db.Delete(append(append(append([]byte("tA"), target.Bytes()...), 0, 0, 0, 0, 0, 0, 0, 4), 0, 0, 0, 0))
+
+	api := NewTraceAPI(backend)
+	filter := func(args TraceFilterArgs) []uint64 {
+		traces, err := api.Filter(context.Background(), args)
+		if err != nil {
+			t.Fatalf("failed to filter traces: %v", err)
+		}
+		var numbers []uint64
+		for _, trace := range traces {
+			var frame struct {
+				BlockNumber uint64 `json:"blockNumber"`
+			}
+			if err := json.Unmarshal(trace, &frame); err != nil {
+				t.Fatalf("failed to decode trace: %v", err)
+			}
+			numbers = append(numbers, frame.BlockNumber)
+		}
+		return numbers
+	}
+	var (
+		from, to = rpc.BlockNumber(1), rpc.BlockNumber(14)
+		after    = uint64(1)
+		count    = uint64(3)
+	)
+	tests := []struct {
+		args TraceFilterArgs
+		want []uint64
+	}{
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, ToAddress: []common.Address{target}}, []uint64{2, 6, 8, 10, 12, 14}},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, FromAddress: []common.Address{caller}, ToAddress: []common.Address{target}, After: &after, Count: &count}, []uint64{4, 6, 8}},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, FromAddress: []common.Address{accounts[1].addr}}, nil},
+		{TraceFilterArgs{FromBlock: &to, ToBlock: &to}, []uint64{14, 14}},
+	}
+	for i, tt := range tests {
+		have := filter(tt.args)
+		if len(have) != len(tt.want) {
+			t.Errorf("test %d: result mismatch: have %v, want %v", i, have, tt.want)
+			continue
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: result mismatch: have %v, want %v", i, have, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,502 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/tsdb/fileutil"
+)
+
+const (
+	// traceExportChunk is the number of blocks whose traces are bundled into a
+	// single export file. Files are only moved into place once complete, so an
+	// interrupted export loses at most one chunk of work.
+	traceExportChunk = 1000
+
+	// traceExportCheckpoint is the name of the file tracking the progress of
+	// the export within the export directory.
+	traceExportCheckpoint = "checkpoint.json"
+
+	// traceExportRegistry is the name of the file tracking the exports not yet
+	// finished within the directory of the export service.
+	traceExportRegistry = "exports.json"
+)
+
+// exportCheckpoint is the progress of a chain trace export, persisted in the
+// export directory to resume from after an interruption.
+type exportCheckpoint struct {
+	From   uint64 `json:"from"`
+	To     uint64 `json:"to"`
+	Tracer string `json:"tracer"`
+	Next   uint64 `json:"next"` // First block not yet exported
+}
+
+// exportRecord is a chain trace export not yet finished, persisted by the export
+// service to restart it along with the node.
+type exportRecord struct {
+	From   uint64       `json:"from"`
+	To     uint64       `json:"to"`
+	Config *TraceConfig `json:"config"`
+}
+
+// ExportService runs the chain trace exports started over RPC. It confines them
+// to subdirectories of its export directory, aborts them when the node shuts
+// down and restarts them when it starts up again, implementing node.Lifecycle.
+type ExportService struct {
+	dir  string // Directory holding the export directories, empty if unavailable
+	api  *API   // API tracing the exported blocks, set once the APIs are created
+	quit chan interface{}
+	wg   sync.WaitGroup
+
+	lock sync.Mutex // Protects the registry of the unfinished exports
+}
+
+// NewExportService creates a service running chain trace exports into the
+// subdirectories of dir.
+func NewExportService(dir string) *ExportService {
+	return &ExportService{
+		dir:  dir,
+		quit: make(chan interface{}),
+	}
+}
+
+// Start implements node.Lifecycle, restarting the exports which were not yet
+// finished when the node shut down. New exports are started on demand.
+func (s *ExportService) Start() error {
+	if s.dir == "" || s.api == nil {
+		return nil
+	}
+	s.lock.Lock()
+	records, err := s.records()
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	for dir, record := range records {
+		next, err := s.start(s.api, dir, record.From, record.To, record.Config)
+		if err != nil {
+			log.Warn("Failed to resume chain trace export", "dir", dir, "err", err)
+			s.unregister(dir)
+			continue
+		}
+		log.Info("Resumed chain trace export", "dir", dir, "next", next, "to", record.To)
+	}
+	return nil
+}
+
+// Stop implements node.Lifecycle, aborting the running exports. Their progress
+// is checkpointed, so they can be resumed after a restart.
+func (s *ExportService) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+	return nil
+}
+
+// resolve returns the path of an export directory given relative to the export
+// directory of the service, rejecting anything reaching outside of it.
+func (s *ExportService) resolve(dir string) (string, error) {
+	if s.dir == "" {
+		return "", errors.New("chain trace export requires a data directory")
+	}
+	if dir == "" || filepath.IsAbs(dir) {
+		return "", fmt.Errorf("export directory %q must be a relative path", dir)
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(dir), "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("export directory %q must not contain '..'", dir)
+		}
+	}
+	if dir = filepath.Clean(dir); dir == "." {
+		return "", fmt.Errorf("export directory %q must not be empty", dir)
+	}
+	return filepath.Join(s.dir, dir), nil
+}
+
+// start runs an export into the given directory in the background, registering
+// it to be restarted along with the node until finished. The first block left to
+// be traced is returned.
+func (s *ExportService) start(api *API, dir string, first, last uint64, config *TraceConfig) (uint64, error) {
+	path, err := s.resolve(dir)
+	if err != nil {
+		return 0, err
+	}
+	// Register the export before checking for shutdown, so Stop either waits
+	// for it or the export sees the closed quit channel
+	s.wg.Add(1)
+	select {
+	case <-s.quit:
+		s.wg.Done()
+		return 0, errors.New("node shutting down")
+	default:
+	}
+	exporter, err := newChainExporter(api, path, first, last, config)
+	if err != nil {
+		s.wg.Done()
+		return 0, err
+	}
+	if err := s.register(dir, &exportRecord{From: first, To: last, Config: config}); err != nil {
+		exporter.lock.Release()
+		s.wg.Done()
+		return 0, err
+	}
+	next := exporter.checkpoint.Next
+	go func() {
+		defer s.wg.Done()
+
+		err := exporter.run(s.quit)
+		if err != nil {
+			log.Error("Chain trace export failed", "dir", path, "err", err)
+		}
+		// Exports interrupted by the shutdown are resumed on the next startup,
+		// failed ones only when requested again
+		select {
+		case <-s.quit:
+			if err != nil {
+				return
+			}
+		default:
+		}
+		s.unregister(dir)
+	}()
+	return next, nil
+}
+
+// records loads the registry of the unfinished exports, keyed by their directory
+// relative to the export directory of the service.
+//
+// Note, this method assumes that the registry lock is held!
+func (s *ExportService) records() (map[string]*exportRecord, error) {
+	records := make(map[string]*exportRecord)
+	blob, err := ioutil.ReadFile(filepath.Join(s.dir, traceExportRegistry))
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &records); err != nil {
+		return nil, fmt.Errorf("invalid export registry: %v", err)
+	}
+	return records, nil
+}
+
+// register adds an export to the registry of the unfinished exports.
+func (s *ExportService) register(dir string, record *exportRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	records, err := s.records()
+	if err != nil {
+		return err
+	}
+	records[filepath.ToSlash(filepath.Clean(dir))] = record
+	return s.storeRecords(records)
+}
+
+// unregister removes a finished export from the registry of the unfinished
+// exports.
+func (s *ExportService) unregister(dir string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	records, err := s.records()
+	if err == nil {
+		delete(records, filepath.ToSlash(filepath.Clean(dir)))
+		err = s.storeRecords(records)
+	}
+	if err != nil {
+		log.Error("Failed to unregister chain trace export", "dir", dir, "err", err)
+	}
+}
+
+// storeRecords atomically replaces the registry of the unfinished exports.
+//
+// Note, this method assumes that the registry lock is held!
+func (s *ExportService) storeRecords(records map[string]*exportRecord) error {
+	blob, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, traceExportRegistry)
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// TraceChainToFiles traces the blocks between from and to (both inclusive) with
+// the given tracer in the background, writing the traces of every block as a line
+// of JSON into gzip compressed files in dir, which is relative to the trace export
+// directory of the node. The export keeps a checkpoint in the directory and is
+// resumed from it when the node is restarted, or when the method is called again
+// with the same arguments. The first block left to be traced is returned.
+func (api *API) TraceChainToFiles(ctx context.Context, from, to rpc.BlockNumber, tracer string, dir string, config *TraceConfig) (hexutil.Uint64, error) {
+	if api.exports == nil {
+		return 0, errors.New("chain trace export unavailable")
+	}
+	if _, err := api.exports.resolve(dir); err != nil {
+		return 0, err
+	}
+	first, err := api.blockByNumber(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	last, err := api.blockByNumber(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+	cfg := new(TraceConfig)
+	if config != nil {
+		*cfg = *config
+	}
+	if tracer != "" {
+		cfg.Tracer = &tracer
+	}
+	next, err := api.exports.start(api, dir, first.NumberU64(), last.NumberU64(), cfg)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(next), nil
+}
+
+// ExportChain traces the blocks between first and last (both inclusive) into the
+// given directory the same way as TraceChainToFiles, but blocks until done or
+// until the abort channel is closed.
+func (api *API) ExportChain(first, last uint64, dir string, config *TraceConfig, abort <-chan interface{}) error {
+	exporter, err := newChainExporter(api, dir, first, last, config)
+	if err != nil {
+		return err
+	}
+	return exporter.run(abort)
+}
+
+// chainExporter writes the traces of a block range into chunked files.
+type chainExporter struct {
+	api        *API
+	dir        string
+	config     *TraceConfig
+	checkpoint *exportCheckpoint
+	lock       fileutil.Releaser
+
+	file *os.File     // Temporary file of the chunk being written
+	gzip *gzip.Writer // Compressor of the chunk being written
+	end  uint64       // Last block of the chunk being written
+}
+
+// newChainExporter locks the export directory and loads the checkpoint of any
+// previous export into it, which must have been started with the same range
+// and tracer.
+func newChainExporter(api *API, dir string, first, last uint64, config *TraceConfig) (*chainExporter, error) {
+	if first == 0 {
+		first = 1 // genesis is not traceable
+	}
+	if first > last {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", last, first)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, _, err := fileutil.Flock(filepath.Join(dir, "LOCK"))
+	if err != nil {
+		return nil, fmt.Errorf("export directory in use: %v", err)
+	}
+	var tracer string
+	if config != nil && config.Tracer != nil {
+		tracer = *config.Tracer
+	}
+	checkpoint := &exportCheckpoint{From: first, To: last, Tracer: tracer, Next: first}
+	if blob, err := ioutil.ReadFile(filepath.Join(dir, traceExportCheckpoint)); err == nil {
+		var stored exportCheckpoint
+		if err := json.Unmarshal(blob, &stored); err != nil {
+			lock.Release()
+			return nil, fmt.Errorf("invalid export checkpoint: %v", err)
+		}
+		if stored.From != first || stored.To != last || stored.Tracer != tracer {
+			lock.Release()
+			return nil, fmt.Errorf("export directory holds blocks #%d-#%d traced with %q", stored.From, stored.To, stored.Tracer)
+		}
+		checkpoint = &stored
+	} else if !os.IsNotExist(err) {
+		lock.Release()
+		return nil, err
+	}
+	return &chainExporter{
+		api:        api,
+		dir:        dir,
+		config:     config,
+		checkpoint: checkpoint,
+		lock:       lock,
+	}, nil
+}
+
+// run traces the blocks left to export, releasing the export directory once
+// done or aborted.
+func (e *chainExporter) run(abort <-chan interface{}) error {
+	defer e.lock.Release()
+	defer e.discard()
+
+	if e.checkpoint.Next > e.checkpoint.To {
+		return nil
+	}
+	ctx := context.Background()
+	start, err := e.api.blockByNumber(ctx, rpc.BlockNumber(e.checkpoint.Next-1))
+	if err != nil {
+		return err
+	}
+	end, err := e.api.blockByNumber(ctx, rpc.BlockNumber(e.checkpoint.To))
+	if err != nil {
+		return err
+	}
+	// Abort the tracing if either the caller requests so or writing fails
+	var (
+		closed   = make(chan interface{})
+		stop     sync.Once
+		failed   error
+		begin    = time.Now()
+		exported = e.checkpoint.Next
+	)
+	defer stop.Do(func() { close(closed) })
+	go func() {
+		select {
+		case <-abort:
+			stop.Do(func() { close(closed) })
+		case <-closed:
+		}
+	}()
+	outcome := e.api.traceChainBlocks(start, end, e.config, closed, func(result *blockTraceResult) {
+		if failed != nil {
+			return
+		}
+		if failed = e.write(result); failed != nil {
+			stop.Do(func() { close(closed) })
+			return
+		}
+		exported = uint64(result.Block) + 1
+	})
+	err = <-outcome
+	if failed != nil {
+		return failed
+	}
+	if err != nil {
+		return err
+	}
+	if exported <= e.checkpoint.To {
+		return errors.New("chain trace export aborted")
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	log.Info("Finished chain trace export", "dir", e.dir, "from", e.checkpoint.From, "to", e.checkpoint.To, "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
+
+// write appends the traces of a block to the chunk file it belongs into, moving
+// the previous chunk into place if the block starts a new one.
+func (e *chainExporter) write(result *blockTraceResult) error {
+	number := uint64(result.Block)
+	if e.gzip != nil && number > e.end {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	if e.gzip == nil {
+		e.end = number - number%traceExportChunk + traceExportChunk - 1
+		if e.end > e.checkpoint.To {
+			e.end = e.checkpoint.To
+		}
+		name := fmt.Sprintf("traces-%012d-%012d.jsonl.gz.tmp", number, e.end)
+		file, err := os.Create(filepath.Join(e.dir, name))
+		if err != nil {
+			return err
+		}
+		e.file, e.gzip = file, gzip.NewWriter(file)
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if _, err := e.gzip.Write(append(blob, '\n')); err != nil {
+		return err
+	}
+	e.checkpoint.Next = number + 1
+	return nil
+}
+
+// flush moves the chunk being written into place and persists the checkpoint
+// past its last block.
+func (e *chainExporter) flush() error {
+	if e.gzip == nil {
+		return nil
+	}
+	name := e.file.Name()
+	if err := e.gzip.Close(); err != nil {
+		return err
+	}
+	if err := e.file.Sync(); err != nil {
+		return err
+	}
+	if err := e.file.Close(); err != nil {
+		return err
+	}
+	e.file, e.gzip = nil, nil
+
+	if err := os.Rename(name, name[:len(name)-len(".tmp")]); err != nil {
+		return err
+	}
+	blob, err := json.Marshal(e.checkpoint)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(e.dir, traceExportCheckpoint)
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	log.Info("Exported chain trace chunk", "dir", e.dir, "next", e.checkpoint.Next, "to", e.checkpoint.To)
+	return nil
+}
+
+// discard drops the chunk being written if the export is interrupted, it is
+// traced again when the export is resumed.
+func (e *chainExporter) discard() {
+	if e.gzip == nil {
+		return
+	}
+	e.file.Close()
+	os.Remove(e.file.Name())
+	e.file, e.gzip = nil, nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// readExport decodes the block traces of an exported chunk file.
+func readExport(t *testing.T, path string) []*blockTraceResult {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open export: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to decompress export: %v", err)
+	}
+	var (
+		results []*blockTraceResult
+		scanner = bufio.NewScanner(reader)
+	)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		result := new(blockTraceResult)
+		if err := json.Unmarshal(scanner.Bytes(), result); err != nil {
+			t.Fatalf("failed to decode block traces: %v", err)
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	return results
+}
+
+// Tests that a chain trace export writes the traces of every block in the
+// range, and that it resumes from the stored checkpoint.
+func TestExportChain(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 10, genesis, func(i int, b *core.BlockGen) {
+		// Every other block transfers to the second account
+		if i%2 == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(b.TxNonce(accounts[0].addr)), accounts[1].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		}
+	})
+	api := NewAPI(backend)
+
+	dir, err := ioutil.TempDir("", "trace-export-")
+	if err != nil {
+		t.Fatalf("failed to create export directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Export the whole chain and check that every block is in the chunk file
+	if err := api.ExportChain(0, 10, dir, nil, nil); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	results := readExport(t, filepath.Join(dir, "traces-000000000001-000000000010.jsonl.gz"))
+	if len(results) != 10 {
+		t.Fatalf("exported block count mismatch: have %d, want 10", len(results))
+	}
+	for i, result := range results {
+		block := backend.chain.GetBlockByNumber(uint64(i + 1))
+		if uint64(result.Block) != block.NumberU64() || result.Hash != block.Hash() {
+			t.Errorf("block %d: exported block mismatch: have #%d [%x]", i+1, result.Block, result.Hash)
+		}
+		if len(result.Traces) != len(block.Transactions()) {
+			t.Errorf("block %d: trace count mismatch: have %d, want %d", i+1, len(result.Traces), len(block.Transactions()))
+		}
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(dir, traceExportCheckpoint))
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	var checkpoint exportCheckpoint
+	if err := json.Unmarshal(blob, &checkpoint); err != nil {
+		t.Fatalf("failed to decode checkpoint: %v", err)
+	}
+	if checkpoint != (exportCheckpoint{From: 1, To: 10, Next: 11}) {
+		t.Fatalf("checkpoint mismatch: have %+v", checkpoint)
+	}
+	// Re-running a finished export is a noop, exporting a different range into
+	// the same directory is rejected
+	if err := api.ExportChain(1, 10, dir, nil, nil); err != nil {
+		t.Fatalf("failed to rerun finished export: %v", err)
+	}
+	if err := api.ExportChain(1, 5, dir, nil, nil); err == nil {
+		t.Fatalf("export of different range succeeded")
+	}
+	// Rewind the checkpoint as if the export was interrupted, resuming must only
+	// trace the blocks left
+	os.Remove(filepath.Join(dir, "traces-000000000001-000000000010.jsonl.gz"))
+	checkpoint.Next = 6
+	blob, _ = json.Marshal(checkpoint)
+	if err := ioutil.WriteFile(filepath.Join(dir, traceExportCheckpoint), blob, 0644); err != nil {
+		t.Fatalf("failed to rewind checkpoint: %v", err)
+	}
+	if err := api.ExportChain(1, 10, dir, nil, nil); err != nil {
+		t.Fatalf("failed to resume export: %v", err)
+	}
+	results = readExport(t, filepath.Join(dir, "traces-000000000006-000000000010.jsonl.gz"))
+	if len(results) != 5 || results[0].Block != 6 || results[4].Block != 10 {
+		t.Fatalf("resumed export mismatch: have %d blocks", len(results))
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "traces-*"))
+	if len(files) != 1 {
+		t.Fatalf("export file count mismatch: have %v, want 1", files)
+	}
+}
+
+// Tests that chain trace exports started over RPC are confined to the export
+// directory of the node, and that they are waited for on shutdown.
+func TestTraceChainToFiles(t *testing.T) {
+	t.Parallel()
+
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{}}
+	backend := newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {})
+
+	dir, err := ioutil.TempDir("", "trace-export-")
+	if err != nil {
+		t.Fatalf("failed to create export directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	exports := NewExportService(filepath.Join(dir, "traces"))
+	api := &API{backend: backend, exports: exports}
+
+	for _, path := range []string{"", ".", "/tmp/escape", "../escape", "nested/../../escape", "nested/.."} {
+		if _, err := api.TraceChainToFiles(context.Background(), 1, 4, "", path, nil); err == nil {
+			t.Errorf("export into %q accepted", path)
+		}
+	}
+	if _, err := api.TraceChainToFiles(context.Background(), 1, 4, "", "nested/run", nil); err != nil {
+		t.Fatalf("failed to start export: %v", err)
+	}
+	if err := exports.Stop(); err != nil {
+		t.Fatalf("failed to stop exports: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "traces", "nested", "run", "LOCK")); err != nil {
+		t.Errorf("export not confined to the export directory: %v", err)
+	}
+	if _, err := api.TraceChainToFiles(context.Background(), 1, 4, "", "other", nil); err == nil {
+		t.Errorf("export started after shutdown")
+	}
+}
+
+// Tests that the chain trace exports not yet finished when the node shut down are
+// restarted along with it, and dropped from the registry once done.
+func TestExportServiceResume(t *testing.T) {
+	t.Parallel()
+
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{}}
+	backend := newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {})
+
+	dir, err := ioutil.TempDir("", "trace-export-")
+	if err != nil {
+		t.Fatalf("failed to create export directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Register an export as if the node was shut down while running it
+	if err := NewExportService(dir).register("nested/run", &exportRecord{From: 1, To: 4}); err != nil {
+		t.Fatalf("failed to register export: %v", err)
+	}
+	exports := NewExportService(dir)
+	APIs(backend, exports)
+	if err := exports.Start(); err != nil {
+		t.Fatalf("failed to start exports: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		exports.lock.Lock()
+		records, err := exports.records()
+		exports.lock.Unlock()
+		if err != nil {
+			t.Fatalf("failed to read export registry: %v", err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("resumed export not finished")
+		}
+	}
+	if err := exports.Stop(); err != nil {
+		t.Fatalf("failed to stop exports: %v", err)
+	}
+	results := readExport(t, filepath.Join(dir, "nested", "run", "traces-000000000001-000000000004.jsonl.gz"))
+	if len(results) != 4 {
+		t.Fatalf("exported block count mismatch: have %d, want 4", len(results))
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// traceIndexConfirmations is the number of blocks the trace indexer stays
+	// behind the chain head, to avoid indexing blocks which are reorged out.
+	traceIndexConfirmations = 64
+
+	// traceIndexRecheck is the interval at which the trace indexer checks for
+	// new blocks to index.
+	traceIndexRecheck = 3 * time.Second
+)
+
+// TraceIndexer indexes the addresses involved in the call frames of canonical
+// transactions in the background, so that trace_filter only has to re-execute
+// the transactions involving the filtered addresses. The index starts at the
+// chain head when the indexer is first enabled.
+type TraceIndexer struct {
+	api  *API
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTraceIndexer creates a trace indexer on top of the given backend.
+func NewTraceIndexer(backend Backend) *TraceIndexer {
+	return &TraceIndexer{
+		api:  NewAPI(backend),
+		quit: make(chan struct{}),
+	}
+}
+
+// Start implements node.Lifecycle, starting the background indexing.
+func (ix *TraceIndexer) Start() error {
+	ix.wg.Add(1)
+	go ix.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the background indexing.
+func (ix *TraceIndexer) Stop() error {
+	close(ix.quit)
+	ix.wg.Wait()
+	return nil
+}
+
+// loop periodically indexes the confirmed blocks not yet indexed.
+func (ix *TraceIndexer) loop() {
+	defer ix.wg.Done()
+
+	ticker := time.NewTicker(traceIndexRecheck)
+	defer ticker.Stop()
+
+	for {
+		if err := ix.index(); err != nil {
+			log.Warn("Failed to index call traces", "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ix.quit:
+			return
+		}
+	}
+}
+
+// index indexes all the blocks between the index head and the latest confirmed
+// block, stopping early if the indexer is terminated.
+func (ix *TraceIndexer) index() error {
+	ctx := context.Background()
+	header, err := ix.api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
+	}
+	if header.Number.Uint64() <= traceIndexConfirmations {
+		return nil
+	}
+	var (
+		db     = ix.api.backend.ChainDb()
+		target = header.Number.Uint64() - traceIndexConfirmations
+		start  = target
+		begin  = time.Now()
+		logged = time.Now()
+	)
+	if head := rawdb.ReadTraceIndexHead(db); head != nil {
+		start = *head + 1
+	}
+	for number := start; number <= target; number++ {
+		select {
+		case <-ix.quit:
+			return nil
+		default:
+		}
+		if time.Since(logged) > 8*time.Second {
+			logged = time.Now()
+			log.Info("Indexing call traces", "number", number, "target", target, "elapsed", time.Since(begin))
+		}
+		block, err := ix.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return err
+		}
+		addresses, err := ix.indexBlock(ctx, block)
+		if err != nil {
+			return err
+		}
+		batch := db.NewBatch()
+		rawdb.WriteTraceIndex(batch, number, addresses)
+		if rawdb.ReadTraceIndexTail(db) == nil {
+			rawdb.WriteTraceIndexTail(batch, number)
+		}
+		rawdb.WriteTraceIndexHead(batch, number)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	if target-start > 8 {
+		log.Info("Indexed call traces", "from", start, "to", target, "elapsed", time.Since(begin))
+	}
+	return nil
+}
+
+// indexBlock executes the transactions of a block, collecting the addresses
+// involved in the call frames of each.
+func (ix *TraceIndexer) indexBlock(ctx context.Context, block *types.Block) ([]map[common.Address]byte, error) {
+	txs := block.Transactions()
+	if len(txs) == 0 || block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent, err := ix.api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := ix.api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer    = types.MakeSigner(ix.api.backend.ChainConfig(), block.Number())
+		blockCtx  = core.NewEVMBlockContext(block.Header(), ix.api.chainContext(ctx), nil)
+		addresses = make([]map[common.Address]byte, len(txs))
+	)
+	for i, tx := range txs {
+		msg, _ := tx.AsMessage(signer)
+
+		if posa, ok := ix.api.backend.Engine().(consensus.PoSA); ok {
+			if isSystem, _ := posa.IsSystemTransaction(tx, block.Header()); isSystem {
+				distributeIncoming(statedb, block.Header().Coinbase, nil)
+			}
+		}
+		collector := newAddressCollector()
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, ix.api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: collector})
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+			return nil, err
+		}
+		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+
+		addresses[i] = collector.addresses
+	}
+	return addresses, nil
+}
+
+// addressCollector is an EVM logger collecting the senders and recipients of
+// all the call frames of a transaction.
+type addressCollector struct {
+	addresses map[common.Address]byte
+}
+
+func newAddressCollector() *addressCollector {
+	return &addressCollector{addresses: make(map[common.Address]byte)}
+}
+
+func (c *addressCollector) collect(from, to common.Address) {
+	c.addresses[from] |= rawdb.TraceIndexFrom
+	c.addresses[to] |= rawdb.TraceIndexTo
+}
+
+func (c *addressCollector) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	c.collect(from, to)
+}
+
+func (c *addressCollector) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	c.collect(from, to)
+}
+
+func (c *addressCollector) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (c *addressCollector) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (c *addressCollector) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (c *addressCollector) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {}
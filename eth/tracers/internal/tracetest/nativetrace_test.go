@@ -0,0 +1,419 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// prestateAccount is an account of a prestateTracer run.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateDiff is the result of a prestateTracer run in diff mode.
+type prestateDiff struct {
+	Pre  map[common.Address]*prestateAccount `json:"pre"`
+	Post map[common.Address]*prestateAccount `json:"post"`
+}
+
+// flatCallTrace is a single trace of a flatCallTracer run.
+type flatCallTrace struct {
+	Action struct {
+		CallType      string          `json:"callType"`
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Gas           *hexutil.Uint64 `json:"gas"`
+		Input         hexutil.Bytes   `json:"input"`
+		Init          hexutil.Bytes   `json:"init"`
+		Value         *hexutil.Big    `json:"value"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+		Balance       *hexutil.Big    `json:"balance"`
+	} `json:"action"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Error       string `json:"error"`
+	Result      *struct {
+		Address *common.Address `json:"address"`
+		Code    hexutil.Bytes   `json:"code"`
+		GasUsed hexutil.Uint64  `json:"gasUsed"`
+		Output  hexutil.Bytes   `json:"output"`
+	} `json:"result"`
+	Subtraces    int    `json:"subtraces"`
+	TraceAddress []int  `json:"traceAddress"`
+	Type         string `json:"type"`
+}
+
+// runTracerTests runs the given tracer against all the call tracer test cases
+// in the given directory, invoking check with each result.
+func runTracerTests(t *testing.T, tracerName string, cfg json.RawMessage, dirPath string, check func(t *testing.T, test *callTracerTest, res json.RawMessage, statedb *state.StateDB)) {
+	files, err := ioutil.ReadDir(filepath.Join("testdata", dirPath))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			test := new(callTracerTest)
+			if blob, err := ioutil.ReadFile(filepath.Join("testdata", dirPath, file.Name())); err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			} else if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			res, statedb := runTracer(t, tracerName, cfg, test)
+			check(t, test, res, statedb)
+		})
+	}
+}
+
+// runTracer executes the transaction of a call tracer test case with the given
+// tracer, returning the trace result and the state after the execution.
+func runTracer(t *testing.T, tracerName string, cfg json.RawMessage, test *callTracerTest) (json.RawMessage, *state.StateDB) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	var (
+		signer    = types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		origin, _ = signer.Sender(tx)
+		txContext = vm.TxContext{
+			Origin:   origin,
+			GasPrice: tx.GasPrice(),
+		}
+		context = vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    test.Context.Miner,
+			BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+			Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+			Difficulty:  (*big.Int)(test.Context.Difficulty),
+			GasLimit:    uint64(test.Context.GasLimit),
+		}
+		_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	)
+	tracer, err := tracers.New(tracerName, &tracers.Context{TxHash: tx.Hash()}, cfg)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res, statedb
+}
+
+// Tests that the native prestate tracer reports the same accounts as the
+// JavaScript one, with the state of the genesis allocation the transaction was
+// executed on.
+func TestPrestateTracerNative(t *testing.T) {
+	for _, dirPath := range []string{"call_tracer", "call_tracer_legacy"} {
+		dirPath := dirPath
+		t.Run(dirPath, func(t *testing.T) {
+			runTracerTests(t, "prestateTracer", nil, dirPath, func(t *testing.T, test *callTracerTest, res json.RawMessage, _ *state.StateDB) {
+				var have, want map[common.Address]*prestateAccount
+				if err := json.Unmarshal(res, &have); err != nil {
+					t.Fatalf("failed to unmarshal trace result: %v", err)
+				}
+				js, _ := runTracer(t, "prestateTracerLegacy", nil, test)
+				if err := json.Unmarshal(js, &want); err != nil {
+					t.Fatalf("failed to unmarshal js trace result: %v", err)
+				}
+				for addr, acc := range want {
+					if have[addr] == nil {
+						t.Errorf("account %x missing", addr)
+						continue
+					}
+					if have[addr].Balance.ToInt().Cmp(acc.Balance.ToInt()) != 0 || have[addr].Nonce != acc.Nonce {
+						t.Errorf("account %x balance/nonce mismatch: have %v/%d, want %v/%d", addr, have[addr].Balance, have[addr].Nonce, acc.Balance, acc.Nonce)
+					}
+					if !bytes.Equal(have[addr].Code, acc.Code) || !reflect.DeepEqual(have[addr].Storage, acc.Storage) {
+						t.Errorf("account %x mismatch: have %+v, want %+v", addr, have[addr], acc)
+					}
+				}
+				for addr, acc := range have {
+					alloc := test.Genesis.Alloc[addr]
+					if alloc.Balance == nil {
+						alloc.Balance = new(big.Int)
+					}
+					if acc.Balance.ToInt().Cmp(alloc.Balance) != 0 || acc.Nonce != alloc.Nonce || !bytes.Equal(acc.Code, alloc.Code) {
+						t.Errorf("account %x prestate mismatch: have %v/%d, want %v/%d", addr, acc.Balance, acc.Nonce, alloc.Balance, alloc.Nonce)
+					}
+					for key, val := range acc.Storage {
+						if alloc.Storage[key] != val {
+							t.Errorf("account %x slot %x mismatch: have %x, want %x", addr, key, val, alloc.Storage[key])
+						}
+					}
+				}
+			})
+		})
+	}
+}
+
+// Tests that the prestate tracer in diff mode reports the modified accounts
+// with their state before and after the transaction.
+func TestPrestateTracerDiffMode(t *testing.T) {
+	runTracerTests(t, "prestateTracer", json.RawMessage(`{"diffMode": true}`), "call_tracer", func(t *testing.T, test *callTracerTest, res json.RawMessage, statedb *state.StateDB) {
+		diff := new(prestateDiff)
+		if err := json.Unmarshal(res, diff); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		if len(diff.Post) == 0 {
+			t.Fatalf("no modified accounts")
+		}
+		for addr, acc := range diff.Pre {
+			alloc := test.Genesis.Alloc[addr]
+			if acc.Balance != nil && acc.Balance.ToInt().Cmp(alloc.Balance) != 0 {
+				t.Errorf("account %x pre balance mismatch: have %v, want %v", addr, acc.Balance, alloc.Balance)
+			}
+			if acc.Nonce != alloc.Nonce {
+				t.Errorf("account %x pre nonce mismatch: have %d, want %d", addr, acc.Nonce, alloc.Nonce)
+			}
+			for key, val := range acc.Storage {
+				if alloc.Storage[key] != val {
+					t.Errorf("account %x pre slot %x mismatch: have %x, want %x", addr, key, val, alloc.Storage[key])
+				}
+			}
+		}
+		for addr, acc := range diff.Post {
+			if acc.Balance != nil && acc.Balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+				t.Errorf("account %x post balance mismatch: have %v, want %v", addr, acc.Balance, statedb.GetBalance(addr))
+			}
+			if acc.Nonce != 0 && acc.Nonce != statedb.GetNonce(addr) {
+				t.Errorf("account %x post nonce mismatch: have %d, want %d", addr, acc.Nonce, statedb.GetNonce(addr))
+			}
+			for key, val := range acc.Storage {
+				if cur := statedb.GetState(addr, key); cur != val {
+					t.Errorf("account %x post slot %x mismatch: have %x, want %x", addr, key, val, cur)
+				}
+			}
+		}
+	})
+}
+
+// Tests that the native 4byte tracer collects the same identifiers as the
+// JavaScript one.
+func TestFourByteTracerNative(t *testing.T) {
+	runTracerTests(t, "4byteTracer", nil, "call_tracer", func(t *testing.T, test *callTracerTest, res json.RawMessage, _ *state.StateDB) {
+		var have, want map[string]int
+		if err := json.Unmarshal(res, &have); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		js, _ := runTracer(t, "4byteTracerJsLegacy", nil, test)
+		if err := json.Unmarshal(js, &want); err != nil {
+			t.Fatalf("failed to unmarshal js trace result: %v", err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("identifiers mismatch: have %v, want %v", have, want)
+		}
+	})
+}
+
+// Tests that the flat call tracer lists the frames of the call tracer results
+// depth first, in the Parity trace format.
+func TestFlatCallTracerNative(t *testing.T) {
+	runTracerTests(t, "flatCallTracer", json.RawMessage(`{"includePrecompiles": true}`), "call_tracer", func(t *testing.T, test *callTracerTest, res json.RawMessage, _ *state.StateDB) {
+		var have []flatCallTrace
+		if err := json.Unmarshal(res, &have); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		want := flattenCallTrace(test.Result, []int{})
+		if len(have) != len(want) {
+			t.Fatalf("trace count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range have {
+			if !reflect.DeepEqual(have[i].TraceAddress, want[i].address) || have[i].Subtraces != len(want[i].frame.Calls) {
+				t.Errorf("trace %d position mismatch: have %v/%d, want %v/%d", i, have[i].TraceAddress, have[i].Subtraces, want[i].address, len(want[i].frame.Calls))
+			}
+			if err := checkFlatCallTrace(&have[i], want[i].frame); err != "" {
+				t.Errorf("trace %d mismatch: %s", i, err)
+			}
+		}
+	})
+}
+
+// addressedCallTrace is a call frame with its position in the call tree.
+type addressedCallTrace struct {
+	frame   *callTrace
+	address []int
+}
+
+// flattenCallTrace lists a call frame and its children depth first.
+func flattenCallTrace(frame *callTrace, address []int) []addressedCallTrace {
+	frames := []addressedCallTrace{{frame, address}}
+	for i := range frame.Calls {
+		child := append(append([]int{}, address...), i)
+		frames = append(frames, flattenCallTrace(&frame.Calls[i], child)...)
+	}
+	return frames
+}
+
+// checkFlatCallTrace compares a flat trace with the call frame it represents.
+func checkFlatCallTrace(trace *flatCallTrace, frame *callTrace) string {
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		if trace.Type != "create" || *trace.Action.From != frame.From || !bytes.Equal(trace.Action.Init, frame.Input) {
+			return "create action mismatch"
+		}
+		if frame.Error == "" && (trace.Result == nil || *trace.Result.Address != frame.To || !bytes.Equal(trace.Result.Code, frame.Output)) {
+			return "create result mismatch"
+		}
+	case "SELFDESTRUCT":
+		if trace.Type != "suicide" || *trace.Action.Address != frame.From || *trace.Action.RefundAddress != frame.To {
+			return "suicide action mismatch"
+		}
+	default:
+		if trace.Type != "call" || trace.Action.CallType != strings.ToLower(frame.Type) || *trace.Action.From != frame.From || *trace.Action.To != frame.To || !bytes.Equal(trace.Action.Input, frame.Input) {
+			return "call action mismatch"
+		}
+		if frame.Error == "" && (trace.Result == nil || !bytes.Equal(trace.Result.Output, frame.Output)) {
+			return "call result mismatch"
+		}
+	}
+	if (frame.Error == "") != (trace.Error == "") {
+		return "error mismatch: have " + trace.Error + ", want " + frame.Error
+	}
+	if frame.Error == "" && frame.Type != "SELFDESTRUCT" && frame.GasUsed != nil && trace.Result.GasUsed != *frame.GasUsed {
+		return "gas used mismatch"
+	}
+	return ""
+}
+
+// Tests that the tracers notified of system transactions report the flag and
+// the balance changes the consensus engine makes ahead of them.
+func TestSystemTracers(t *testing.T) {
+	var (
+		system   = common.HexToAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+		coinbase = common.HexToAddress("0x00000000000000000000000000000000000000c0")
+		contract = common.HexToAddress("0x0000000000000000000000000000000000001000")
+		balance  = big.NewInt(params.Ether)
+		alloc    = core.GenesisAlloc{
+			system:   {Balance: big.NewInt(1000)},
+			coinbase: {Balance: balance},
+			contract: {Balance: new(big.Int), Code: []byte{0x00}},
+		}
+		jsTracer = `{changes: [], balanceChange: function(change) { this.changes.push({address: toHex(change.address), prev: change.prev.toString(), post: change.new.toString(), reason: change.reason}); }, fault: function() {}, result: function(ctx) { return {systemTx: ctx.systemTx, changes: this.changes}; }}`
+	)
+	run := func(tracerName string) json.RawMessage {
+		t.Helper()
+		_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+		tracer, err := tracers.New(tracerName, new(tracers.Context), nil)
+		if err != nil {
+			t.Fatalf("failed to create tracer: %v", err)
+		}
+		// Move the fees to the coinbase as the consensus engine does
+		hooks := tracer.(tracers.SystemTracer)
+		hooks.CaptureSystemTx()
+		hooks.CaptureBalanceChange(system, big.NewInt(1000), new(big.Int), "distributeIncoming")
+		hooks.CaptureBalanceChange(coinbase, balance, new(big.Int).Add(balance, big.NewInt(1000)), "distributeIncoming")
+		statedb.SetBalance(system, new(big.Int))
+		statedb.AddBalance(coinbase, big.NewInt(1000))
+
+		context := vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    coinbase,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(1),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    params.GenesisGasLimit,
+		}
+		msg := types.NewMessage(coinbase, &contract, 0, big.NewInt(1000), 100000, new(big.Int), nil, nil, false)
+		evm := vm.NewEVM(context, core.NewEVMTxContext(msg), statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+		if _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		return res
+	}
+	// The call tracer reports both on the top-level call
+	var call struct {
+		SystemTx       bool `json:"systemTx"`
+		BalanceChanges []struct {
+			Address common.Address `json:"address"`
+			Prev    *hexutil.Big   `json:"prev"`
+			New     *hexutil.Big   `json:"new"`
+			Reason  string         `json:"reason"`
+		} `json:"balanceChanges"`
+	}
+	if err := json.Unmarshal(run("callTracer"), &call); err != nil {
+		t.Fatalf("failed to unmarshal call trace: %v", err)
+	}
+	if !call.SystemTx || len(call.BalanceChanges) != 2 {
+		t.Fatalf("call trace mismatch: %+v", call)
+	}
+	if change := call.BalanceChanges[1]; change.Address != coinbase || change.Prev.ToInt().Cmp(balance) != 0 ||
+		change.New.ToInt().Cmp(new(big.Int).Add(balance, big.NewInt(1000))) != 0 || change.Reason != "distributeIncoming" {
+		t.Errorf("coinbase balance change mismatch: %+v", change)
+	}
+	// The prestate tracer reports the balances before the changes
+	var pre map[common.Address]*prestateAccount
+	if err := json.Unmarshal(run("prestateTracer"), &pre); err != nil {
+		t.Fatalf("failed to unmarshal prestate trace: %v", err)
+	}
+	for addr, want := range map[common.Address]*big.Int{system: big.NewInt(1000), coinbase: balance, contract: new(big.Int)} {
+		if pre[addr] == nil || pre[addr].Balance.ToInt().Cmp(want) != 0 {
+			t.Errorf("account %x prestate mismatch: have %+v, want balance %v", addr, pre[addr], want)
+		}
+	}
+	// The JavaScript tracers are passed both too
+	have := run(jsTracer)
+	want := fmt.Sprintf(`{"systemTx":true,"changes":[{"address":"%s","prev":"1000","post":"0","reason":"distributeIncoming"},{"address":"%s","prev":"%v","post":"%v","reason":"distributeIncoming"}]}`,
+		strings.ToLower(system.Hex()), strings.ToLower(coinbase.Hex()), balance, new(big.Int).Add(balance, big.NewInt(1000)))
+	if string(have) != want {
+		t.Errorf("js trace mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
@@ -47,15 +47,24 @@
 	// result is invoked when all the opcodes have been iterated over and returns
 	// the final result of the tracing.
 	result: function(ctx, db) {
-		// At this point, we need to deduct the 'value' from the
-		// outer transaction, and move it back to the origin
+		// If any code was executed, the sender and the recipient were looked up
+		// before it ran, with the entire gas allowance charged upfront. Otherwise
+		// they are looked up now, with only the used gas charged.
+		var gas = ctx.gas + ctx.intrinsicGas;
+		if (this.prestate === null) {
+			this.prestate = {};
+			gas = ctx.gasUsed + ctx.intrinsicGas;
+		}
 		this.lookupAccount(ctx.from, db);
+		this.lookupAccount(ctx.to, db);
 
+		// At this point, we need to deduct the 'value' from the
+		// outer transaction, and move it back to the origin
 		var fromBal = bigInt(this.prestate[toHex(ctx.from)].balance.slice(2), 16);
 		var toBal   = bigInt(this.prestate[toHex(ctx.to)].balance.slice(2), 16);
 
 		this.prestate[toHex(ctx.to)].balance   = '0x'+toBal.subtract(ctx.value).toString(16);
-		this.prestate[toHex(ctx.from)].balance = '0x'+fromBal.add(ctx.value).add((ctx.gasUsed + ctx.intrinsicGas) * ctx.gasPrice).toString(16);
+		this.prestate[toHex(ctx.from)].balance = '0x'+fromBal.add(ctx.value).add(bigInt(gas).multiply(ctx.gasPrice)).toString(16);
 
 		// Decrement the caller's nonce, and remove empty create targets
 		this.prestate[toHex(ctx.from)].nonce--;
@@ -73,8 +82,10 @@
 		// Add the current account if we just started tracing
 		if (this.prestate === null){
 			this.prestate = {};
-			// Balance will potentially be wrong here, since this will include the value
-			// sent along with the message. We fix that in 'result()'.
+			// Balances will potentially be wrong here, since they include the value
+			// sent along with the message and the gas bought by the sender. We fix
+			// that in 'result()'.
+			this.lookupAccount(log.contract.getCaller(), db);
 			this.lookupAccount(log.contract.getAddress(), db);
 		}
 		// Whenever new state is accessed, add it to the prestate
@@ -419,12 +419,13 @@ type jsTracer struct {
 	activePrecompiles []common.Address // Updated on CaptureStart based on given rules
 	traceSteps        bool             // When true, will invoke step() on each opcode
 	traceCallFrames   bool             // When true, will invoke enter() and exit() js funcs
+	traceBalances     bool             // When true, will invoke balanceChange() js func
 }
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
 // and 'result' functions.
-func newJsTracer(code string, ctx *tracers2.Context) (tracers2.Tracer, error) {
+func newJsTracer(code string, ctx *tracers2.Context, cfg json.RawMessage) (tracers2.Tracer, error) {
 	if c, ok := assetTracers[code]; ok {
 		code = c
 	}
@@ -576,6 +577,9 @@ func newJsTracer(code string, ctx *tracers2.Context) (tracers2.Tracer, error) {
 	tracer.traceCallFrames = hasEnter && hasExit
 	tracer.traceSteps = hasStep
 
+	tracer.traceBalances = tracer.vm.GetPropString(tracer.tracerObject, "balanceChange")
+	tracer.vm.Pop()
+
 	// Tracer is valid, inject the big int library to access large numbers
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
@@ -825,6 +829,33 @@ func (jst *jsTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 	}
 }
 
+// CaptureSystemTx implements tracers.SystemTracer, exposing the flag of system
+// transactions to the 'result' function as ctx.systemTx.
+func (jst *jsTracer) CaptureSystemTx() {
+	jst.ctx["systemTx"] = true
+}
+
+// CaptureBalanceChange implements tracers.SystemTracer, passing the balance changes
+// of the consensus engine to the optional 'balanceChange' function.
+func (jst *jsTracer) CaptureBalanceChange(addr common.Address, prev, new *big.Int, reason string) {
+	if !jst.traceBalances {
+		return
+	}
+	if jst.err != nil {
+		return
+	}
+	obj := jst.vm.PushObject()
+	jst.addToObj(obj, "address", addr)
+	jst.addToObj(obj, "prev", prev)
+	jst.addToObj(obj, "new", new)
+	jst.addToObj(obj, "reason", reason)
+	jst.vm.PutPropString(jst.stateObject, "balanceChange")
+
+	if _, err := jst.call(true, "balanceChange", "balanceChange"); err != nil {
+		jst.err = wrapError("balanceChange", err)
+	}
+}
+
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
 func (jst *jsTracer) GetResult() (json.RawMessage, error) {
 	// Transform the context into a JavaScript object and inject into the state
@@ -855,6 +886,8 @@ func (jst *jsTracer) addToObj(obj int, key string, val interface{}) {
 
 func pushValue(ctx *duktape.Context, val interface{}) {
 	switch val := val.(type) {
+	case bool:
+		ctx.PushBoolean(val)
 	case uint64:
 		ctx.PushUint(uint(val))
 	case string:
@@ -45,22 +45,36 @@ type callFrame struct {
 	Output  string      `json:"output,omitempty"`
 	Error   string      `json:"error,omitempty"`
 	Calls   []callFrame `json:"calls,omitempty"`
+
+	SystemTx       bool            `json:"systemTx,omitempty"`       // Set on the top-level call of system transactions
+	BalanceChanges []balanceChange `json:"balanceChanges,omitempty"` // Set on the top-level call, made by the consensus engine ahead of it
+}
+
+// balanceChange is a balance modification applied by the consensus engine
+// directly, outside of the EVM.
+type balanceChange struct {
+	Address string `json:"address"`
+	Prev    string `json:"prev"`
+	New     string `json:"new"`
+	Reason  string `json:"reason"`
 }
 
 type callTracer struct {
 	env       *vm.EVM
 	callstack []callFrame
-	interrupt uint32 // Atomic flag to signal execution interruption
-	reason    error  // Textual reason for the interruption
+	systemTx  bool            // Whether the traced tx is applied by the consensus engine
+	balances  []balanceChange // Balance changes made by the consensus engine ahead of the tx
+	interrupt uint32          // Atomic flag to signal execution interruption
+	reason    error           // Textual reason for the interruption
 }
 
 // newCallTracer returns a native go tracer which tracks
 // call frames of a tx, and implements vm.EVMLogger.
-func newCallTracer() tracers.Tracer {
+func newCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
 	// First callframe contains tx context info
 	// and is populated on start and end.
 	t := &callTracer{callstack: make([]callFrame, 1)}
-	return t
+	return t, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -73,6 +87,9 @@ func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Ad
 		Input: bytesToHex(input),
 		Gas:   uintToHex(gas),
 		Value: bigToHex(value),
+
+		SystemTx:       t.systemTx,
+		BalanceChanges: t.balances,
 	}
 	if create {
 		t.callstack[0].Type = "CREATE"
@@ -156,6 +173,23 @@ func (t *callTracer) GetResult() (json.RawMessage, error) {
 	return json.RawMessage(res), t.reason
 }
 
+// CaptureSystemTx implements tracers.SystemTracer, flagging the traced tx as one
+// applied by the consensus engine.
+func (t *callTracer) CaptureSystemTx() {
+	t.systemTx = true
+}
+
+// CaptureBalanceChange implements tracers.SystemTracer, reporting the balance
+// changes of the consensus engine on the top-level call.
+func (t *callTracer) CaptureBalanceChange(addr common.Address, prev, new *big.Int, reason string) {
+	t.balances = append(t.balances, balanceChange{
+		Address: addrToHex(addr),
+		Prev:    bigToHex(prev),
+		New:     bigToHex(new),
+		Reason:  reason,
+	})
+}
+
 // Stop terminates execution of the tracer at the first opportune moment.
 func (t *callTracer) Stop(err error) {
 	t.reason = err
@@ -0,0 +1,259 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+// parityErrors maps the prefixes of the EVM errors to the error messages of
+// Parity traces.
+var parityErrors = []struct {
+	prefix string
+	parity string
+}{
+	{vm.ErrExecutionReverted.Error(), "Reverted"},
+	{vm.ErrOutOfGas.Error(), "Out of gas"},
+	{vm.ErrCodeStoreOutOfGas.Error(), "Out of gas"},
+	{vm.ErrInvalidJump.Error(), "Bad jump destination"},
+	{vm.ErrWriteProtection.Error(), "Mutable Call In Static Context"},
+	{vm.ErrDepth.Error(), "Out of stack"},
+	{vm.ErrInsufficientBalance.Error(), "Insufficient balance"},
+	{"invalid opcode", "Bad instruction"},
+	{"stack underflow", "Stack underflow"},
+	{"stack limit reached", "Out of stack"},
+}
+
+// flatCallAction is the action of a Parity trace. Calls fill in the call
+// fields, creations the init code, and self-destructs the refund fields.
+type flatCallAction struct {
+	CallType      string `json:"callType,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	Gas           string `json:"gas,omitempty"`
+	Input         string `json:"input,omitempty"`
+	Init          string `json:"init,omitempty"`
+	Value         string `json:"value,omitempty"`
+	Address       string `json:"address,omitempty"`
+	RefundAddress string `json:"refundAddress,omitempty"`
+	Balance       string `json:"balance,omitempty"`
+}
+
+// flatCallResult is the result of a successful call or creation.
+type flatCallResult struct {
+	Address string `json:"address,omitempty"`
+	Code    string `json:"code,omitempty"`
+	GasUsed string `json:"gasUsed"`
+	Output  string `json:"output,omitempty"`
+}
+
+// flatCallTrace is a single call frame in the format of the Parity `trace_`
+// namespace.
+type flatCallTrace struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result,omitempty"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash,omitempty"`
+	TransactionPosition *int            `json:"transactionPosition,omitempty"`
+	Type                string          `json:"type"`
+}
+
+type flatCallTracerConfig struct {
+	IncludePrecompiles bool `json:"includePrecompiles"` // If true, calls to precompiles are traced too
+}
+
+// flatCallTracer is a call tracer whose result is the list of call frames in
+// the order they were entered, in the format of Parity's `trace_block` and
+// `trace_filter`. It reuses the callTracer to assemble the call frames.
+type flatCallTracer struct {
+	tracer *callTracer
+	ctx    *tracers.Context
+	config flatCallTracerConfig
+
+	blockNumber       uint64
+	activePrecompiles []common.Address // Updated on CaptureStart based on given rules
+}
+
+// newFlatCallTracer returns a native go tracer which tracks the call frames
+// of a tx in Parity's flat format, and implements vm.EVMLogger.
+func newFlatCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config flatCallTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	tracer, err := newCallTracer(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &flatCallTracer{tracer: tracer.(*callTracer), ctx: ctx, config: config}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.tracer.CaptureStart(env, from, to, create, input, gas, value)
+
+	t.blockNumber = env.Context.BlockNumber.Uint64()
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber)
+	t.activePrecompiles = vm.ActivePrecompiles(rules)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *flatCallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	t.tracer.CaptureEnd(output, gasUsed, d, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *flatCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *flatCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *flatCallTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureExit(output, gasUsed, err)
+}
+
+// GetResult returns the json-encoded list of call frames, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.tracer.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	res, err := json.Marshal(t.flatten(&t.tracer.callstack[0], []int{}))
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.tracer.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *flatCallTracer) Stop(err error) {
+	t.tracer.Stop(err)
+}
+
+// flatten converts a call frame and its children into the list of Parity
+// traces, depth first.
+func (t *flatCallTracer) flatten(frame *callFrame, traceAddress []int) []flatCallTrace {
+	trace := flatCallTrace{
+		BlockNumber:  t.blockNumber,
+		TraceAddress: traceAddress,
+		Error:        parityError(frame.Error),
+	}
+	if t.ctx.BlockHash != (common.Hash{}) {
+		trace.BlockHash = &t.ctx.BlockHash
+	}
+	if t.ctx.TxHash != (common.Hash{}) {
+		trace.TransactionHash = &t.ctx.TxHash
+		trace.TransactionPosition = &t.ctx.TxIndex
+	}
+	value := frame.Value
+	if value == "" {
+		value = "0x0"
+	}
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+		trace.Action = flatCallAction{From: frame.From, Gas: frame.Gas, Init: frame.Input, Value: value}
+		if frame.Error == "" {
+			trace.Result = &flatCallResult{Address: frame.To, Code: frame.Output, GasUsed: frame.GasUsed}
+		}
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+		trace.Action = flatCallAction{Address: frame.From, RefundAddress: frame.To, Balance: value}
+	default:
+		trace.Type = "call"
+		trace.Action = flatCallAction{
+			CallType: strings.ToLower(frame.Type),
+			From:     frame.From,
+			To:       frame.To,
+			Gas:      frame.Gas,
+			Input:    frame.Input,
+			Value:    value,
+		}
+		if frame.Error == "" {
+			trace.Result = &flatCallResult{GasUsed: frame.GasUsed, Output: frame.Output}
+		}
+	}
+	// Calls to precompiles are just fancy opcodes, which Parity doesn't trace
+	var children []*callFrame
+	for i := range frame.Calls {
+		if child := &frame.Calls[i]; t.config.IncludePrecompiles || !t.isPrecompiled(child) {
+			children = append(children, child)
+		}
+	}
+	trace.Subtraces = len(children)
+
+	traces := []flatCallTrace{trace}
+	for i, child := range children {
+		childAddress := make([]int, len(traceAddress)+1)
+		copy(childAddress, traceAddress)
+		childAddress[len(traceAddress)] = i
+
+		traces = append(traces, t.flatten(child, childAddress)...)
+	}
+	return traces
+}
+
+// isPrecompiled returns whether the frame is a call to a precompile.
+func (t *flatCallTracer) isPrecompiled(frame *callFrame) bool {
+	if frame.Type == "CREATE" || frame.Type == "CREATE2" || frame.Type == "SELFDESTRUCT" {
+		return false
+	}
+	addr := common.HexToAddress(frame.To)
+	for _, p := range t.activePrecompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// parityError converts an EVM error to the message of Parity traces.
+func parityError(err string) string {
+	for _, e := range parityErrors {
+		if strings.HasPrefix(err, e.prefix) {
+			return e.parity
+		}
+	}
+	return err
+}
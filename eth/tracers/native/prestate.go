@@ -0,0 +1,327 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	register("prestateTracer", newPrestateTracer)
+}
+
+// account is the state of an account touched by the traced transaction.
+type account struct {
+	Balance *big.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[common.Hash]common.Hash
+}
+
+// prestateAccount is the json representation of an account in the prestate,
+// matching the genesis allocation format.
+type prestateAccount struct {
+	Balance string                      `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    string                      `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// diffAccount is the json representation of an account in diff mode, which
+// only contains the fields that were modified by the transaction.
+type diffAccount struct {
+	Balance string                      `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// empty returns whether the account has no fields set.
+func (a *diffAccount) empty() bool {
+	return a.Balance == "" && a.Nonce == 0 && a.Code == "" && len(a.Storage) == 0
+}
+
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, the state before and after the transaction is returned
+}
+
+type prestateTracer struct {
+	env       *vm.EVM
+	pre       map[common.Address]*account
+	create    bool
+	to        common.Address
+	config    prestateTracerConfig
+	balances  map[common.Address]*big.Int // Balance deltas applied by the consensus engine ahead of the tx
+	interrupt uint32                      // Atomic flag to signal execution interruption
+	reason    error                       // Textual reason for the interruption
+}
+
+// newPrestateTracer returns a native go tracer which collects the state of
+// the accounts touched by a tx before its execution, and optionally after it,
+// and implements vm.EVMLogger.
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{
+		pre:      make(map[common.Address]*account),
+		config:   config,
+		balances: make(map[common.Address]*big.Int),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create = create
+	t.to = to
+
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	for addr := range t.balances {
+		t.lookupAccount(addr)
+	}
+	if t.config.DiffMode {
+		// The fees are credited to the system address under parlia, to the coinbase otherwise
+		if env.ChainConfig().Parlia != nil {
+			t.lookupAccount(consensus.SystemAddress)
+		} else {
+			t.lookupAccount(env.Context.Coinbase)
+		}
+	}
+	// The value was already transferred to the recipient, and the creation
+	// of a contract has set its nonce
+	toAcc := t.pre[to]
+	toAcc.Balance = new(big.Int).Sub(toAcc.Balance, value)
+	if create {
+		toAcc.Nonce = 0
+	}
+	// The sender was charged the value and the entire gas allowance upfront, and
+	// its nonce was increased
+	isHomestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+	isIstanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+	intrinsicGas, err := core.IntrinsicGas(input, nil, create, isHomestead, isIstanbul)
+	if err != nil {
+		return
+	}
+	fee := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(gas+intrinsicGas))
+
+	fromAcc := t.pre[from]
+	fromAcc.Balance = new(big.Int).Add(fromAcc.Balance, new(big.Int).Add(value, fee))
+	fromAcc.Nonce--
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	stack := scope.Stack
+	stackLen := len(stack.Data())
+	caller := scope.Contract.Address()
+
+	switch {
+	case stackLen >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		t.lookupStorage(caller, common.Hash(stack.Back(0).Bytes32()))
+	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		t.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case stackLen >= 2 && (op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL):
+		t.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller)))
+	case stackLen >= 4 && op == vm.CREATE2:
+		offset, size := stack.Back(1), stack.Back(2)
+		code := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		salt := stack.Back(3).Bytes32()
+		t.lookupAccount(crypto.CreateAddress2(caller, salt, crypto.Keccak256(code)))
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *prestateTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// GetResult returns the json-encoded state of the touched accounts before the
+// transaction, or in diff mode the modified accounts before and after it, and
+// any error arising from the encoding or forceful termination (via `Stop`).
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	var (
+		res []byte
+		err error
+	)
+	if t.config.DiffMode {
+		res, err = json.Marshal(t.diff())
+	} else {
+		// The created contract didn't exist before the transaction
+		if t.create {
+			delete(t.pre, t.to)
+		}
+		pre := make(map[common.Address]*prestateAccount, len(t.pre))
+		for addr, acc := range t.pre {
+			pre[addr] = &prestateAccount{
+				Balance: bigToHex(acc.Balance),
+				Nonce:   acc.Nonce,
+				Code:    bytesToHex(acc.Code),
+				Storage: acc.Storage,
+			}
+		}
+		res, err = json.Marshal(pre)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// diff compares the collected prestate with the current state, returning the
+// modified accounts before and after the transaction. Unmodified fields are
+// omitted from the post state, self-destructed accounts from it entirely.
+func (t *prestateTracer) diff() map[string]map[common.Address]*diffAccount {
+	var (
+		pre  = make(map[common.Address]*diffAccount)
+		post = make(map[common.Address]*diffAccount)
+		db   = t.env.StateDB
+	)
+	for addr, acc := range t.pre {
+		prev := &diffAccount{
+			Nonce:   acc.Nonce,
+			Storage: make(map[common.Hash]common.Hash),
+		}
+		if acc.Balance.Sign() != 0 {
+			prev.Balance = bigToHex(acc.Balance)
+		}
+		if len(acc.Code) > 0 {
+			prev.Code = bytesToHex(acc.Code)
+		}
+		if db.HasSuicided(addr) {
+			for key, val := range acc.Storage {
+				if val != (common.Hash{}) {
+					prev.Storage[key] = val
+				}
+			}
+			if !prev.empty() {
+				pre[addr] = prev
+			}
+			continue
+		}
+		var (
+			next     = &diffAccount{Storage: make(map[common.Hash]common.Hash)}
+			modified bool
+		)
+		if balance := db.GetBalance(addr); balance.Cmp(acc.Balance) != 0 {
+			next.Balance, modified = bigToHex(balance), true
+		}
+		if nonce := db.GetNonce(addr); nonce != acc.Nonce {
+			next.Nonce, modified = nonce, true
+		}
+		if code := db.GetCode(addr); !bytes.Equal(code, acc.Code) {
+			next.Code, modified = bytesToHex(code), true
+		}
+		for key, val := range acc.Storage {
+			if cur := db.GetState(addr, key); cur != val {
+				prev.Storage[key], next.Storage[key], modified = val, cur, true
+			}
+		}
+		if !modified {
+			continue
+		}
+		// Accounts created by the transaction have no prestate
+		if !prev.empty() {
+			pre[addr] = prev
+		}
+		post[addr] = next
+	}
+	return map[string]map[common.Address]*diffAccount{"pre": pre, "post": post}
+}
+
+// CaptureSystemTx implements tracers.SystemTracer.
+func (t *prestateTracer) CaptureSystemTx() {}
+
+// CaptureBalanceChange implements tracers.SystemTracer. The balances modified by
+// the consensus engine are part of the prestate, valued before the change.
+func (t *prestateTracer) CaptureBalanceChange(addr common.Address, prev, post *big.Int, reason string) {
+	delta, ok := t.balances[addr]
+	if !ok {
+		delta = new(big.Int)
+		t.balances[addr] = delta
+	}
+	delta.Add(delta, post).Sub(delta, prev)
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount fetches details of an account and adds it to the prestate
+// if it doesn't exist there.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	balance := new(big.Int).Set(t.env.StateDB.GetBalance(addr))
+	if delta, ok := t.balances[addr]; ok {
+		balance.Sub(balance, delta)
+	}
+	t.pre[addr] = &account{
+		Balance: balance,
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    t.env.StateDB.GetCode(addr),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage fetches the requested storage slot and adds it to the prestate
+// of the given contract. It assumes `lookupAccount` has been performed on the
+// contract before.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[key]; ok {
+		return
+	}
+	t.pre[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
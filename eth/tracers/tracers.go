@@ -20,6 +20,7 @@ package tracers
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -42,7 +43,20 @@ type Tracer interface {
 	Stop(err error)
 }
 
-type lookupFunc func(string, *Context) (Tracer, error)
+// SystemTracer is an optional interface of tracers, notified of the state changes
+// which the consensus engine applies on its own rather than on behalf of a user.
+type SystemTracer interface {
+	// CaptureSystemTx is called before executing a system transaction, one the
+	// consensus engine applies when finalising the block, e.g. the Parlia reward
+	// distribution, slashing or system contract initialisation.
+	CaptureSystemTx()
+
+	// CaptureBalanceChange is called when the consensus engine modifies the
+	// balance of an account directly, outside of the EVM.
+	CaptureBalanceChange(addr common.Address, prev, new *big.Int, reason string)
+}
+
+type lookupFunc func(string, *Context, json.RawMessage) (Tracer, error)
 
 var (
 	lookups []lookupFunc
@@ -61,10 +75,11 @@ func RegisterLookup(wildcard bool, lookup lookupFunc) {
 }
 
 // New returns a new instance of a tracer, by iterating through the
-// registered lookups.
-func New(code string, ctx *Context) (Tracer, error) {
+// registered lookups. The optional cfg is passed through to the tracer to
+// tune its behaviour, its format is tracer specific.
+func New(code string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
 	for _, lookup := range lookups {
-		if tracer, err := lookup(code, ctx); err == nil {
+		if tracer, err := lookup(code, ctx, cfg); err == nil {
 			return tracer, nil
 		}
 	}
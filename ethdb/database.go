@@ -98,6 +98,14 @@ type AncientWriter interface {
 	// TruncateAncients discards all but the first n ancient data from the ancient store.
 	TruncateAncients(n uint64) error
 
+	// TruncateTail discards the ancient data below block n from the ancient
+	// store. Data may be retained partially, AncientOffSet reports the new tail.
+	TruncateTail(n uint64) error
+
+	// RepairAncient overwrites a corrupted ancient item with its original content.
+	// The re-encoded item must be of the same size as the stored one.
+	RepairAncient(kind string, number uint64, item []byte) error
+
 	// Sync flushes all in-memory ancient store data to disk.
 	Sync() error
 }
@@ -127,6 +135,10 @@ type AncientStore interface {
 type DiffStore interface {
 	DiffStore() KeyValueStore
 	SetDiffStore(diff KeyValueStore)
+
+	// FreezeDiffLayers makes the chain freezer move the diff layers of frozen
+	// blocks from the diff store into the ancient store.
+	FreezeDiffLayers()
 }
 
 // Database contains all the methods required by the high level database to not
@@ -19,27 +19,39 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
+
+	// Force-load the native tracers to trigger registration
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 )
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
 )
 
+// internalCallsTimeout is the maximum time spent tracing a block to resolve the
+// internal calls of its transactions.
+const internalCallsTimeout = 10 * time.Second
+
 type Long int64
 
 // ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
@@ -151,6 +163,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // AccessTuple represents EIP-2930
 type AccessTuple struct {
 	address     common.Address
@@ -408,6 +424,113 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+func (t *Transaction) IsSystemTransaction(ctx context.Context) (bool, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return false, err
+	}
+	posa, ok := t.backend.Engine().(consensus.PoSA)
+	if !ok {
+		return false, nil
+	}
+	header, err := t.block.resolveHeader(ctx)
+	if err != nil || header == nil {
+		return false, err
+	}
+	return posa.IsSystemTransaction(tx, header)
+}
+
+func (t *Transaction) InternalCalls(ctx context.Context) (*[]*InternalCall, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return nil, err
+	}
+	traces, err := t.block.resolveCalls(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(traces)) {
+		return nil, fmt.Errorf("transaction %#x not traced in block", t.hash)
+	}
+	trace := traces[t.index]
+	if trace.err != nil {
+		return nil, trace.err
+	}
+	calls := make([]*InternalCall, 0)
+	var flatten func(frames []*callFrame, depth int32)
+	flatten = func(frames []*callFrame, depth int32) {
+		for _, frame := range frames {
+			calls = append(calls, &InternalCall{frame: frame, depth: depth})
+			flatten(frame.Calls, depth+1)
+		}
+	}
+	flatten(trace.top.Calls, 1)
+	return &calls, nil
+}
+
+// callFrame is a call frame as produced by the native call tracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  *hexutil.Bytes  `json:"output"`
+	Error   string          `json:"error"`
+	Calls   []*callFrame    `json:"calls"`
+}
+
+// InternalCall represents a call frame executed within a transaction.
+type InternalCall struct {
+	frame *callFrame
+	depth int32
+}
+
+func (c *InternalCall) Type(ctx context.Context) string {
+	return c.frame.Type
+}
+
+func (c *InternalCall) Depth(ctx context.Context) int32 {
+	return c.depth
+}
+
+func (c *InternalCall) From(ctx context.Context) common.Address {
+	return c.frame.From
+}
+
+func (c *InternalCall) To(ctx context.Context) *common.Address {
+	return c.frame.To
+}
+
+func (c *InternalCall) Value(ctx context.Context) *hexutil.Big {
+	return c.frame.Value
+}
+
+func (c *InternalCall) Gas(ctx context.Context) hexutil.Uint64 {
+	return c.frame.Gas
+}
+
+func (c *InternalCall) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return c.frame.GasUsed
+}
+
+func (c *InternalCall) Input(ctx context.Context) hexutil.Bytes {
+	return c.frame.Input
+}
+
+func (c *InternalCall) Output(ctx context.Context) *hexutil.Bytes {
+	return c.frame.Output
+}
+
+func (c *InternalCall) Error(ctx context.Context) *string {
+	if c.frame.Error == "" {
+		return nil
+	}
+	return &c.frame.Error
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -420,6 +543,67 @@ type Block struct {
 	header       *types.Header
 	block        *types.Block
 	receipts     []*types.Receipt
+
+	callsOnce sync.Once  // Guards the block trace, transactions may resolve concurrently
+	calls     []*txCalls // Call traces of the block's transactions, by index
+	callsErr  error      // Error encountered while tracing the block
+}
+
+// txCalls is the call trace of a single transaction within a block.
+type txCalls struct {
+	top *callFrame
+	err error
+}
+
+// resolveCalls traces the block with the call tracer, returning the top level
+// call frame of every transaction. The block is only traced once, however many
+// of its transactions are queried for their internal calls.
+func (b *Block) resolveCalls(ctx context.Context) ([]*txCalls, error) {
+	b.callsOnce.Do(func() {
+		b.calls, b.callsErr = b.traceCalls(ctx)
+	})
+	return b.calls, b.callsErr
+}
+
+func (b *Block) traceCalls(ctx context.Context) ([]*txCalls, error) {
+	backend, ok := b.backend.(tracers.Backend)
+	if !ok {
+		return nil, errors.New("internal calls are not available on this node")
+	}
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Bound the total tracing work, the deadline also interrupts the per
+	// transaction tracers once exceeded.
+	ctx, cancel := context.WithTimeout(ctx, internalCallsTimeout)
+	defer cancel()
+
+	tracer := "callTracer"
+	results, err := tracers.NewAPI(backend).TraceBlockByHash(ctx, hash, &tracers.TraceConfig{Tracer: &tracer})
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]*txCalls, len(results))
+	for i, res := range results {
+		calls[i] = new(txCalls)
+		if res.Error != "" {
+			calls[i].err = errors.New(res.Error)
+			continue
+		}
+		raw, ok := res.Result.(json.RawMessage)
+		if !ok {
+			calls[i].err = fmt.Errorf("unexpected call trace type %T", res.Result)
+			continue
+		}
+		top := new(callFrame)
+		if err := json.Unmarshal(raw, top); err != nil {
+			calls[i].err = err
+			continue
+		}
+		calls[i].top = top
+	}
+	return calls, nil
 }
 
 // resolve returns the internal Block object representing this block, fetching
@@ -690,6 +874,58 @@ func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, erro
 	}, nil
 }
 
+// parliaEngine returns the Parlia consensus engine of the backend, or nil if the
+// chain is not running Parlia.
+func parliaEngine(backend ethapi.Backend) *parlia.Parlia {
+	engine, _ := backend.Engine().(*parlia.Parlia)
+	return engine
+}
+
+func (b *Block) Validator(ctx context.Context) (*common.Address, error) {
+	engine := parliaEngine(b.backend)
+	if engine == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := engine.Author(header)
+	if err != nil {
+		return nil, err
+	}
+	return &validator, nil
+}
+
+func (b *Block) InTurn(ctx context.Context) (*bool, error) {
+	engine := parliaEngine(b.backend)
+	if engine == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inTurn := engine.InTurn(header)
+	return &inTurn, nil
+}
+
+func (b *Block) ValidatorSet(ctx context.Context) (*[]common.Address, error) {
+	engine := parliaEngine(b.backend)
+	if engine == nil {
+		return nil, nil
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := engine.EpochValidators(header)
+	if err != nil || validators == nil {
+		return nil, err
+	}
+	return &validators, nil
+}
+
 func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -1115,6 +1351,26 @@ func (r *Resolver) ChainID(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*r.backend.ChainConfig().ChainID), nil
 }
 
+func (r *Resolver) Validators(ctx context.Context, args struct{ Block *Long }) ([]common.Address, error) {
+	engine := parliaEngine(r.backend)
+	if engine == nil || r.backend.Chain() == nil {
+		return nil, errors.New("validators are only available on Parlia chains")
+	}
+	number := rpc.LatestBlockNumber
+	if args.Block != nil {
+		if *args.Block < 0 {
+			return nil, errors.New("invalid block number")
+		}
+		number = rpc.BlockNumber(*args.Block)
+	}
+	for _, api := range engine.APIs(r.backend.Chain()) {
+		if service, ok := api.Service.(*parlia.API); ok {
+			return service.GetValidators(&number)
+		}
+	}
+	return nil, errors.New("parlia API unavailable")
+}
+
 // SyncState represents the synchronisation status returned from the `syncing` accessor.
 type SyncState struct {
 	progress ethereum.SyncProgress
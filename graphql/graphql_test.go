@@ -17,6 +17,8 @@
 package graphql
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -26,13 +28,18 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 
@@ -179,6 +186,11 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			want: `{"data":{"block":{"number":1,"transactions":[{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x64","hash":"0x4f7b8d718145233dcf7f29e34a969c63dd4de8715c054ea2af022b66c4f4633e","type":0,"accessList":[],"index":0},{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","hash":"0x9c6c2c045b618fe87add0e49ba3ca00659076ecae00fd51de3ba5d4ccf9dbf40","type":1,"accessList":[{"address":"0x0000000000000000000000000000000000000dad","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000000"]}],"index":1}]}}}`,
 			code: 200,
 		},
+		{ // Parlia fields are null on other engines, internal calls are traced
+			body: `{"query": "{block {number validator inTurn validatorSet transactions { isSystemTransaction internalCalls { type depth }}}}"}`,
+			want: `{"data":{"block":{"number":1,"validator":null,"inTurn":null,"validatorSet":null,"transactions":[{"isSystemTransaction":false,"internalCalls":[]},{"isSystemTransaction":false,"internalCalls":[]}]}}}`,
+			code: 200,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
@@ -197,6 +209,85 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 	}
 }
 
+// parliaBackend is a backend serving a single block header of a chain running
+// Parlia, enough to resolve the consensus specific fields.
+type parliaBackend struct {
+	ethapi.Backend
+	engine *parlia.Parlia
+	header *types.Header
+}
+
+func (b *parliaBackend) Engine() consensus.Engine { return b.engine }
+
+func (b *parliaBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.header, nil
+}
+
+// Tests that the Parlia specific fields are resolved from the block header.
+func TestGraphQLParliaFields(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	validator := crypto.PubkeyToAddress(testSignerKey.PublicKey)
+	validators := []common.Address{{0x1}, validator}
+
+	// Assemble an epoch block sealed in turn, listing the validator set
+	extra := make([]byte, 32, 32+len(validators)*common.AddressLength+crypto.SignatureLength)
+	for _, val := range validators {
+		extra = append(extra, val.Bytes()...)
+	}
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+
+	header := &types.Header{
+		Number:     big.NewInt(200),
+		Coinbase:   validator,
+		Difficulty: big.NewInt(2),
+		Extra:      extra,
+	}
+	backend := &parliaBackend{
+		engine: parlia.New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{}),
+		header: header,
+	}
+	block := &Block{backend: backend, hash: header.Hash()}
+
+	ctx := context.Background()
+	if have, err := block.Validator(ctx); err != nil || have == nil || *have != validator {
+		t.Errorf("validator mismatch: have %v (%v), want %x", have, err, validator)
+	}
+	if have, err := block.InTurn(ctx); err != nil || have == nil || !*have {
+		t.Errorf("in-turn mismatch: have %v (%v), want true", have, err)
+	}
+	have, err := block.ValidatorSet(ctx)
+	if err != nil || have == nil || len(*have) != len(validators) {
+		t.Fatalf("validator set mismatch: have %v (%v), want %v", have, err, validators)
+	}
+	for i, val := range *have {
+		if val != validators[i] {
+			t.Errorf("validator %d mismatch: have %x, want %x", i, val, validators[i])
+		}
+	}
+	// Blocks outside of the epoch boundary don't list the validators
+	header.Number = big.NewInt(201)
+	block = &Block{backend: backend, hash: header.Hash()}
+	if have, err := block.ValidatorSet(ctx); err != nil || have != nil {
+		t.Errorf("non-epoch validator set mismatch: have %v (%v), want nil", have, err)
+	}
+	// Only free transfers of the validator into system contracts are system transactions
+	signer := types.NewEIP155Signer(config.ChainID)
+	contract := common.HexToAddress(systemcontract.ValidatorContract)
+	for i, tt := range []struct {
+		gasPrice *big.Int
+		want     bool
+	}{
+		{gasPrice: common.Big0, want: true},
+		{gasPrice: common.Big1, want: false},
+	} {
+		tx, _ := types.SignTx(types.NewTransaction(0, contract, common.Big0, 50000, tt.gasPrice, nil), signer, testSignerKey)
+		transaction := &Transaction{backend: backend, hash: tx.Hash(), tx: tx, block: block}
+		if have, err := transaction.IsSystemTransaction(ctx); err != nil || have != tt.want {
+			t.Errorf("test %d: system transaction mismatch: have %v (%v), want %v", i, have, err, tt.want)
+		}
+	}
+}
+
 // Tests that a graphQL request is not handled successfully when graphql is not enabled on the specified endpoint
 func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	stack := createNode(t, false, false)
@@ -234,16 +325,40 @@ func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	return stack
 }
 
+// testSignerKey is the key of the single signer of the test chains.
+var testSignerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// cliqueExtraData returns the genesis extra-data authorizing the given signer.
+func cliqueExtraData(signer common.Address) []byte {
+	extra := make([]byte, 32+common.AddressLength+crypto.SignatureLength)
+	copy(extra[32:], signer[:])
+	return extra
+}
+
+// sealCliqueChain links up and signs the generated blocks, as the chain maker
+// leaves them unsealed. The blocks must be generated in-turn.
+func sealCliqueChain(blocks []*types.Block, key *ecdsa.PrivateKey) {
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, 32+crypto.SignatureLength)
+
+		sig, _ := crypto.Sign(clique.SealHash(header).Bytes(), key)
+		copy(header.Extra[32:], sig)
+		blocks[i] = block.WithSeal(header)
+	}
+}
+
 func createGQLService(t *testing.T, stack *node.Node) {
 	// create backend
 	ethConf := &ethconfig.Config{
 		Genesis: &core.Genesis{
-			Config:     params.AllEthashProtocolChanges,
+			Config:     params.AllCliqueProtocolChanges,
 			GasLimit:   11500000,
-			Difficulty: big.NewInt(1048576),
-		},
-		Ethash: ethash.Config{
-			PowMode: ethash.ModeFake,
+			Difficulty: big.NewInt(1),
+			ExtraData:  cliqueExtraData(crypto.PubkeyToAddress(testSignerKey.PublicKey)),
 		},
 		NetworkId:               1337,
 		TrieCleanCache:          5,
@@ -258,8 +373,11 @@ func createGQLService(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create eth backend: %v", err)
 	}
 	// Create some blocks and import them
-	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(),
-		ethash.NewFaker(), ethBackend.ChainDb(), 10, func(i int, gen *core.BlockGen) {})
+	chain, _ := core.GenerateChain(params.AllCliqueProtocolChanges, ethBackend.BlockChain().Genesis(),
+		ethBackend.Engine(), ethBackend.ChainDb(), 10, func(i int, gen *core.BlockGen) {
+			gen.SetDifficulty(big.NewInt(2))
+		})
+	sealCliqueChain(chain, testSignerKey)
 	_, err = ethBackend.BlockChain().InsertChain(chain)
 	if err != nil {
 		t.Fatalf("could not create import blocks: %v", err)
@@ -273,16 +391,17 @@ func createGQLService(t *testing.T, stack *node.Node) {
 
 func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 	// create backend
-	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	key := testSignerKey
 	address := crypto.PubkeyToAddress(key.PublicKey)
 	funds := big.NewInt(1000000000)
 	dad := common.HexToAddress("0x0000000000000000000000000000000000000dad")
 
 	ethConf := &ethconfig.Config{
 		Genesis: &core.Genesis{
-			Config:     params.AllEthashProtocolChanges,
+			Config:     params.AllCliqueProtocolChanges,
 			GasLimit:   11500000,
-			Difficulty: big.NewInt(1048576),
+			Difficulty: big.NewInt(1),
+			ExtraData:  cliqueExtraData(address),
 			Alloc: core.GenesisAlloc{
 				address: {Balance: funds},
 				// The address 0xdad sloads 0x00 and 0x01
@@ -298,9 +417,6 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 				},
 			},
 		},
-		Ethash: ethash.Config{
-			PowMode: ethash.ModeFake,
-		},
 		NetworkId:               1337,
 		TrieCleanCache:          5,
 		TrieCleanCacheJournal:   "triecache",
@@ -337,12 +453,15 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 	})
 
 	// Create some blocks and import them
-	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(),
-		ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, b *core.BlockGen) {
-			b.SetCoinbase(common.Address{1})
+	chain, _ := core.GenerateChain(params.AllCliqueProtocolChanges, ethBackend.BlockChain().Genesis(),
+		ethBackend.Engine(), ethBackend.ChainDb(), 1, func(i int, b *core.BlockGen) {
+			// Clique credits the fees to the signer of the block
+			b.SetCoinbase(address)
+			b.SetDifficulty(big.NewInt(2))
 			b.AddTx(legacyTx)
 			b.AddTx(envelopTx)
 		})
+	sealCliqueChain(chain, key)
 
 	_, err = ethBackend.BlockChain().InsertChain(chain)
 	if err != nil {
@@ -31,11 +31,6 @@ const schema string = `
     # Long is a 64 bit unsigned integer.
     scalar Long
 
-    schema {
-        query: Query
-        mutation: Mutation
-    }
-
     # Account is an Ethereum account at a particular block.
     type Account {
         # Address is the address owning the account.
@@ -67,6 +62,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was reverted due to a chain reorganisation.
+        # This can only be the case for logs delivered by subscriptions.
+        removed: Boolean!
     }
 
     #EIP-2718 
@@ -127,6 +125,40 @@ const schema string = `
         #Envelope transaction support
         type: Int
         accessList: [AccessTuple!]
+        # IsSystemTransaction is true if the transaction was sent by the validator
+        # of the block to a system contract as part of the Parlia consensus.
+        isSystemTransaction: Boolean!
+        # InternalCalls is the list of calls made during the execution of this
+        # transaction, excluding the top-level call, in the order they were
+        # entered. The transaction is re-executed to collect them. If the
+        # transaction has not yet been mined, this field will be null.
+        internalCalls: [InternalCall!]
+    }
+
+    # InternalCall is a call frame executed within a transaction.
+    type InternalCall {
+        # Type is the kind of call: CALL, CALLCODE, DELEGATECALL, STATICCALL,
+        # CREATE, CREATE2 or SELFDESTRUCT.
+        type: String!
+        # Depth is the nesting level of the call, starting at 1 for the calls
+        # made by the top-level call of the transaction.
+        depth: Int!
+        # From is the address making the call.
+        from: Address!
+        # To is the address the call is sent to.
+        to: Address
+        # Value is the value, in wei, sent along with the call.
+        value: BigInt
+        # Gas is the amount of gas available to the call.
+        gas: Long!
+        # GasUsed is the amount of gas used by the call.
+        gasUsed: Long!
+        # Input is the data sent to the callee.
+        input: Bytes!
+        # Output is the data returned by the callee.
+        output: Bytes
+        # Error is the reason the call failed, null if it succeeded.
+        error: String
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -218,6 +250,15 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Validator is the validator which sealed this block. This will be null
+        # if the chain is not running Parlia.
+        validator: Address
+        # InTurn is true if the block was sealed by the validator whose turn it
+        # was. This will be null if the chain is not running Parlia.
+        inTurn: Boolean
+        # ValidatorSet is the set of validators listed in an epoch block. This
+        # will be null for other blocks or if the chain is not running Parlia.
+        validatorSet: [Address!]
     }
 
     # CallData represents the data associated with a local contract call.
@@ -323,6 +364,9 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # Validators returns the validator set of the Parlia consensus at the
+        # given block, or at the most recent known block if none is supplied.
+        validators(block: Long): [Address!]!
     }
 
     type Mutation {
@@ -330,3 +374,31 @@ const schema string = `
         sendRawTransaction(data: Bytes!): Bytes32!
     }
 `
+
+// subscriptionSchema declares the subscriptions served over websocket, on top of
+// the types of schema. The root resolver of a schema has to serve both the logs
+// query and the logs subscription, so the subscriptions come with a query root
+// of their own.
+const subscriptionSchema string = `
+    schema {
+        query: SubscriptionQuery
+        subscription: Subscription
+    }
+
+    # SubscriptionQuery is the query root of the websocket endpoint. Queries are
+    # served by the HTTP endpoint.
+    type SubscriptionQuery {
+        # ChainID returns the current chain ID for transaction replay protection.
+        chainID: BigInt!
+    }
+
+    type Subscription {
+        # NewBlock fires for every new head of the canonical chain.
+        newBlock: Block!
+        # Logs fires for every log matching the filter in the blocks added to the
+        # canonical chain, and again with removed set if the block is reorged out.
+        logs(filter: BlockFilterCriteria): Log!
+        # PendingTransactions fires for every transaction entering the pool.
+        pendingTransactions: Transaction!
+    }
+`
@@ -65,7 +65,8 @@ func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
+// It additionally exports an interactive query browser on the / endpoint, and
+// serves subscriptions over the graphql-ws protocol on the websocket endpoint.
 func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
 	q := Resolver{backend}
 
@@ -80,5 +81,14 @@ func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string)
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
 	stack.RegisterHandler("GraphQL", "/graphql/", handler)
 
+	// Serve the subscriptions on the websocket endpoint
+	sub := SubscriptionResolver{backend: backend}
+
+	ss, err := graphql.ParseSchema(schema+subscriptionSchema, &sub)
+	if err != nil {
+		return err
+	}
+	stack.RegisterWebsocketHandler("GraphQL subscriptions", wsProtocol, newWSHandler(ss, cors))
+
 	return nil
 }
@@ -0,0 +1,289 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// wsProtocol is the websocket subprotocol of GraphQL subscriptions, as
+	// defined by subscriptions-transport-ws.
+	wsProtocol = "graphql-ws"
+
+	wsReadLimit     = 1024 * 1024
+	wsWriteTimeout  = 10 * time.Second
+	wsKeepAliveTime = 30 * time.Second
+	wsMaxOperations = 100 // Operations running at once on a single connection
+)
+
+// Message types of the graphql-ws protocol.
+const (
+	gqlConnectionInit      = "connection_init"      // Client -> Server
+	gqlConnectionTerminate = "connection_terminate" // Client -> Server
+	gqlStart               = "start"                // Client -> Server
+	gqlStop                = "stop"                 // Client -> Server
+	gqlConnectionAck       = "connection_ack"       // Server -> Client
+	gqlConnectionError     = "connection_error"     // Server -> Client
+	gqlConnectionKeepAlive = "ka"                   // Server -> Client
+	gqlData                = "data"                 // Server -> Client
+	gqlError               = "error"                // Server -> Client
+	gqlComplete            = "complete"             // Server -> Client
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsHandler serves GraphQL operations, and subscriptions in particular, over
+// websocket connections speaking the graphql-ws protocol.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// newWSHandler creates a graphql-ws handler accepting connections from the given
+// origins. Without any origins, only same origin connections are accepted.
+func newWSHandler(schema *graphql.Schema, origins []string) *wsHandler {
+	h := &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{wsProtocol},
+		},
+	}
+	if len(origins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range origins {
+				if allowed == "*" || strings.EqualFold(allowed, origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return h
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader already replied with an error
+	}
+	c := &wsConn{
+		schema: h.schema,
+		conn:   conn,
+		subs:   make(map[string]*wsSubscription),
+	}
+	c.serve()
+}
+
+// wsConn is a graphql-ws connection with the operations running on it.
+type wsConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+	wg     sync.WaitGroup
+
+	writeLock sync.Mutex
+	subsLock  sync.Mutex
+	subs      map[string]*wsSubscription
+}
+
+// wsSubscription is an operation started on a graphql-ws connection.
+type wsSubscription struct {
+	cancel context.CancelFunc
+}
+
+// serve processes the messages of the client until the connection is closed or
+// terminated, stopping all operations started on it when returning.
+func (c *wsConn) serve() {
+	defer c.conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.wg.Wait()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	initialized := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case gqlConnectionInit:
+			if initialized {
+				continue
+			}
+			initialized = true
+			c.write(wsMessage{Type: gqlConnectionAck})
+			c.write(wsMessage{Type: gqlConnectionKeepAlive})
+
+			c.wg.Add(1)
+			go c.keepAlive(ctx)
+
+		case gqlStart:
+			if !initialized {
+				c.write(wsMessage{Type: gqlConnectionError, Payload: wsErrorPayload("connection not initialized")})
+				return
+			}
+			c.start(ctx, msg)
+
+		case gqlStop:
+			c.stop(msg.ID)
+
+		case gqlConnectionTerminate:
+			return
+
+		default:
+			c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload("unknown message type " + msg.Type)})
+		}
+	}
+}
+
+// start executes an operation in the background, delivering every result of it
+// to the client followed by a completion message.
+func (c *wsConn) start(ctx context.Context, msg wsMessage) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(msg.Payload, &params); err != nil {
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload(err.Error())})
+		return
+	}
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	if _, ok := c.subs[msg.ID]; ok {
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload("operation id already in use")})
+		return
+	}
+	if len(c.subs) >= wsMaxOperations {
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload("too many operations")})
+		return
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	responses, err := c.schema.Subscribe(subCtx, params.Query, params.OperationName, params.Variables)
+	if err != nil {
+		cancel()
+		c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload(err.Error())})
+		return
+	}
+	sub := &wsSubscription{cancel: cancel}
+	c.subs[msg.ID] = sub
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for response := range responses {
+			payload, err := json.Marshal(response)
+			if err != nil {
+				err = c.write(wsMessage{ID: msg.ID, Type: gqlError, Payload: wsErrorPayload(err.Error())})
+			} else {
+				err = c.write(wsMessage{ID: msg.ID, Type: gqlData, Payload: payload})
+			}
+			if err != nil {
+				// The connection is gone, stop the operation and drop its results
+				cancel()
+				for range responses {
+				}
+				break
+			}
+		}
+		c.subsLock.Lock()
+		if c.subs[msg.ID] == sub {
+			delete(c.subs, msg.ID)
+		}
+		c.subsLock.Unlock()
+		cancel()
+
+		if ctx.Err() == nil {
+			c.write(wsMessage{ID: msg.ID, Type: gqlComplete})
+		}
+	}()
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.subsLock.Lock()
+	defer c.subsLock.Unlock()
+
+	if sub, ok := c.subs[id]; ok {
+		sub.cancel()
+		delete(c.subs, id)
+	}
+}
+
+// keepAlive periodically pings the client until the connection is closed.
+func (c *wsConn) keepAlive(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.write(wsMessage{Type: gqlConnectionKeepAlive}) != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// write sends a message to the client. On failure the connection is closed, so
+// that the pending read fails too and all operations on it are stopped.
+func (c *wsConn) write(msg wsMessage) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// wsErrorPayload creates the payload of an error message.
+func wsErrorPayload(message string) json.RawMessage {
+	payload, _ := json.Marshal(map[string]string{"message": message})
+	return payload
+}
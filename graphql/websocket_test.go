@@ -0,0 +1,192 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// testCounter is a resolver counting upwards on subscription.
+type testCounter struct{}
+
+func (*testCounter) Hello() string { return "world" }
+
+func (*testCounter) Count(ctx context.Context) <-chan int32 {
+	counts := make(chan int32)
+	go func() {
+		defer close(counts)
+		for i := int32(0); ; i++ {
+			select {
+			case counts <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return counts
+}
+
+func (*testCounter) Idle(ctx context.Context) <-chan int32 {
+	idle := make(chan int32)
+	go func() {
+		<-ctx.Done()
+		close(idle)
+	}()
+	return idle
+}
+
+// Tests that operations are served over the graphql-ws protocol until stopped.
+func TestWebsocketSubscription(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+		type Query { hello: String! }
+		type Subscription { count: Int! idle: Int! }
+	`, &testCounter{})
+
+	server := httptest.NewServer(newWSHandler(schema, nil))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg wsMessage) {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("failed to send %s: %v", msg.Type, err)
+		}
+	}
+	recv := func(typ string) wsMessage {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			var msg wsMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("failed to read %s: %v", typ, err)
+			}
+			if msg.Type == gqlConnectionKeepAlive && typ != gqlConnectionKeepAlive {
+				continue
+			}
+			if msg.Type != typ {
+				t.Fatalf("message type mismatch: have %s, want %s (%s)", msg.Type, typ, msg.Payload)
+			}
+			return msg
+		}
+	}
+	send(wsMessage{Type: gqlConnectionInit})
+	recv(gqlConnectionAck)
+
+	// Queries deliver a single result
+	send(wsMessage{ID: "1", Type: gqlStart, Payload: json.RawMessage(`{"query": "{hello}"}`)})
+	if msg := recv(gqlData); msg.ID != "1" || string(msg.Payload) != `{"data":{"hello":"world"}}` {
+		t.Fatalf("query result mismatch: %s %s", msg.ID, msg.Payload)
+	}
+	if msg := recv(gqlComplete); msg.ID != "1" {
+		t.Fatalf("completed operation mismatch: have %s, want 1", msg.ID)
+	}
+	// Subscriptions deliver results until stopped
+	send(wsMessage{ID: "2", Type: gqlStart, Payload: json.RawMessage(`{"query": "subscription {count}"}`)})
+	for i := 0; i < 3; i++ {
+		msg := recv(gqlData)
+		var result struct {
+			Data struct{ Count int } `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Payload, &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		if msg.ID != "2" || result.Data.Count != i {
+			t.Fatalf("result %d mismatch: %s %s", i, msg.ID, msg.Payload)
+		}
+	}
+	// Results in flight may still arrive before the completion
+	send(wsMessage{ID: "2", Type: gqlStop})
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read completion: %v", err)
+		}
+		if msg.Type == gqlComplete {
+			if msg.ID != "2" {
+				t.Fatalf("completed operation mismatch: have %s, want 2", msg.ID)
+			}
+			break
+		}
+		if msg.Type != gqlData && msg.Type != gqlConnectionKeepAlive {
+			t.Fatalf("unexpected message after stop: %s", msg.Type)
+		}
+	}
+}
+
+// Tests that the operations running at once on a connection are capped.
+func TestWebsocketOperationLimit(t *testing.T) {
+	schema := graphql.MustParseSchema(`
+		schema {
+			query: Query
+			subscription: Subscription
+		}
+		type Query { hello: String! }
+		type Subscription { count: Int! idle: Int! }
+	`, &testCounter{})
+
+	server := httptest.NewServer(newWSHandler(schema, nil))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(wsMessage{Type: gqlConnectionInit}); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i := 0; i <= wsMaxOperations; i++ {
+		msg := wsMessage{ID: fmt.Sprint(i), Type: gqlStart, Payload: json.RawMessage(`{"query": "subscription {idle}"}`)}
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("failed to start operation %d: %v", i, err)
+		}
+	}
+	// Only the operation over the limit is rejected
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read rejection: %v", err)
+		}
+		if msg.Type == gqlConnectionAck || msg.Type == gqlConnectionKeepAlive {
+			continue
+		}
+		if msg.Type != gqlError || msg.ID != fmt.Sprint(wsMaxOperations) {
+			t.Fatalf("unexpected message: %s %s %s", msg.Type, msg.ID, msg.Payload)
+		}
+		break
+	}
+}
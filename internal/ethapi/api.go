@@ -70,6 +70,64 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// GasPriceSuggestions holds slow, standard and fast gas price suggestions.
+type GasPriceSuggestions struct {
+	Slow     *hexutil.Big `json:"slow"`
+	Standard *hexutil.Big `json:"standard"`
+	Fast     *hexutil.Big `json:"fast"`
+}
+
+// SuggestGasPrices returns gas price suggestions with increasing chances of a
+// quick inclusion, sampled from recent blocks.
+func (s *PublicEthereumAPI) SuggestGasPrices(ctx context.Context) (*GasPriceSuggestions, error) {
+	prices, err := s.b.SuggestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GasPriceSuggestions{
+		Slow:     (*hexutil.Big)(prices.Slow),
+		Standard: (*hexutil.Big)(prices.Standard),
+		Fast:     (*hexutil.Big)(prices.Fast),
+	}, nil
+}
+
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio of a range of blocks and the gas prices
+// paid at the requested percentiles, excluding consensus system transactions.
+// The chain has no base fee, so it is reported as zero for compatibility.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, gasUsed, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsed,
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, w := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(w))
+			for j, v := range w {
+				results.Reward[i][j] = (*hexutil.Big)(v)
+			}
+		}
+	}
+	if len(gasUsed) > 0 {
+		results.BaseFee = make([]*hexutil.Big, len(gasUsed)+1)
+		for i := range results.BaseFee {
+			results.BaseFee[i] = new(hexutil.Big)
+		}
+	}
+	return results, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up to date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronise from
@@ -967,6 +1025,270 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	return result.Return(), result.Err
 }
 
+// BlockOverrides is a set of block context fields to override when executing
+// messages on top of a block.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+}
+
+// Apply overrides the given fields into the block context.
+func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
+	if diff == nil {
+		return
+	}
+	if diff.Number != nil {
+		blockCtx.BlockNumber = diff.Number.ToInt()
+	}
+	if diff.Difficulty != nil {
+		blockCtx.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.Time != nil {
+		blockCtx.Time = new(big.Int).SetUint64(uint64(*diff.Time))
+	}
+	if diff.GasLimit != nil {
+		blockCtx.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		blockCtx.Coinbase = *diff.Coinbase
+	}
+}
+
+// RPCDiffAccount is the state of an account on one side of a state diff.
+type RPCDiffAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   hexutil.Uint64              `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// RPCAccountDiff holds the state of an account before and after a message,
+// with a nil side meaning the account didn't exist at that point.
+type RPCAccountDiff struct {
+	Pre  *RPCDiffAccount `json:"pre"`
+	Post *RPCDiffAccount `json:"post"`
+}
+
+func newRPCDiffAccount(account *state.DiffAccount) *RPCDiffAccount {
+	if account == nil {
+		return nil
+	}
+	return &RPCDiffAccount{
+		Balance: (*hexutil.Big)(account.Balance),
+		Nonce:   hexutil.Uint64(account.Nonce),
+		Code:    account.Code,
+		Storage: account.Storage,
+	}
+}
+
+// NewRPCStateDiff converts a state diff into its RPC representation.
+func NewRPCStateDiff(diff state.StateDiff) map[common.Address]*RPCAccountDiff {
+	result := make(map[common.Address]*RPCAccountDiff, len(diff))
+	for addr, account := range diff {
+		result[addr] = &RPCAccountDiff{
+			Pre:  newRPCDiffAccount(account.Pre),
+			Post: newRPCDiffAccount(account.Post),
+		}
+	}
+	return result
+}
+
+// CallManyResult is the outcome of a single message executed as part of a
+// sequence by eth_callMany or eth_callBundle.
+type CallManyResult struct {
+	TxHash     *common.Hash                       `json:"txHash,omitempty"`
+	GasUsed    hexutil.Uint64                     `json:"gasUsed"`
+	ReturnData hexutil.Bytes                      `json:"returnData"`
+	Error      string                             `json:"error,omitempty"`
+	Logs       []*types.Log                       `json:"logs"`
+	StateDiff  map[common.Address]*RPCAccountDiff `json:"stateDiff"`
+}
+
+// DoCallMany executes the given messages in order on top of the same state, so
+// that every message observes the changes made by the previous ones. Signed
+// transactions pass their hashes in txHashes, plain calls leave it empty.
+func DoCallMany(ctx context.Context, b Backend, msgs []types.Message, txHashes []common.Hash, state *state.StateDB, header *types.Header, blockOverrides *BlockOverrides, timeout time.Duration) ([]*CallManyResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call sequence finished", "runtime", time.Since(start)) }(time.Now())
+
+	// Setup context so it may be cancelled the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	// Make sure the context is cancelled when the calls have completed
+	// this makes sure resources are cleaned up.
+	defer cancel()
+
+	var (
+		results   = make([]*CallManyResult, 0, len(msgs))
+		gp        = new(core.GasPool).AddGas(math.MaxUint64)
+		deleteObj = b.ChainConfig().IsEIP158(header.Number)
+	)
+	// Seal any state overrides applied by the caller, otherwise they would be
+	// reported as changes made by the first message. Empty accounts are kept
+	// as the overrides might have set them up deliberately.
+	state.Finalise(false)
+
+	for i, msg := range msgs {
+		// Logs are collected by transaction hash, plain calls get a placeholder
+		var txHash common.Hash
+		if i < len(txHashes) {
+			txHash = txHashes[i]
+		} else {
+			txHash = common.BigToHash(big.NewInt(int64(i + 1)))
+		}
+		state.Prepare(txHash, header.Hash(), i)
+
+		evm, vmError, err := b.GetEVM(ctx, msg, state, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		blockOverrides.Apply(&evm.Context)
+
+		// Wait for the context to be done and cancel the evm. Even if the
+		// EVM has finished, cancelling may be done (repeatedly)
+		gopool.Submit(func() {
+			<-ctx.Done()
+			evm.Cancel()
+		})
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		res := &CallManyResult{
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			ReturnData: result.ReturnData,
+			Logs:       state.GetLogs(txHash),
+			StateDiff:  NewRPCStateDiff(state.JournalDiff(deleteObj)),
+		}
+		if i < len(txHashes) {
+			res.TxHash = &txHashes[i]
+		} else {
+			for _, log := range res.Logs {
+				log.TxHash = common.Hash{}
+			}
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+			if len(result.Revert()) > 0 {
+				res.Error = newRevertError(result).Error()
+			}
+		}
+		results = append(results, res)
+
+		// Seal the changes so the next message starts from a clean journal
+		state.Finalise(deleteObj)
+	}
+	return results, nil
+}
+
+// CallMany executes the given calls in order on top of the state of the given
+// block, each call observing the changes of the previous ones. Nothing is
+// persisted to the state or the chain.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) ([]*CallManyResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("no calls specified")
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	msgs := make([]types.Message, len(calls))
+	for i, call := range calls {
+		msgs[i] = call.ToMessage(s.b.RPCGasCap())
+	}
+	return DoCallMany(ctx, s.b, msgs, nil, state, header, blockOverrides, 5*time.Second)
+}
+
+// CallBundleArgs represents the arguments for simulating a bundle of signed
+// transactions.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes        `json:"txs"`
+	StateBlockNumber *rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	StateOverrides   *StateOverride         `json:"stateOverrides"`
+	BlockOverrides   *BlockOverrides        `json:"blockOverrides"`
+}
+
+// CallBundleResult is the outcome of simulating a bundle.
+type CallBundleResult struct {
+	BundleHash       common.Hash       `json:"bundleHash"`
+	StateBlockNumber hexutil.Uint64    `json:"stateBlockNumber"`
+	GasUsed          hexutil.Uint64    `json:"totalGasUsed"`
+	Results          []*CallManyResult `json:"results"`
+}
+
+// CallBundle simulates a bundle of signed transactions on top of the state
+// of the given block, or the pending one if none is given. Unlike the miner,
+// it reports the outcome of every transaction even if some of them revert.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle missing txs")
+	}
+	blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if args.StateBlockNumber != nil {
+		blockNrOrHash = *args.StateBlockNumber
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := args.StateOverrides.Apply(state); err != nil {
+		return nil, err
+	}
+	var (
+		bundle = &types.Bundle{Txs: make(types.Transactions, 0, len(args.Txs))}
+		signer = types.MakeSigner(s.b.ChainConfig(), header.Number)
+		msgs   = make([]types.Message, 0, len(args.Txs))
+		hashes = make([]common.Hash, 0, len(args.Txs))
+	)
+	for _, encodedTx := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encodedTx); err != nil {
+			return nil, err
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+		msgs = append(msgs, msg)
+		hashes = append(hashes, tx.Hash())
+	}
+	results, err := DoCallMany(ctx, s.b, msgs, hashes, state, header, args.BlockOverrides, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	var gasUsed uint64
+	for _, res := range results {
+		gasUsed += uint64(res.GasUsed)
+	}
+	return &CallBundleResult{
+		BundleHash:       bundle.Hash(),
+		StateBlockNumber: hexutil.Uint64(header.Number.Uint64()),
+		GasUsed:          hexutil.Uint64(gasUsed),
+		Results:          results,
+	}, nil
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1917,17 +2239,61 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions of the given block,
+// read in one go from the database. System transactions applied by the consensus
+// engine are flagged as such.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	var (
+		hash   = block.Hash()
+		number = block.NumberU64()
+		txs    = block.Transactions()
+	)
+	receipts := rawdb.ReadReceipts(s.b.ChainDb(), hash, number, s.b.ChainConfig())
+	if receipts == nil && len(txs) > 0 {
+		// Receipts are not stored locally by light clients, retrieve them
+		if receipts, err = s.b.GetReceipts(ctx, hash); err != nil {
+			return nil, err
+		}
+	}
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d receipts, %d transactions", len(receipts), len(txs))
+	}
+	posa, isPoSA := s.b.Engine().(consensus.PoSA)
+	signer := types.MakeSigner(s.b.ChainConfig(), block.Number())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, hash, number, signer, txs[i], i)
+		if isPoSA {
+			if isSystem, _ := posa.IsSystemTransaction(txs[i], block.Header()); isSystem {
+				result[i]["systemTx"] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+// marshalReceipt converts a receipt into the RPC representation of it.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(tx.GasPrice()),
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
@@ -1947,7 +2313,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -2168,6 +2534,55 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, input
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendBundleArgs represents the arguments for submitting an atomic bundle of
+// signed transactions.
+type SendBundleArgs struct {
+	Txs          []hexutil.Bytes `json:"txs"`
+	BlockNumber  hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp *uint64         `json:"minTimestamp"`
+	MaxTimestamp *uint64         `json:"maxTimestamp"`
+}
+
+// SendBundle will add the signed transactions to the bundle pool as a single
+// bundle. The miner includes all of them, in order, at the top of the target
+// block or none of them at all.
+func (s *PublicTransactionPoolAPI) SendBundle(ctx context.Context, args SendBundleArgs) (common.Hash, error) {
+	if len(args.Txs) == 0 {
+		return common.Hash{}, errors.New("bundle missing txs")
+	}
+	if args.BlockNumber == 0 {
+		return common.Hash{}, errors.New("bundle missing blockNumber")
+	}
+	bundle := &types.Bundle{
+		Txs:         make(types.Transactions, 0, len(args.Txs)),
+		BlockNumber: new(big.Int).SetUint64(uint64(args.BlockNumber)),
+	}
+	for _, encodedTx := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encodedTx); err != nil {
+			return common.Hash{}, err
+		}
+		if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+			return common.Hash{}, err
+		}
+		if !s.b.UnprotectedAllowed() && !tx.Protected() {
+			return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = *args.MinTimestamp
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = *args.MaxTimestamp
+	}
+	if err := s.b.SendBundle(ctx, bundle); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted bundle", "hash", bundle.Hash(), "txs", len(bundle.Txs), "block", bundle.BlockNumber)
+	return bundle.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -0,0 +1,207 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// callBackend is a minimal Backend serving a fixed state for call simulation.
+// Any method not overridden panics on the nil embedded interface.
+type callBackend struct {
+	Backend
+	state  *state.StateDB
+	header *types.Header
+}
+
+func (b *callBackend) ChainConfig() *params.ChainConfig { return params.AllEthashProtocolChanges }
+func (b *callBackend) RPCGasCap() uint64                { return 25000000 }
+
+func (b *callBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.state.Copy(), b.header, nil
+}
+
+func (b *callBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMBlockContext(header, nil, &header.Coinbase)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.ChainConfig(), vm.Config{}), func() error { return nil }, nil
+}
+
+// Tests that state overrides are not reported as changes made by the first
+// call of an eth_callMany sequence.
+func TestCallManyStateDiffExcludesOverrides(t *testing.T) {
+	var (
+		sender     = common.Address{0x01}
+		recipient  = common.Address{0x02}
+		overridden = common.Address{0x03}
+		balance    = (*hexutil.Big)(big.NewInt(1000))
+		slots      = map[common.Hash]common.Hash{{0xaa}: {0xbb}}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(sender, big.NewInt(1000000))
+	statedb.Finalise(true)
+
+	api := NewPublicBlockChainAPI(&callBackend{
+		state:  statedb,
+		header: &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), GasLimit: 30000000},
+	})
+	value := (*hexutil.Big)(big.NewInt(1))
+	overrides := &StateOverride{
+		overridden: {Balance: &balance, StateDiff: &slots},
+		sender:     {Balance: (**hexutil.Big)(&value)},
+	}
+	results, err := api.CallMany(context.Background(), []CallArgs{
+		{From: &sender, To: &recipient, Value: value},
+	}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), overrides, nil)
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("result count mismatch: have %d, want 1", len(results))
+	}
+	diff := results[0].StateDiff
+	if _, ok := diff[overridden]; ok {
+		t.Errorf("overridden account reported in first state diff: %v", diff[overridden])
+	}
+	acc := diff[sender]
+	if acc == nil || acc.Pre == nil || acc.Post == nil {
+		t.Fatalf("missing sender in first state diff: %v", acc)
+	}
+	if have := (*big.Int)(acc.Pre.Balance); have.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("sender pre balance mismatch: have %v, want 1 (the override)", have)
+	}
+	if have := (*big.Int)(acc.Post.Balance); have.Sign() != 0 {
+		t.Errorf("sender post balance mismatch: have %v, want 0", have)
+	}
+	if acc := diff[recipient]; acc == nil || acc.Pre != nil || acc.Post == nil {
+		t.Errorf("recipient creation missing from first state diff: %v", acc)
+	}
+}
+
+// receiptBackend is a minimal Backend serving a single block along with its
+// receipts stored in the database.
+type receiptBackend struct {
+	Backend
+	db     ethdb.Database
+	block  *types.Block
+	engine consensus.Engine
+}
+
+func (b *receiptBackend) ChainConfig() *params.ChainConfig { return params.AllEthashProtocolChanges }
+func (b *receiptBackend) ChainDb() ethdb.Database          { return b.db }
+func (b *receiptBackend) Engine() consensus.Engine         { return b.engine }
+
+func (b *receiptBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok && hash == b.block.Hash() {
+		return b.block, nil
+	}
+	if number, ok := blockNrOrHash.Number(); ok && (number == rpc.LatestBlockNumber || uint64(number) == b.block.NumberU64()) {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+// systemEngine is a PoSA engine flagging the transactions into a single system
+// contract as system transactions.
+type systemEngine struct {
+	consensus.PoSA
+	contract common.Address
+}
+
+func (e *systemEngine) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	return tx.To() != nil && *tx.To() == e.contract, nil
+}
+
+// Tests that the receipts of a whole block are served with the fields derived
+// from their transactions, flagging the system ones on PoSA chains.
+func TestGetBlockReceipts(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		config   = params.AllEthashProtocolChanges
+		signer   = types.LatestSigner(config)
+		contract = common.Address{0x10, 0x00}
+		db       = rawdb.NewMemoryDatabase()
+	)
+	txs := make([]*types.Transaction, 3)
+	txs[0], _ = types.SignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &common.Address{0x01}, Gas: params.TxGas, GasPrice: big.NewInt(2)})
+	txs[1], _ = types.SignNewTx(key, signer, &types.AccessListTx{ChainID: config.ChainID, Nonce: 1, To: &common.Address{0x02}, Gas: params.TxGas, GasPrice: big.NewInt(3)})
+	txs[2], _ = types.SignNewTx(key, signer, &types.LegacyTx{Nonce: 2, To: &contract, Gas: params.TxGas, GasPrice: common.Big0})
+
+	receipts := make([]*types.Receipt, len(txs))
+	for i, tx := range txs {
+		receipts[i] = &types.Receipt{
+			Type:              tx.Type(),
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i+1) * params.TxGas,
+			Logs:              []*types.Log{},
+		}
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)}, txs, nil, receipts, trie.NewStackTrie(nil))
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+
+	backend := &receiptBackend{db: db, block: block, engine: &systemEngine{contract: contract}}
+	api := NewPublicTransactionPoolAPI(backend, nil)
+
+	for _, blockNrOrHash := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithHash(block.Hash(), false),
+		rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+	} {
+		results, err := api.GetBlockReceipts(context.Background(), blockNrOrHash)
+		if err != nil {
+			t.Fatalf("%v: failed to retrieve receipts: %v", blockNrOrHash, err)
+		}
+		if len(results) != len(txs) {
+			t.Fatalf("%v: receipt count mismatch: have %d, want %d", blockNrOrHash, len(results), len(txs))
+		}
+		for i, result := range results {
+			if have := result["transactionHash"]; have != txs[i].Hash() {
+				t.Errorf("%v: receipt %d transaction mismatch: have %v, want %x", blockNrOrHash, i, have, txs[i].Hash())
+			}
+			if have := result["gasUsed"]; have != hexutil.Uint64(params.TxGas) {
+				t.Errorf("%v: receipt %d gas used mismatch: have %v, want %d", blockNrOrHash, i, have, params.TxGas)
+			}
+			if have := result["effectiveGasPrice"].(*hexutil.Big); have.ToInt().Cmp(txs[i].GasPrice()) != 0 {
+				t.Errorf("%v: receipt %d effective gas price mismatch: have %v, want %v", blockNrOrHash, i, have, txs[i].GasPrice())
+			}
+			_, system := result["systemTx"]
+			if want := i == 2; system != want {
+				t.Errorf("%v: receipt %d system flag mismatch: have %v, want %v", blockNrOrHash, i, system, want)
+			}
+		}
+	}
+	// Unknown blocks have no receipts
+	results, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(common.Hash{0xff}, false))
+	if err != nil || results != nil {
+		t.Errorf("unknown block receipts mismatch: have %v (%v), want none", results, err)
+	}
+}
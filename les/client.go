@@ -298,7 +298,7 @@ func (s *LightEthereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, 5*time.Minute, s.config.RangeLimit),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, 5*time.Minute, s.config.RangeLimit, filters.LogLimits{BlockRange: s.config.RPCLogBlockRange, Results: s.config.RPCLogResultCap}),
 			Public:    true,
 		}, {
 			Namespace: "net",
@@ -18,6 +18,9 @@ package miner
 
 import (
 	"errors"
+	"fmt"
+	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -75,12 +78,27 @@ const (
 
 	// staleThreshold is the maximum depth of the acceptable stale block.
 	staleThreshold = 11
+
+	// maxSimulatedBundles is the maximum number of bundles simulated for a block,
+	// the ones with the highest minimum gas price are picked first.
+	maxSimulatedBundles = 128
 )
 
 var (
 	commitTxsTimer = metrics.NewRegisteredTimer("worker/committxs", nil)
+
+	buildBlockTimer      = metrics.NewRegisteredTimer("worker/build", nil)
+	waitBlockTimer       = metrics.NewRegisteredTimer("worker/wait", nil)
+	deadlineReachedMeter = metrics.NewRegisteredMeter("worker/deadline/reached", nil)
+
+	bundleIncludedMeter  = metrics.NewRegisteredMeter("worker/bundles/included", nil)
+	bundleDiscardedMeter = metrics.NewRegisteredMeter("worker/bundles/discarded", nil)
 )
 
+// errBundleReverted is returned if one of the transactions of a bundle fails
+// during execution, in which case the whole bundle is discarded.
+var errBundleReverted = errors.New("bundle transaction reverted")
+
 // environment is the worker's current environment and holds all of the current state information.
 type environment struct {
 	signer types.Signer
@@ -95,6 +113,26 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+
+	deadline time.Time // time after which no more transactions are committed, zero if unbounded
+}
+
+// bundleEnv is the scratch environment a bundle is executed in. It is kept
+// separate from the current environment because the state journal can't be
+// reverted across transactions, so a failing bundle is discarded by simply
+// dropping its environment.
+type bundleEnv struct {
+	state    *state.StateDB
+	gasPool  *core.GasPool
+	header   *types.Header
+	tcount   int
+	receipts []*types.Receipt
+}
+
+// bundleSim is the cached outcome of simulating a bundle on the parent state.
+type bundleSim struct {
+	sim *types.SimulatedBundle
+	err error
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -103,6 +141,7 @@ type task struct {
 	state     *state.StateDB
 	block     *types.Block
 	createdAt time.Time
+	sealStart time.Time // Time the sealing started, the engine may hold the block until its slot
 }
 
 const (
@@ -159,6 +198,12 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
 
+	// Bundle simulations on the parent state, reused across the recommits of
+	// the same block. Only accessed by the main loop.
+	bundleSimParent   common.Hash
+	bundleSimCoinbase common.Address
+	bundleSims        map[common.Hash]*bundleSim
+
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
 	extra    []byte
@@ -571,6 +616,7 @@ func (w *worker) taskLoop() {
 			if w.skipSealHook != nil && w.skipSealHook(task) {
 				continue
 			}
+			task.sealStart = time.Now()
 			w.pendingMu.Lock()
 			w.pendingTasks[sealHash] = task
 			w.pendingMu.Unlock()
@@ -610,6 +656,8 @@ func (w *worker) resultLoop() {
 				log.Error("Block found but no relative pending task", "number", block.Number(), "sealhash", sealhash, "hash", hash)
 				continue
 			}
+			waitBlockTimer.UpdateSince(task.sealStart)
+
 			// Different block could share same sealhash, deep copy here to prevent write-write conflict.
 			var (
 				receipts = make([]*types.Receipt, len(task.receipts))
@@ -652,6 +700,24 @@ func (w *worker) resultLoop() {
 	}
 }
 
+// deadlineReached reports whether the time to build the block has run out.
+func (env *environment) deadlineReached() bool {
+	return !env.deadline.IsZero() && !time.Now().Before(env.deadline)
+}
+
+// buildDeadline returns the time by which the worker has to stop committing
+// transactions for the given header, or the zero time if the consensus engine
+// doesn't bound the block building time. The deadline is the end of the slot
+// reported by the engine minus the configured leftover, which is reserved for
+// finalising, sealing and broadcasting the block.
+func (w *worker) buildDeadline(header *types.Header) time.Time {
+	delay := w.engine.Delay(w.chain, header)
+	if delay == nil {
+		return time.Time{}
+	}
+	return time.Now().Add(*delay - w.config.DelayLeftOver)
+}
+
 // makeCurrent creates a new environment for the current cycle.
 func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 	// Retrieve the parent state to execute on top and start a prefetcher for
@@ -748,6 +814,160 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
+// newBundleEnv creates a scratch environment on top of the current one.
+func (w *worker) newBundleEnv() *bundleEnv {
+	gasPool := *w.current.gasPool
+	return &bundleEnv{
+		state:   w.current.state.Copy(),
+		gasPool: &gasPool,
+		header:  types.CopyHeader(w.current.header),
+		tcount:  w.current.tcount,
+	}
+}
+
+// applyBundle executes all transactions of the bundle in the given scratch
+// environment, failing if any of them can't be applied or reverts.
+func (w *worker) applyBundle(env *bundleEnv, bundle *types.Bundle, coinbase common.Address) error {
+	for _, tx := range bundle.Txs {
+		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig(), core.NewReceiptBloomGenerator())
+		if err != nil {
+			return err
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return fmt.Errorf("%w: %v", errBundleReverted, tx.Hash())
+		}
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+	}
+	return nil
+}
+
+// producerBalance returns the funds held by the block producer, which under
+// Parlia includes the system address the transaction fees are collected in.
+func (w *worker) producerBalance(statedb *state.StateDB, coinbase common.Address) *big.Int {
+	balance := new(big.Int).Set(statedb.GetBalance(coinbase))
+	if w.chainConfig.Parlia != nil {
+		balance.Add(balance, statedb.GetBalance(consensus.SystemAddress))
+	}
+	return balance
+}
+
+// simulateBundle executes the bundle against the current state and computes
+// its effective gas price, which is everything the block producer earns from
+// it, fees and direct payments alike, divided by the gas it uses.
+func (w *worker) simulateBundle(bundle *types.Bundle, coinbase common.Address) (*types.SimulatedBundle, error) {
+	env := w.newBundleEnv()
+	before := w.producerBalance(env.state, coinbase)
+
+	if err := w.applyBundle(env, bundle, coinbase); err != nil {
+		return nil, err
+	}
+	var (
+		gasUsed = env.header.GasUsed - w.current.header.GasUsed
+		fees    = new(big.Int).Sub(w.producerBalance(env.state, coinbase), before)
+		price   = new(big.Int)
+	)
+	if gasUsed > 0 {
+		price.Div(fees, new(big.Int).SetUint64(gasUsed))
+	}
+	return &types.SimulatedBundle{
+		Bundle:         bundle,
+		GasUsed:        gasUsed,
+		TotalFees:      fees,
+		EffectivePrice: price,
+	}, nil
+}
+
+// commitBundles simulates the given bundles, ranks them by effective gas price
+// and includes them one by one at the top of the block. A bundle which fails
+// on top of the higher ranked ones is dropped as a whole.
+//
+// The simulations are cached until the parent block changes, and only up to
+// maxSimulatedBundles bundles are simulated per block, preferring the ones with
+// the highest minimum gas price.
+func (w *worker) commitBundles(bundles []*types.Bundle, coinbase common.Address, interrupt *int32) bool {
+	// Short circuit if current is nil
+	if w.current == nil {
+		return true
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+		w.current.gasPool.SubGas(params.SystemTxsGas)
+	}
+	if w.bundleSimParent != w.current.header.ParentHash || w.bundleSimCoinbase != coinbase {
+		w.bundleSimParent, w.bundleSimCoinbase = w.current.header.ParentHash, coinbase
+		w.bundleSims = make(map[common.Hash]*bundleSim)
+	}
+	sort.SliceStable(bundles, func(i, j int) bool {
+		return bundles[i].MinGasPrice().Cmp(bundles[j].MinGasPrice()) > 0
+	})
+	simulated := make([]*types.SimulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
+		}
+		if w.current.deadlineReached() {
+			deadlineReachedMeter.Mark(1)
+			return false
+		}
+		hash := bundle.Hash()
+		res, ok := w.bundleSims[hash]
+		if !ok {
+			if len(w.bundleSims) >= maxSimulatedBundles {
+				continue
+			}
+			sim, err := w.simulateBundle(bundle, coinbase)
+			if err != nil {
+				log.Debug("Discarding failed bundle", "hash", hash, "err", err)
+				bundleDiscardedMeter.Mark(1)
+			}
+			res = &bundleSim{sim: sim, err: err}
+			w.bundleSims[hash] = res
+		}
+		if res.err == nil {
+			simulated = append(simulated, res.sim)
+		}
+	}
+	sort.Sort(types.BundlesByPrice(simulated))
+
+	var included int
+	for _, sim := range simulated {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
+			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
+		}
+		if w.current.deadlineReached() {
+			log.Info("Not enough time for further bundles", "included", included, "left", len(simulated)-included)
+			deadlineReachedMeter.Mark(1)
+			break
+		}
+		env := w.newBundleEnv()
+		if err := w.applyBundle(env, sim.Bundle, coinbase); err != nil {
+			log.Debug("Discarding conflicting bundle", "hash", sim.Bundle.Hash(), "err", err)
+			bundleDiscardedMeter.Mark(1)
+			continue
+		}
+		// The bundle made it, swap the scratch environment in
+		w.current.state.StopPrefetcher()
+		w.current.state = env.state
+		w.current.gasPool = env.gasPool
+		w.current.header.GasUsed = env.header.GasUsed
+		w.current.txs = append(w.current.txs, sim.Bundle.Txs...)
+		w.current.receipts = append(w.current.receipts, env.receipts...)
+		w.current.tcount = env.tcount
+
+		log.Debug("Included bundle", "hash", sim.Bundle.Hash(), "txs", len(sim.Bundle.Txs), "gas", sim.GasUsed, "price", sim.EffectivePrice)
+		bundleIncludedMeter.Mark(1)
+		included++
+	}
+	// The copied states only carry an inactive prefetcher, restart it
+	if included > 0 {
+		w.current.state.StartPrefetcher("miner")
+	}
+	return false
+}
+
 func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
@@ -761,10 +981,10 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 
 	var coalescedLogs []*types.Log
 	var stopTimer *time.Timer
-	delay := w.engine.Delay(w.chain, w.current.header)
-	if delay != nil {
-		stopTimer = time.NewTimer(*delay - w.config.DelayLeftOver)
-		log.Debug("Time left for mining work", "left", (*delay - w.config.DelayLeftOver).String(), "leftover", w.config.DelayLeftOver)
+	if !w.current.deadline.IsZero() {
+		left := time.Until(w.current.deadline)
+		stopTimer = time.NewTimer(left)
+		log.Debug("Time left for mining work", "left", left.String(), "leftover", w.config.DelayLeftOver)
 		defer stopTimer.Stop()
 	}
 
@@ -806,6 +1026,7 @@ LOOP:
 			select {
 			case <-stopTimer.C:
 				log.Info("Not enough time for further transactions", "txs", len(w.current.txs))
+				deadlineReachedMeter.Mark(1)
 				break LOOP
 			default:
 			}
@@ -927,6 +1148,10 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		log.Error("Failed to create mining context", "err", err)
 		return
 	}
+	// Compute the build deadline once, so that every stage of the block building
+	// draws from the same slot budget.
+	w.current.deadline = w.buildDeadline(header)
+
 	// Accumulate the uncles for the current block
 	uncles := make([]*types.Header, 0)
 	// Create an empty block based on temporary copied state for
@@ -935,6 +1160,12 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.commit(uncles, nil, false, tstart)
 	}
 
+	// Bundles are only valid at the top of the block, commit them first.
+	if bundles := w.eth.TxPool().Bundles(header.Number, header.Time); len(bundles) > 0 {
+		if w.commitBundles(bundles, w.coinbase, interrupt) {
+			return
+		}
+	}
 	// Fill the block with all available pending transactions.
 	pending, err := w.eth.TxPool().Pending()
 	if err != nil {
@@ -966,6 +1197,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		commitTxsTimer.UpdateSince(start)
 		log.Info("Gas pool", "height", header.Number.String(), "pool", w.current.gasPool.String())
 	}
+	buildBlockTimer.UpdateSince(tstart)
 	w.commit(uncles, w.fullTaskHook, false, tstart)
 }
 
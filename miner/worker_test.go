@@ -353,3 +353,185 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+func TestCommitBundles(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		signer = types.LatestSigner(ethashChainConfig)
+	)
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	// Mine into an unrelated account so that bundle fees aren't offset by transfers
+	w.setEtherbase(common.Address{0x01})
+
+	// A bundle paying nothing, one paying well and one which can't be applied
+	cheap := types.MustSignNewTx(testUserKey, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &testBankAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(0),
+	})
+	rich := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &testUserAddress,
+		Value:    big.NewInt(1000),
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(10),
+	})
+	broken := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    5,
+		To:       &testUserAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(100),
+	})
+	for _, txs := range []types.Transactions{{cheap}, {rich}, {rich, broken}} {
+		if err := b.txPool.AddBundle(&types.Bundle{Txs: txs, BlockNumber: big.NewInt(1)}); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	if err := b.txPool.AddBundle(&types.Bundle{Txs: types.Transactions{cheap}, BlockNumber: big.NewInt(0)}); err != core.ErrBundleStale {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, core.ErrBundleStale)
+	}
+	taskCh := make(chan *task, 1)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.block.Transactions()) > 0 {
+			select {
+			case taskCh <- task:
+			default:
+			}
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start()
+
+	select {
+	case task := <-taskCh:
+		txs := task.block.Transactions()
+		if len(txs) != 2 {
+			t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), 2)
+		}
+		if txs[0].Hash() != rich.Hash() || txs[1].Hash() != cheap.Hash() {
+			t.Fatalf("bundle order mismatch: have [%x, %x], want [%x, %x]", txs[0].Hash(), txs[1].Hash(), rich.Hash(), cheap.Hash())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
+// Tests that bundle simulations are reused across the recommits of a block, and
+// that only the most promising bundles are simulated up to the limit.
+func TestCommitBundlesSimulationCache(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		signer = types.LatestSigner(ethashChainConfig)
+	)
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	// Free bundles from throwaway accounts beyond the limit, and a paying one last
+	var bundles []*types.Bundle
+	for i := 0; i < maxSimulatedBundles; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(0)})
+		bundles = append(bundles, &types.Bundle{Txs: types.Transactions{tx}, BlockNumber: big.NewInt(1)})
+	}
+	paying := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(1)})
+	bundles = append(bundles, &types.Bundle{Txs: types.Transactions{paying}, BlockNumber: big.NewInt(1)})
+
+	commit := func() *environment {
+		parent := w.chain.CurrentBlock()
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(1),
+			GasLimit:   parent.GasLimit(),
+			Time:       parent.Time() + 1,
+			Difficulty: big.NewInt(1),
+		}
+		if err := w.makeCurrent(parent, header); err != nil {
+			t.Fatalf("failed to create mining context: %v", err)
+		}
+		w.commitBundles(append([]*types.Bundle{}, bundles...), common.Address{0x01}, nil)
+		return w.current
+	}
+	env := commit()
+	if len(w.bundleSims) != maxSimulatedBundles {
+		t.Fatalf("simulated bundle count mismatch: have %d, want %d", len(w.bundleSims), maxSimulatedBundles)
+	}
+	if _, ok := w.bundleSims[bundles[len(bundles)-1].Hash()]; !ok {
+		t.Fatalf("paying bundle not simulated")
+	}
+	if len(env.txs) != maxSimulatedBundles || env.txs[0].Hash() != paying.Hash() {
+		t.Fatalf("included bundles mismatch: have %d txs", len(env.txs))
+	}
+	// A recommit on the same parent must reuse the cached simulations
+	cached := make(map[common.Hash]*bundleSim)
+	for hash, sim := range w.bundleSims {
+		cached[hash] = sim
+	}
+	commit()
+	if len(w.bundleSims) != len(cached) {
+		t.Fatalf("simulated bundle count changed: have %d, want %d", len(w.bundleSims), len(cached))
+	}
+	for hash, sim := range w.bundleSims {
+		if cached[hash] != sim {
+			t.Fatalf("bundle %x simulated again", hash)
+		}
+	}
+}
+
+// slotEngine wraps a consensus engine, bounding the block building time like
+// Parlia does.
+type slotEngine struct {
+	consensus.Engine
+	delay time.Duration
+}
+
+func (e *slotEngine) Delay(chain consensus.ChainReader, header *types.Header) *time.Duration {
+	return &e.delay
+}
+
+func TestBuildDeadline(t *testing.T) {
+	for _, tt := range []struct {
+		delay time.Duration
+		txs   int
+	}{
+		{delay: time.Hour, txs: len(pendingTxs)},
+		{delay: 0, txs: 0},
+	} {
+		engine := ethash.NewFaker()
+
+		backend := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+		backend.txPool.AddLocals(pendingTxs)
+		w := newWorker(testConfig, ethashChainConfig, &slotEngine{Engine: engine, delay: tt.delay}, backend, new(event.TypeMux), nil, false)
+		w.setEtherbase(testBankAddress)
+		w.disablePreseal()
+
+		taskCh := make(chan *task, 1)
+		w.newTaskHook = func(task *task) {
+			if task.block.NumberU64() == 1 {
+				select {
+				case taskCh <- task:
+				default:
+				}
+			}
+		}
+		w.skipSealHook = func(task *task) bool { return true }
+		w.start()
+
+		select {
+		case task := <-taskCh:
+			if have := len(task.block.Transactions()); have != tt.txs {
+				t.Errorf("delay %v: transaction count mismatch: have %d, want %d", tt.delay, have, tt.txs)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("delay %v: timeout", tt.delay)
+		}
+		w.close()
+		engine.Close()
+	}
+}
@@ -70,6 +70,16 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value backend to create new databases with, either
+	// "leveldb" or "pebble". Existing databases are always opened with the
+	// backend they were created with, which the engine, if set, must match.
+	DBEngine string `toml:",omitempty"`
+
+	// AncientCompression is the compression of the ancient block bodies and
+	// receipts, either "snappy" or "zstd". Existing tables compressed otherwise
+	// are migrated, an empty value retains them.
+	AncientCompression string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -486,6 +486,24 @@ func (n *Node) RegisterHandler(name, path string, handler http.Handler) {
 	n.http.handlerNames[path] = name
 }
 
+// RegisterWebsocketHandler mounts a handler on the websocket endpoint, serving the
+// connections which negotiate the given subprotocol instead of RPC.
+//
+// The name of the handler is shown in a log message when the websocket endpoint
+// starts and should be a descriptive term for the service provided by the handler.
+func (n *Node) RegisterWebsocketHandler(name, protocol string, handler http.Handler) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state != initializingState {
+		panic("can't register websocket handler on running/stopped node")
+	}
+	// The websocket endpoint is either served by the HTTP server or on its own
+	for _, server := range []*httpServer{n.http, n.ws} {
+		server.wsProtocols[protocol] = &wsProtocolHandler{name: name, handler: handler}
+	}
+}
+
 // Attach creates an RPC client attached to an in-process API handler.
 func (n *Node) Attach() (*rpc.Client, error) {
 	return rpc.DialInProc(n.inprocHandler), nil
@@ -573,7 +591,10 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
+		var kvdb ethdb.KeyValueStore
+		if kvdb, err = n.openKeyValueStore(n.ResolvePath(name), cache, handles, namespace, readonly); err == nil {
+			db = rawdb.NewDatabase(kvdb)
+		}
 	}
 
 	if err == nil {
@@ -582,7 +603,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	return db, err
 }
 
-func (n *Node) OpenAndMergeDatabase(name string, cache, handles int, freezer, diff, namespace string, readonly, persistDiff bool) (ethdb.Database, error) {
+func (n *Node) OpenAndMergeDatabase(name string, cache, handles int, freezer, diff, namespace string, readonly, persistDiff, freezeDiff bool) (ethdb.Database, error) {
 	chainDataHandles := handles
 	if persistDiff {
 		chainDataHandles = handles * chainDataHandlesPercentage / 100
@@ -598,6 +619,9 @@ func (n *Node) OpenAndMergeDatabase(name string, cache, handles int, freezer, di
 			return nil, err
 		}
 		chainDB.SetDiffStore(diffStore)
+		if freezeDiff {
+			chainDB.FreezeDiffLayers()
+		}
 	}
 	return chainDB, nil
 }
@@ -626,7 +650,12 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		case !filepath.IsAbs(freezer):
 			freezer = n.ResolvePath(freezer)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, readonly, disableFreeze, isLastOffset)
+		var kvdb ethdb.KeyValueStore
+		if kvdb, err = n.openKeyValueStore(root, cache, handles, namespace, readonly); err == nil {
+			if db, err = rawdb.NewDatabaseWithFreezerCompression(kvdb, freezer, namespace, n.config.AncientCompression, readonly, disableFreeze, isLastOffset); err != nil {
+				kvdb.Close()
+			}
+		}
 	}
 
 	if err == nil {
@@ -659,6 +688,25 @@ func (n *Node) OpenDiffDatabase(name string, handles int, diff, namespace string
 	return db, err
 }
 
+// openKeyValueStore opens the key-value store at the given path with the engine
+// it was created with, or the configured one (LevelDB by default) if it doesn't
+// exist yet.
+func (n *Node) openKeyValueStore(path string, cache, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+	engine := n.config.DBEngine
+	existing := rawdb.PreexistingDatabase(path)
+	if engine != "" && existing != "" && engine != existing {
+		return nil, fmt.Errorf("db.engine choice was %v but found pre-existing %v database in specified data directory", engine, existing)
+	}
+	if existing != "" {
+		engine = existing
+	}
+	if engine == "" {
+		engine = rawdb.DBLeveldb
+	}
+	n.log.Debug("Using key-value database", "engine", engine, "path", path)
+	return rawdb.NewKeyValueStore(engine, path, cache, handles, namespace, readonly)
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
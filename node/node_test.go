@@ -28,11 +28,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -172,6 +174,51 @@ func TestNodeCloseClosesDB(t *testing.T) {
 	}
 }
 
+// Tests that databases are reopened with the engine they were created with, and
+// that asking for a different one is refused.
+func TestNodeOpenDatabaseEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Closing the node closes the databases opened through it
+	open := func(engine string) (*Node, ethdb.Database, error) {
+		stack, err := New(&Config{DataDir: dir, DBEngine: engine})
+		if err != nil {
+			t.Fatalf("failed to create protocol stack: %v", err)
+		}
+		db, err := stack.OpenDatabase("mydb", 0, 0, "", false)
+		return stack, db, err
+	}
+	stack, db, err := open(rawdb.DBPebble)
+	if err != nil {
+		t.Fatalf("can't create pebble DB: %v", err)
+	}
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatalf("can't Put on pebble DB: %v", err)
+	}
+	stack.Close()
+
+	if engine := rawdb.PreexistingDatabase(stack.ResolvePath("mydb")); engine != rawdb.DBPebble {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, rawdb.DBPebble)
+	}
+	stack, _, err = open(rawdb.DBLeveldb)
+	stack.Close()
+	if err == nil {
+		t.Fatalf("opened pebble DB as leveldb")
+	}
+	stack, db, err = open("")
+	if err != nil {
+		t.Fatalf("can't reopen pebble DB: %v", err)
+	}
+	defer stack.Close()
+	if value, err := db.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Fatalf("value mismatch: have %q/%v, want %q", value, err, "value")
+	}
+}
+
 // This test checks that OpenDatabase can be used from within a Lifecycle Start method.
 func TestNodeOpenDatabaseFromLifecycleStart(t *testing.T) {
 	stack, _ := New(testNodeConfig())
@@ -485,6 +532,45 @@ func TestWebsocketHTTPOnSeparatePort_WSRequest(t *testing.T) {
 	}
 }
 
+// Tests that websocket connections negotiating a registered subprotocol are routed
+// to its handler, while all others are still served RPC.
+func TestRegisterWebsocketHandler(t *testing.T) {
+	node := createNode(t, 0, 0)
+	defer node.Close()
+
+	upgrader := websocket.Upgrader{Subprotocols: []string{"test-ws"}}
+	node.RegisterWebsocketHandler("test", "test-ws", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("success"))
+	}))
+	if err := node.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{"other", "test-ws"}}
+	conn, _, err := dialer.Dial(node.WSEndpoint(), nil)
+	if err != nil {
+		t.Fatalf("could not dial websocket handler: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != "test-ws" {
+		t.Fatalf("subprotocol mismatch: have %q, want %q", conn.Subprotocol(), "test-ws")
+	}
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read from websocket handler: %v", err)
+	}
+	assert.Equal(t, "success", string(msg))
+
+	if !checkRPC(node.WSEndpoint()) {
+		t.Fatalf("ws request failed")
+	}
+}
+
 type rpcPrefixTest struct {
 	httpPrefix, wsPrefix string
 	// These lists paths on which JSON-RPC should be served / not served.
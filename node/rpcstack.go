@@ -69,8 +69,9 @@ type httpServer struct {
 	httpHandler atomic.Value // *rpcHandler
 
 	// WebSocket handler things.
-	wsConfig  wsConfig
-	wsHandler atomic.Value // *rpcHandler
+	wsConfig    wsConfig
+	wsHandler   atomic.Value                  // *rpcHandler
+	wsProtocols map[string]*wsProtocolHandler // handlers of websocket subprotocols other than RPC
 
 	// These are set by setListenAddr.
 	endpoint string
@@ -81,7 +82,7 @@ type httpServer struct {
 }
 
 func newHTTPServer(log log.Logger, timeouts rpc.HTTPTimeouts) *httpServer {
-	h := &httpServer{log: log, timeouts: timeouts, handlerNames: make(map[string]string)}
+	h := &httpServer{log: log, timeouts: timeouts, handlerNames: make(map[string]string), wsProtocols: make(map[string]*wsProtocolHandler)}
 
 	h.httpHandler.Store((*rpcHandler)(nil))
 	h.wsHandler.Store((*rpcHandler)(nil))
@@ -150,6 +151,15 @@ func (h *httpServer) start() error {
 			url += h.wsConfig.prefix
 		}
 		h.log.Info("WebSocket enabled", "url", url)
+
+		var protocols []string
+		for protocol := range h.wsProtocols {
+			protocols = append(protocols, protocol)
+		}
+		sort.Strings(protocols)
+		for _, protocol := range protocols {
+			h.log.Info(h.wsProtocols[protocol].name+" enabled", "url", url, "protocol", protocol)
+		}
 	}
 	// if server is websocket only, return after logging
 	if !h.rpcAllowed() {
@@ -185,7 +195,11 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ws := h.wsHandler.Load().(*rpcHandler)
 	if ws != nil && isWebsocket(r) {
 		if checkPath(r, h.wsConfig.prefix) {
-			ws.ServeHTTP(w, r)
+			if handler := h.wsProtocolHandler(r); handler != nil {
+				handler.ServeHTTP(w, r)
+			} else {
+				ws.ServeHTTP(w, r)
+			}
 		}
 		return
 	}
@@ -210,6 +224,19 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 }
 
+// wsProtocolHandler returns the handler of the first websocket subprotocol requested
+// by the client which has one registered, or nil if the request is for RPC.
+func (h *httpServer) wsProtocolHandler(r *http.Request) http.Handler {
+	for _, header := range r.Header.Values("Sec-Websocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if handler, ok := h.wsProtocols[strings.TrimSpace(protocol)]; ok {
+				return handler.handler
+			}
+		}
+	}
+	return nil
+}
+
 // checkPath checks whether a given request URL matches a given path prefix.
 func checkPath(r *http.Request, path string) bool {
 	// if no prefix has been specified, request URL must be on root
@@ -355,6 +382,13 @@ func (h *httpServer) wsAllowed() bool {
 	return h.wsHandler.Load().(*rpcHandler) != nil
 }
 
+// wsProtocolHandler is a handler serving the websocket connections negotiating a
+// specific subprotocol on the websocket endpoint.
+type wsProtocolHandler struct {
+	name    string
+	handler http.Handler
+}
+
 // isWebsocket checks the header of an http request for a websocket upgrade request.
 func isWebsocket(r *http.Request) bool {
 	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
@@ -75,6 +75,7 @@ type Database struct {
 	newest  common.Hash                 // Newest tracked node, flush-list tail
 
 	preimages map[common.Hash][]byte // Preimages of nodes from the secure trie
+	refs      *refcounter            // Reference counts of the flushed nodes for online pruning, nil if disabled
 
 	gctime  time.Duration      // Time spent on garbage collection since last commit
 	gcnodes uint64             // Nodes garbage collected since last commit
@@ -282,6 +283,7 @@ type Config struct {
 	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
 	Journal   string // Journal of clean cache to survive node restarts
 	Preimages bool   // Flag whether the preimage of trie key is recorded
+	Refcount  bool   // Flag whether the flushed nodes are reference counted for online pruning
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
@@ -313,6 +315,9 @@ func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database
 	if config == nil || config.Preimages { // TODO(karalabe): Flip to default off in the future
 		db.preimages = make(map[common.Hash][]byte)
 	}
+	if config != nil && config.Refcount {
+		db.refs = newRefcounter(diskdb)
+	}
 	return db
 }
 
@@ -342,6 +347,9 @@ func (db *Database) insert(hash common.Hash, size int, node node) {
 		if c := db.dirties[child]; c != nil {
 			c.parents++
 		}
+		if db.refs != nil {
+			db.refs.pin(child)
+		}
 	})
 	db.dirties[hash] = entry
 
@@ -501,19 +509,26 @@ func (db *Database) Reference(child common.Hash, parent common.Hash) {
 
 // reference is the private locked version of Reference.
 func (db *Database) reference(child common.Hash, parent common.Hash) {
-	// If the node does not exist, it's a node pulled from disk, skip
+	// If the node does not exist, it's a node pulled from disk, skip. If the
+	// flushed nodes are reference counted, the reference is still tracked to
+	// keep the node on disk as long as it's in use.
 	node, ok := db.dirties[child]
-	if !ok {
+	if !ok && db.refs == nil {
 		return
 	}
 	// If the reference already exists, only duplicate for roots
 	if db.dirties[parent].children == nil {
 		db.dirties[parent].children = make(map[common.Hash]uint16)
 		db.childrenSize += cachedNodeChildrenSize
-	} else if _, ok = db.dirties[parent].children[child]; ok && parent != (common.Hash{}) {
+	} else if _, exists := db.dirties[parent].children[child]; exists && parent != (common.Hash{}) {
 		return
 	}
-	node.parents++
+	if ok {
+		node.parents++
+	}
+	if db.refs != nil {
+		db.refs.pin(child)
+	}
 	db.dirties[parent].children[child]++
 	if db.dirties[parent].children[child] == 1 {
 		db.childrenSize += common.HashLength + 2 // uint16 counter
@@ -557,9 +572,17 @@ func (db *Database) dereference(child common.Hash, parent common.Hash) {
 			db.childrenSize -= (common.HashLength + 2) // uint16 counter
 		}
 	}
+	if db.refs != nil {
+		db.refs.unpin(child)
+	}
 	// If the child does not exist, it's a previously committed node.
 	node, ok := db.dirties[child]
 	if !ok {
+		// A node flushed by Cap is only kept alive by its dirty referrers, queue
+		// it for deletion once the last of them is gone.
+		if db.refs != nil && db.refs.memrefs[child] == 0 {
+			db.refs.orphans[child] = struct{}{}
+		}
 		return
 	}
 	// If there are no more references to the child, delete it and cascade
@@ -614,6 +637,12 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	size += db.childrenSize - common.StorageSize(len(db.dirties[common.Hash{}].children)*(common.HashLength+2))
 	db.lock.RUnlock()
 
+	// Hold the reference counts until the flushed nodes are uncached
+	if db.refs != nil {
+		db.refs.lock.Lock()
+		defer db.refs.lock.Unlock()
+		defer db.refs.reset()
+	}
 	batch := db.diskdb.NewBatch()
 
 	// If the preimage cache got large enough, push to disk. If it's still small
@@ -641,6 +670,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 			// Fetch the oldest referenced node and push into the batch
 			node := db.dirties[oldest]
 			rawdb.WriteTrieNode(batch, oldest, node.rlp())
+			if db.refs != nil {
+				db.refs.flush(batch, oldest, node)
+			}
 
 			// If we exceeded the ideal batch size, commit and reset
 			if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -685,6 +717,10 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		delete(db.dirties, db.oldest)
 		db.oldest = node.flushNext
 
+		if db.refs != nil {
+			node.forChilds(db.refs.unpin)
+		}
+
 		db.dirtiesSize -= common.StorageSize(common.HashLength + int(node.size))
 		if node.children != nil {
 			db.childrenSize -= common.StorageSize(cachedNodeChildrenSize + len(node.children)*(common.HashLength+2))
@@ -714,6 +750,22 @@ func (db *Database) Cap(limit common.StorageSize) error {
 // Note, this method is a non-synchronized mutator. It is unsafe to call this
 // concurrently with other mutators.
 func (db *Database) Commit(node common.Hash, report bool, callback func(common.Hash)) error {
+	return db.commitRoot(node, nil, report, callback)
+}
+
+// CommitRetained commits the trie like Commit, and if the flushed nodes are
+// reference counted, marks its root as retained at the given block number in
+// the same write as the root node itself. The trie is then kept on disk until
+// released.
+//
+// Note, this method is a non-synchronized mutator. It is unsafe to call this
+// concurrently with other mutators.
+func (db *Database) CommitRetained(node common.Hash, number uint64, report bool) error {
+	return db.commitRoot(node, &number, report, nil)
+}
+
+// commitRoot is the shared implementation of Commit and CommitRetained.
+func (db *Database) commitRoot(node common.Hash, number *uint64, report bool, callback func(common.Hash)) error {
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
 	// memory cache during commit but not yet in persistent storage). This is ensured
@@ -736,11 +788,22 @@ func (db *Database) Commit(node common.Hash, report bool, callback func(common.H
 	nodes, storage := len(db.dirties), db.dirtiesSize
 	db.lock.RUnlock()
 
+	// Hold the reference counts until the committed nodes are uncached
+	if db.refs != nil {
+		db.refs.lock.Lock()
+		defer db.refs.lock.Unlock()
+		defer db.refs.reset()
+	}
 	uncacher := &cleaner{db}
 	if err := db.commit(node, batch, uncacher, callback); err != nil {
 		log.Error("Failed to commit trie from trie database", "err", err)
 		return err
 	}
+	// Retain the root along with the last batch, which contains the root node
+	if number != nil && db.refs != nil {
+		db.refs.reference(batch, node)
+		rawdb.WriteRetainedTrieRoot(batch, *number, node)
+	}
 	// Trie mostly committed to disk, flush any batch leftovers
 	if err := batch.Write(); err != nil {
 		log.Error("Failed to write trie to disk", "err", err)
@@ -795,11 +858,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	if err != nil {
 		return err
 	}
-	// If we've reached an optimal batch size, commit and start over
-	rawdb.WriteTrieNode(batch, hash, node.rlp())
-	if callback != nil {
-		callback(hash)
-	}
+	// If we've reached an optimal batch size, commit and start over. The check
+	// is done before writing the node, so that the root always ends up in the
+	// last batch.
 	if batch.ValueSize() >= ethdb.IdealBatchSize {
 		if err := batch.Write(); err != nil {
 			return err
@@ -809,6 +870,13 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 		batch.Reset()
 		db.lock.Unlock()
 	}
+	rawdb.WriteTrieNode(batch, hash, node.rlp())
+	if db.refs != nil {
+		db.refs.flush(batch, hash, node)
+	}
+	if callback != nil {
+		callback(hash)
+	}
 	return nil
 }
 
@@ -824,6 +892,10 @@ type cleaner struct {
 // the two-phase commit is to ensure ensure data availability while moving from
 // memory to disk.
 func (c *cleaner) Put(key []byte, rlp []byte) error {
+	// Skip anything that's not a trie node, e.g. reference counts
+	if len(key) != common.HashLength {
+		return nil
+	}
 	hash := common.BytesToHash(key)
 
 	// If the node does not exist, we're done on this path
@@ -844,6 +916,9 @@ func (c *cleaner) Put(key []byte, rlp []byte) error {
 		c.db.dirties[node.flushNext].flushPrev = node.flushPrev
 	}
 	// Remove the node from the dirty cache
+	if c.db.refs != nil {
+		node.forChilds(c.db.refs.unpin)
+	}
 	delete(c.db.dirties, hash)
 	c.db.dirtiesSize -= common.StorageSize(common.HashLength + int(node.size))
 	if node.children != nil {
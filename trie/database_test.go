@@ -17,9 +17,11 @@
 package trie
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 )
 
@@ -31,3 +33,157 @@ func TestDatabaseMetarootFetch(t *testing.T) {
 		t.Fatalf("metaroot retrieval succeeded")
 	}
 }
+
+// Tests that releasing retained tries deletes the nodes no longer referenced,
+// while keeping the retained and the in-memory tries intact.
+func TestDatabaseRelease(t *testing.T) {
+	diskdb := memorydb.New()
+	db := NewDatabaseWithConfig(diskdb, &Config{Refcount: true})
+
+	// update creates a new trie on top of the given root, modifying some keys
+	update := func(root common.Hash, seed byte, mod byte) (common.Hash, map[string][]byte) {
+		tr, err := New(root, db)
+		if err != nil {
+			t.Fatalf("failed to open trie %x: %v", root, err)
+		}
+		content := make(map[string][]byte)
+		for i := byte(0); i < 100; i++ {
+			key := common.LeftPadBytes([]byte{1, i}, 32)
+			if i%10 == mod || root == (common.Hash{}) {
+				tr.Update(key, bytes.Repeat([]byte{seed, i}, 16))
+			}
+			content[string(key)] = tr.Get(key)
+		}
+		root, err = tr.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		return root, content
+	}
+	// verify checks that a trie is complete on disk and contains the content
+	verify := func(root common.Hash, content map[string][]byte) {
+		tr, err := New(root, NewDatabase(diskdb))
+		if err != nil {
+			t.Fatalf("failed to open trie %x: %v", root, err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if it.Error() != nil {
+			t.Fatalf("trie %x incomplete: %v", root, it.Error())
+		}
+		for key, val := range content {
+			if have := tr.Get([]byte(key)); !bytes.Equal(have, val) {
+				t.Fatalf("trie %x value mismatch for %x: have %x, want %x", root, key, have, val)
+			}
+		}
+	}
+	root1, _ := update(common.Hash{}, 1, 0)
+	if err := db.CommitRetained(root1, 1, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	root2, content2 := update(root1, 2, 0)
+	if err := db.CommitRetained(root2, 2, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	// Keep a trie based on the oldest root in memory only, sharing some of its
+	// nodes with the oldest root only
+	root3, content3 := update(root1, 3, 5)
+	db.Reference(root3, common.Hash{})
+
+	// The most recent retained root must never be released
+	if deleted, err := db.Release(1); err != nil || deleted != 0 {
+		t.Fatalf("unexpected release below the oldest root: deleted %d, err %v", deleted, err)
+	}
+	deleted, err := db.Release(2)
+	if err != nil {
+		t.Fatalf("failed to release trie: %v", err)
+	}
+	if deleted == 0 {
+		t.Fatalf("no nodes deleted on release")
+	}
+	if retained := rawdb.ReadRetainedTrieRoots(diskdb); len(retained) != 1 || retained[0].Root != root2 {
+		t.Fatalf("unexpected retained roots: %v", retained)
+	}
+	if len(rawdb.ReadTrieNode(diskdb, root1)) != 0 {
+		t.Fatalf("released root not deleted")
+	}
+	verify(root2, content2)
+
+	// Flush the in-memory trie and release everything else, the nodes it
+	// shares with the released root must have been kept
+	if err := db.CommitRetained(root3, 3, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	db.Dereference(root3)
+	if _, err := db.Release(4); err != nil {
+		t.Fatalf("failed to release trie: %v", err)
+	}
+	verify(root3, content3)
+}
+
+// Tests that the nodes flushed to disk by Cap are deleted on release once they
+// are garbage collected from memory without being committed.
+func TestDatabaseReleaseCapped(t *testing.T) {
+	diskdb := memorydb.New()
+	db := NewDatabaseWithConfig(diskdb, &Config{Refcount: true})
+
+	tr, _ := New(common.Hash{}, db)
+	for i := byte(0); i < 100; i++ {
+		tr.Update(common.LeftPadBytes([]byte{1, i}, 32), bytes.Repeat([]byte{1, i}, 16))
+	}
+	root1, _ := tr.Commit(nil)
+	if err := db.CommitRetained(root1, 1, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	retained := make(map[common.Hash]bool)
+	for it := tr.NodeIterator(nil); it.Next(true); {
+		if it.Hash() != (common.Hash{}) {
+			retained[it.Hash()] = true
+		}
+	}
+	// Create a trie on top, flush it to disk via Cap and drop it from memory
+	tr, _ = New(root1, db)
+	for i := byte(0); i < 100; i += 10 {
+		tr.Update(common.LeftPadBytes([]byte{1, i}, 32), bytes.Repeat([]byte{2, i}, 16))
+	}
+	root2, _ := tr.Commit(nil)
+	db.Reference(root2, common.Hash{})
+
+	var stale []common.Hash
+	for it := tr.NodeIterator(nil); it.Next(true); {
+		if hash := it.Hash(); hash != (common.Hash{}) && !retained[hash] {
+			stale = append(stale, hash)
+		}
+	}
+	if len(stale) == 0 {
+		t.Fatalf("no new nodes in updated trie")
+	}
+	if err := db.Cap(0); err != nil {
+		t.Fatalf("failed to cap database: %v", err)
+	}
+	for _, hash := range stale {
+		if len(rawdb.ReadTrieNode(diskdb, hash)) == 0 {
+			t.Fatalf("node %x not flushed by cap", hash)
+		}
+	}
+	db.Dereference(root2)
+
+	deleted, err := db.Release(2)
+	if err != nil {
+		t.Fatalf("failed to release trie: %v", err)
+	}
+	if deleted != len(stale) {
+		t.Errorf("deleted node count mismatch: have %d, want %d", deleted, len(stale))
+	}
+	for _, hash := range stale {
+		if len(rawdb.ReadTrieNode(diskdb, hash)) != 0 {
+			t.Errorf("stale node %x not deleted", hash)
+		}
+	}
+	for hash := range retained {
+		if len(rawdb.ReadTrieNode(diskdb, hash)) == 0 {
+			t.Errorf("retained node %x deleted", hash)
+		}
+	}
+}
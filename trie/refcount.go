@@ -0,0 +1,320 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	memcachePruneTimeTimer  = metrics.NewRegisteredResettingTimer("trie/memcache/prune/time", nil)
+	memcachePruneNodesMeter = metrics.NewRegisteredMeter("trie/memcache/prune/nodes", nil)
+)
+
+// refcount is the persisted reference information of a trie node flushed to disk.
+type refcount struct {
+	Count    uint32        // Number of flushed nodes and retained roots referencing the node
+	External []common.Hash // External children (e.g. storage trie roots) referenced by the node
+}
+
+// refcounter maintains persistent reference counts of the trie nodes flushed to
+// disk, allowing the nodes no longer reachable from any retained state root to
+// be deleted while the node is running.
+//
+// Only the nodes written while reference counting is enabled are tracked. Nodes
+// already on disk without a reference count (persisted by an earlier run without
+// online pruning, or by state sync) are never deleted, as their referrers are
+// unknown.
+//
+// All persistent updates are written atomically with the trie nodes they belong
+// to, and a retained root is released in a single batch, so an unclean shutdown
+// can leave behind unreferenced nodes, but never a root with missing nodes.
+type refcounter struct {
+	diskdb ethdb.KeyValueStore
+
+	lock    sync.Mutex                // Lock serializing the persistent reference updates
+	pending map[common.Hash]*refcount // Reference counts updated in not yet written batches, nil if deleted
+
+	memrefs map[common.Hash]uint32   // References held by dirty nodes, protected by the database lock
+	orphans map[common.Hash]struct{} // Flushed nodes which lost their last dirty referrer, protected by the database lock
+}
+
+// newRefcounter creates a reference counter for the trie nodes in diskdb.
+func newRefcounter(diskdb ethdb.KeyValueStore) *refcounter {
+	return &refcounter{
+		diskdb:  diskdb,
+		pending: make(map[common.Hash]*refcount),
+		memrefs: make(map[common.Hash]uint32),
+		orphans: make(map[common.Hash]struct{}),
+	}
+}
+
+// get retrieves the reference count of a flushed node, or nil if it's untracked.
+func (r *refcounter) get(hash common.Hash) *refcount {
+	if rc, ok := r.pending[hash]; ok {
+		return rc
+	}
+	blob := rawdb.ReadTrieNodeRefs(r.diskdb, hash)
+	if len(blob) == 0 {
+		return nil
+	}
+	rc := new(refcount)
+	if err := rlp.DecodeBytes(blob, rc); err != nil {
+		log.Error("Invalid trie node references", "hash", hash, "err", err)
+		return nil
+	}
+	return rc
+}
+
+// put writes an updated reference count into the batch.
+func (r *refcounter) put(batch ethdb.KeyValueWriter, hash common.Hash, rc *refcount) {
+	blob, err := rlp.EncodeToBytes(rc)
+	if err != nil {
+		panic(err)
+	}
+	rawdb.WriteTrieNodeRefs(batch, hash, blob)
+	r.pending[hash] = rc
+}
+
+// reset drops the pending reference counts once all the batches holding them
+// have been written to disk.
+func (r *refcounter) reset() {
+	r.pending = make(map[common.Hash]*refcount)
+}
+
+// flush tracks a dirty node being written to disk. A node new to the disk starts
+// out unreferenced and references all its children, while a node already on disk
+// is accounted for by its existing referrers.
+func (r *refcounter) flush(batch ethdb.KeyValueWriter, hash common.Hash, node *cachedNode) {
+	if r.get(hash) != nil {
+		return
+	}
+	if has, _ := r.diskdb.Has(hash.Bytes()); has {
+		return
+	}
+	rc := new(refcount)
+	for child := range node.children {
+		rc.External = append(rc.External, child)
+	}
+	r.put(batch, hash, rc)
+
+	node.forChilds(func(child common.Hash) {
+		r.reference(batch, child)
+	})
+}
+
+// reference adds a reference to a flushed node, if it's tracked.
+func (r *refcounter) reference(batch ethdb.KeyValueWriter, hash common.Hash) {
+	if rc := r.get(hash); rc != nil {
+		rc.Count++
+		r.put(batch, hash, rc)
+	}
+}
+
+// pin records a reference from a dirty node to the given node.
+//
+// Note, this method assumes that the database's lock is held!
+func (r *refcounter) pin(hash common.Hash) {
+	r.memrefs[hash]++
+}
+
+// unpin removes a reference from a dirty node to the given node.
+//
+// Note, this method assumes that the database's lock is held!
+func (r *refcounter) unpin(hash common.Hash) {
+	if r.memrefs[hash] <= 1 {
+		delete(r.memrefs, hash)
+		return
+	}
+	r.memrefs[hash]--
+}
+
+// Retained returns the state roots retained on disk by the online pruning, in
+// ascending block number order.
+func (db *Database) Retained() []rawdb.RetainedTrieRoot {
+	if db.refs == nil {
+		return nil
+	}
+	return rawdb.ReadRetainedTrieRoots(db.diskdb)
+}
+
+// Release releases all the state roots retained below the given block number,
+// except the most recent one, and deletes the trie nodes which are no longer
+// referenced from disk. The nodes flushed by Cap which were garbage collected
+// from memory before any flushed node referenced them are deleted too. It
+// returns the number of deleted nodes.
+//
+// Every root is released atomically, so the method can be interrupted at any
+// point and is safe to call concurrently with the other mutators.
+func (db *Database) Release(number uint64) (int, error) {
+	if db.refs == nil {
+		return 0, nil
+	}
+	deleted, err := db.releaseOrphans()
+	if err != nil {
+		return deleted, err
+	}
+	roots := rawdb.ReadRetainedTrieRoots(db.diskdb)
+	for i := 0; i < len(roots)-1 && roots[i].Number < number; i++ {
+		nodes, err := db.release(roots[i])
+		if err != nil {
+			return deleted, err
+		}
+		deleted += nodes
+	}
+	return deleted, nil
+}
+
+// release releases a single retained state root in one batch.
+func (db *Database) release(root rawdb.RetainedTrieRoot) (int, error) {
+	db.refs.lock.Lock()
+	defer db.refs.lock.Unlock()
+	defer db.refs.reset()
+
+	var (
+		start   = time.Now()
+		batch   = db.diskdb.NewBatch()
+		deleted []common.Hash
+	)
+	rawdb.DeleteRetainedTrieRoot(batch, root.Number, root.Root)
+	db.dereferenceDisk(batch, root.Root, &deleted)
+
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	if db.cleans != nil {
+		for _, hash := range deleted {
+			db.cleans.Del(hash[:])
+		}
+	}
+	memcachePruneTimeTimer.Update(time.Since(start))
+	memcachePruneNodesMeter.Mark(int64(len(deleted)))
+
+	log.Debug("Released retained trie", "number", root.Number, "root", root.Root, "deleted", len(deleted), "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(deleted), nil
+}
+
+// releaseOrphans deletes the queued nodes flushed by Cap which are referenced
+// neither by a flushed nor by a dirty node.
+func (db *Database) releaseOrphans() (int, error) {
+	db.lock.Lock()
+	orphans := db.refs.orphans
+	db.refs.orphans = make(map[common.Hash]struct{})
+	db.lock.Unlock()
+
+	if len(orphans) == 0 {
+		return 0, nil
+	}
+	db.refs.lock.Lock()
+	defer db.refs.lock.Unlock()
+	defer db.refs.reset()
+
+	var (
+		start   = time.Now()
+		batch   = db.diskdb.NewBatch()
+		deleted []common.Hash
+	)
+	for hash := range orphans {
+		// Skip the nodes referenced since being queued, or injected again into
+		// the dirty cache
+		if rc := db.refs.get(hash); rc == nil || rc.Count > 0 {
+			continue
+		}
+		db.lock.RLock()
+		_, dirty := db.dirties[hash]
+		db.lock.RUnlock()
+		if dirty {
+			continue
+		}
+		db.dereferenceDisk(batch, hash, &deleted)
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	if db.cleans != nil {
+		for _, hash := range deleted {
+			db.cleans.Del(hash[:])
+		}
+	}
+	memcachePruneTimeTimer.Update(time.Since(start))
+	memcachePruneNodesMeter.Mark(int64(len(deleted)))
+
+	log.Debug("Released orphaned trie nodes", "queued", len(orphans), "deleted", len(deleted), "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(deleted), nil
+}
+
+// dereferenceDisk removes a reference from a flushed node, deleting it from disk
+// and cascading into its children if it's no longer referenced by any flushed
+// or dirty node.
+func (db *Database) dereferenceDisk(batch ethdb.KeyValueWriter, hash common.Hash, deleted *[]common.Hash) {
+	rc := db.refs.get(hash)
+	if rc == nil {
+		return
+	}
+	if rc.Count > 0 {
+		rc.Count--
+	}
+	db.lock.RLock()
+	pinned := db.refs.memrefs[hash] > 0
+	db.lock.RUnlock()
+
+	if rc.Count > 0 || pinned {
+		db.refs.put(batch, hash, rc)
+		return
+	}
+	// The node is unreferenced, delete it and cascade into the children
+	if blob := rawdb.ReadTrieNode(db.diskdb, hash); len(blob) > 0 {
+		if n, err := decodeNode(hash[:], blob); err != nil {
+			log.Error("Failed to decode released trie node", "hash", hash, "err", err)
+		} else {
+			forHashChildren(n, func(child common.Hash) {
+				db.dereferenceDisk(batch, child, deleted)
+			})
+		}
+	}
+	for _, child := range rc.External {
+		db.dereferenceDisk(batch, child, deleted)
+	}
+	rawdb.DeleteTrieNode(batch, hash)
+	rawdb.DeleteTrieNodeRefs(batch, hash)
+	db.refs.pending[hash] = nil
+
+	*deleted = append(*deleted, hash)
+}
+
+// forHashChildren traverses the node hierarchy of a decoded trie node and invokes
+// the callback for all the hashnode children.
+func forHashChildren(n node, onChild func(hash common.Hash)) {
+	switch n := n.(type) {
+	case *shortNode:
+		forHashChildren(n.Val, onChild)
+	case *fullNode:
+		for i := 0; i < 16; i++ {
+			forHashChildren(n.Children[i], onChild)
+		}
+	case hashNode:
+		onChild(common.BytesToHash(n))
+	}
+}